@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"backend/internal/audit"
+	"backend/internal/config"
+	"backend/internal/fieldcrypt"
+	"backend/internal/geoip"
+	"backend/internal/repository"
+	"backend/internal/service"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: admin <command> [flags]
+
+Commands:
+  retention [-dry-run] [table ...]
+        Apply the retention policies of the given tables, or of all of
+        them, and report what was purged. With -dry-run nothing is deleted.
+
+  mail-dead [-limit n] [-retry] [id ...]
+        List the emails delivery was given up on, newest first. With
+        -retry, queue the given dead emails, or all of them, for delivery
+        again; the auth service sends them.
+`
+
+// The admin CLI runs maintenance tasks against the auth service's database,
+// with the same configuration as the auth service.
+func main() {
+	log.SetFlags(0)
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch flag.Arg(0) {
+	case "retention":
+		retention(flag.Args()[1:])
+	case "mail-dead":
+		mailDead(flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func retention(args []string) {
+	flags := flag.NewFlagSet("retention", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only count what would be purged")
+	flags.Parse(args)
+
+	cfg, db, ring := connect()
+	defer db.Close()
+
+	var auditSink audit.Sink = audit.NopSink{}
+	if cfg.AuditLogFile != "" {
+		auditSink = audit.NewFileSink(cfg.AuditLogFile)
+	}
+
+	geo, err := geoip.NewResolver(cfg.GeoIP.Databases, cfg.GeoIP.ReloadInterval)
+	if err != nil {
+		log.Fatalf("Failed to load GeoIP databases: %v", err)
+	}
+	defer geo.Close()
+
+	auditService := service.NewAuditService(db, repository.NewAuditRepository(db), geo, auditSink)
+	retentionService := service.NewRetentionService(
+		auditService,
+		repository.NewRetentionRepository(db),
+		repository.NewUserRepository(db, ring),
+		service.RetentionPoliciesFromConfig(cfg.Retention),
+	)
+
+	reports, err := retentionService.Purge(flags.Args(), *dryRun)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tPURGED\t")
+	for _, report := range reports {
+		purged := fmt.Sprint(report.Purged)
+		if report.DryRun {
+			purged += " (dry run)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t\n", report.Table, purged)
+	}
+	w.Flush()
+
+	if err != nil {
+		log.Fatalf("Retention failed: %v", err)
+	}
+}
+
+func mailDead(args []string) {
+	flags := flag.NewFlagSet("mail-dead", flag.ExitOnError)
+	limit := flags.Int("limit", 100, "list at most this many emails")
+	retry := flags.Bool("retry", false, "queue the emails for delivery again")
+	flags.Parse(args)
+
+	cfg, db, ring := connect()
+	defer db.Close()
+
+	// Nothing is sent from here, so the queue needs no transport
+	mailQueue := service.NewMailQueueService(repository.NewMailQueueRepository(db, ring), nil, service.MailQueueOptions{
+		MaxAttempts:  cfg.Mail.MaxAttempts,
+		RetryBase:    cfg.Mail.RetryBase,
+		RetryMax:     cfg.Mail.RetryMax,
+		ClaimTimeout: cfg.Mail.ClaimTimeout,
+	})
+
+	if *retry {
+		count, err := mailQueue.Retry(flags.Args())
+		if err != nil {
+			log.Fatalf("Retrying dead emails failed: %v", err)
+		}
+		fmt.Printf("Queued %d emails for delivery\n", count)
+		return
+	}
+
+	mails, err := mailQueue.DeadLetters(*limit)
+	if err != nil {
+		log.Fatalf("Listing dead emails failed: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tTO\tSUBJECT\tATTEMPTS\tLAST ERROR\t")
+	for _, mail := range mails {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t\n",
+			mail.ID, mail.CreatedAt.Format(time.RFC3339), mail.To, mail.Subject, mail.Attempts, mail.LastError)
+	}
+	w.Flush()
+}
+
+// connect loads the auth service's configuration and opens its database
+// and field encryption key ring.
+func connect() (*config.AuthConfig, *sql.DB, *fieldcrypt.KeyRing) {
+	cfg, err := config.LoadAuthConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+
+	ring, err := fieldcrypt.LoadKeyRing(
+		cfg.FieldEncryption.Keys,
+		cfg.FieldEncryption.KeysFile,
+		cfg.FieldEncryption.ActiveKey,
+		cfg.FieldEncryption.IndexKey,
+	)
+	if err != nil {
+		log.Fatalf("Failed to load field encryption keys: %v", err)
+	}
+
+	return cfg, db, ring
+}
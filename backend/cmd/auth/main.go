@@ -11,13 +11,25 @@ import (
 	"syscall"
 	"time"
 
+	"backend/internal/audit"
 	"backend/internal/config"
 	"backend/internal/database"
+	"backend/internal/domainpolicy"
+	"backend/internal/fieldcrypt"
+	"backend/internal/geoip"
+	"backend/internal/mailer"
+	"backend/internal/mailtmpl"
+	"backend/internal/middleware"
 	"backend/internal/repository"
 	"backend/internal/service"
+	"backend/internal/sms"
 	"backend/services/auth/handlers"
 
 	_ "github.com/lib/pq"
+
+	// Time zone preferences are checked against the IANA database, which
+	// the runtime image doesn't have
+	_ "time/tzdata"
 )
 
 func main() {
@@ -40,24 +52,176 @@ func main() {
 	}
 	log.Println("✅ Database connected successfully")
 
+	// Initialize field encryption
+	ring, err := fieldcrypt.LoadKeyRing(
+		cfg.FieldEncryption.Keys,
+		cfg.FieldEncryption.KeysFile,
+		cfg.FieldEncryption.ActiveKey,
+		cfg.FieldEncryption.IndexKey,
+	)
+	if err != nil {
+		log.Fatalf("Failed to load field encryption keys: %v", err)
+	}
+	if !ring.Enabled() {
+		log.Println("⚠️  No field encryption keys configured, personal data will be stored unencrypted")
+	}
+
 	// Run migrations
-	if err := database.RunMigrations(db); err != nil {
+	if err := database.RunMigrations(db, ring); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 	log.Println("✅ Database migrations completed")
 
+	// Initialize GeoIP enrichment
+	geo, err := geoip.NewResolver(cfg.GeoIP.Databases, cfg.GeoIP.ReloadInterval)
+	if err != nil {
+		log.Fatalf("Failed to load GeoIP databases: %v", err)
+	}
+	defer geo.Close()
+	if !geo.Enabled() {
+		log.Println("⚠️  No GeoIP databases configured, locations will not be recorded")
+	}
+
+	// Initialize email domain policy
+	domains, err := domainpolicy.NewPolicy(
+		cfg.EmailDomains.AllowlistFile,
+		cfg.EmailDomains.DenylistFile,
+		cfg.EmailDomains.BlockDisposable,
+		cfg.EmailDomains.ReloadInterval,
+	)
+	if err != nil {
+		log.Fatalf("Failed to load email domain lists: %v", err)
+	}
+	if cfg.InviteOnly {
+		log.Println("🔒 Registration is invite-only")
+	}
+
 	// Initialize repositories
-	userRepo := repository.NewUserRepository(db)
+	userRepo := repository.NewUserRepository(db, ring)
+	identityRepo := repository.NewIdentityRepository(db, ring)
 	tokenRepo := repository.NewTokenRepository(db)
+	deviceRepo := repository.NewTrustedDeviceRepository(db)
+	orgRepo := repository.NewOrganizationRepository(db, ring)
+	orgInvitationRepo := repository.NewOrgInvitationRepository(db)
+	loginEventRepo := repository.NewLoginEventRepository(db)
+	challengeRepo := repository.NewRegistrationChallengeRepository(db)
+	invitationRepo := repository.NewInvitationCodeRepository(db)
+	magicLinkRepo := repository.NewMagicLinkRepository(db)
+	phoneOTPRepo := repository.NewPhoneOTPRepository(db)
+	emailVerificationRepo := repository.NewEmailVerificationRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db, ring)
+	auditRepo := repository.NewAuditRepository(db)
+	legalRepo := repository.NewLegalRepository(db)
+	retentionRepo := repository.NewRetentionRepository(db)
+	prefsRepo := repository.NewPreferencesRepository(db)
+	translationRepo := repository.NewTranslationRepository(db)
+	mailQueueRepo := repository.NewMailQueueRepository(db, ring)
+
+	// Initialize outbound mail. Emails are queued and delivered in the
+	// background; broken templates stop the service here rather than
+	// failing sends later.
+	transport, err := mailTransport(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to set up mail transport: %v", err)
+	}
+	mailQueue := service.NewMailQueueService(mailQueueRepo, transport, service.MailQueueOptions{
+		MaxAttempts:  cfg.Mail.MaxAttempts,
+		RetryBase:    cfg.Mail.RetryBase,
+		RetryMax:     cfg.Mail.RetryMax,
+		ClaimTimeout: cfg.Mail.ClaimTimeout,
+	})
+	templates, err := mailtmpl.Load()
+	if err != nil {
+		log.Fatalf("Failed to load email templates: %v", err)
+	}
+	emails := service.NewEmailer(mailQueue, templates, prefsRepo)
+
+	// Initialize SMS delivery
+	var smsSender sms.SMSSender = sms.NewLogSender()
+	if cfg.SMSOutbox != "" {
+		smsSender = sms.NewFileSender(cfg.SMSOutbox)
+	}
+
+	// Initialize the audit log sink
+	var auditSink audit.Sink = audit.NopSink{}
+	if cfg.AuditLogFile != "" {
+		auditSink = audit.NewFileSink(cfg.AuditLogFile)
+	}
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, tokenRepo, cfg.JWTSecret, cfg.JWTExpiry)
+	auditService := service.NewAuditService(db, auditRepo, geo, auditSink)
+	loginHistoryService := service.NewLoginHistoryService(loginEventRepo, geo, emails)
+	registrationGuard := service.NewRegistrationGuard(challengeRepo, loginEventRepo, service.RegistrationLimits{
+		Difficulty:   cfg.Registration.PowDifficulty,
+		ChallengeTTL: cfg.Registration.ChallengeTTL,
+		IPLimit:      cfg.Registration.IPLimit,
+		SubnetLimit:  cfg.Registration.SubnetLimit,
+		Window:       cfg.Registration.Window,
+	})
+	registrationPolicy := service.NewRegistrationPolicy(domains, invitationRepo, cfg.InviteOnly)
+	authService := service.NewAuthService(userRepo, identityRepo, tokenRepo, deviceRepo, orgRepo, legalRepo, prefsRepo, loginHistoryService, registrationGuard, registrationPolicy, auditService, cfg.JWTSecret, cfg.JWTExpiry, cfg.StepUpMaxAge, cfg.TrustedDeviceTTL, cfg.DeletionGrace)
+	magicLinkService := service.NewMagicLinkService(authService, userRepo, magicLinkRepo, emails, cfg.MagicLinkURL, cfg.MagicLinkTTL)
+	phoneOTPService := service.NewPhoneOTPService(authService, userRepo, phoneOTPRepo, smsSender, cfg.OTPTTL)
+	orgService := service.NewOrganizationService(authService, orgRepo, orgInvitationRepo, userRepo, emails, cfg.OrgInviteURL, cfg.OrgInviteTTL)
+	identityService := service.NewIdentityService(authService, phoneOTPService, userRepo, emailVerificationRepo, magicLinkRepo, emails, cfg.MagicLinkTTL)
+	guestService := service.NewGuestService(authService, phoneOTPService, userRepo, cfg.Guests.Enabled)
+	legalService := service.NewLegalService(authService, legalRepo, userRepo)
+	preferencesService := service.NewPreferencesService(authService, prefsRepo, userRepo)
+	translationService := service.NewTranslationService(authService, translationRepo)
+	retentionService := service.NewRetentionService(auditService, retentionRepo, userRepo, service.RetentionPoliciesFromConfig(cfg.Retention))
+	exportSigningKey := cfg.DataExports.SigningKey
+	if exportSigningKey == "" {
+		exportSigningKey = cfg.JWTSecret
+	}
+	exportService := service.NewExportService(dataExportRepo, userRepo, emails, smsSender, exportSigningKey, service.ExportOptions{
+		Dir:         cfg.DataExports.Dir,
+		DownloadURL: cfg.DataExports.DownloadURL,
+		TTL:         cfg.DataExports.TTL,
+		LinkTTL:     cfg.DataExports.LinkTTL,
+		Cooldown:    cfg.DataExports.Cooldown,
+	})
+
+	// Finish deleting accounts once their grace period is over
+	if cfg.DeletionGrace > 0 {
+		go authService.RunPurge(cfg.PurgeInterval)
+	}
+
+	// Delete data that is past its retention period
+	go retentionService.Run(cfg.Retention.Interval)
+
+	// Deliver queued emails and retry failed ones
+	go mailQueue.Run(cfg.Mail.PollInterval)
+
+	// Build requested data exports and delete them once they expire
+	go exportService.Run(cfg.DataExports.PollInterval)
+	go exportService.RunCleanup(cfg.DataExports.CleanupInterval)
+
+	// Move personal data to the active encryption key
+	if ring.Enabled() {
+		keyRotation := service.NewKeyRotationService(userRepo, identityRepo, mailQueueRepo, cfg.FieldEncryption.RotationBatch)
+		go keyRotation.Run(cfg.FieldEncryption.RotationInterval)
+	}
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
+	magicLinkHandler := handlers.NewMagicLinkHandler(magicLinkService)
+	phoneHandler := handlers.NewPhoneHandler(phoneOTPService)
+	loginHistoryHandler := handlers.NewLoginHistoryHandler(loginHistoryService, authService)
+	invitationHandler := handlers.NewInvitationHandler(registrationPolicy, authService)
+	orgHandler := handlers.NewOrganizationHandler(orgService)
+	guestHandler := handlers.NewGuestHandler(guestService)
+	identityHandler := handlers.NewIdentityHandler(identityService)
+	exportHandler := handlers.NewExportHandler(exportService)
+	auditHandler := handlers.NewAuditHandler(auditService, authService)
+	legalHandler := handlers.NewLegalHandler(legalService, authService)
+	preferencesHandler := handlers.NewPreferencesHandler(preferencesService)
+	translationHandler := handlers.NewTranslationHandler(translationService, authService)
+	emailHandler := handlers.NewEmailHandler(emails, authService)
 
-	// Setup routes
+	// Setup routes. Authenticated endpoints are wrapped with active, which
+	// rejects access tokens of deleted accounts.
 	mux := http.NewServeMux()
+	active := handlers.RequireActiveUser(authService)
 
 	// Health check
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
@@ -67,23 +231,91 @@ func main() {
 	})
 
 	// Auth endpoints
+	mux.HandleFunc("POST /api/v1/auth/register/challenge", authHandler.RegisterChallenge)
 	mux.HandleFunc("POST /api/v1/auth/register", authHandler.Register)
 	mux.HandleFunc("POST /api/v1/auth/login", authHandler.Login)
 	mux.HandleFunc("POST /api/v1/auth/refresh", authHandler.RefreshToken)
 	mux.HandleFunc("POST /api/v1/auth/forgot-password", authHandler.ForgotPassword)
-	mux.HandleFunc("DELETE /api/v1/auth/account", authHandler.DeleteAccount)
+	mux.HandleFunc("POST /api/v1/auth/magic-link", magicLinkHandler.Request)
+	mux.HandleFunc("POST /api/v1/auth/magic-link/verify", magicLinkHandler.Verify)
+	mux.HandleFunc("POST /api/v1/auth/phone/otp", phoneHandler.SendOTP)
+	mux.HandleFunc("POST /api/v1/auth/phone/verify", phoneHandler.VerifyOTP)
+	mux.HandleFunc("POST /api/v1/auth/guest", guestHandler.SignIn)
+	mux.Handle("POST /api/v1/auth/reauthenticate", active(authHandler.Reauthenticate))
+	mux.Handle("DELETE /api/v1/auth/account", active(authHandler.DeleteAccount))
+
+	// Legal documents
+	mux.HandleFunc("GET /api/v1/legal/documents", legalHandler.GetDocuments)
+	mux.HandleFunc("GET /api/v1/legal/versions", legalHandler.GetVersions)
+
+	// Translations of the app
+	mux.HandleFunc("GET /api/v1/translations/{locale}", translationHandler.GetBundle)
+
+	// Data export downloads, authorized by the signed link
+	mux.HandleFunc("GET /api/v1/exports/{id}/download", exportHandler.Download)
 
 	// User endpoints
-	mux.HandleFunc("GET /api/v1/user/profile", authHandler.GetProfile)
-	mux.HandleFunc("PUT /api/v1/user/profile", authHandler.UpdateProfile)
-	mux.HandleFunc("GET /api/v1/user/usage", authHandler.GetUsage)
-	mux.HandleFunc("GET /api/v1/users", authHandler.ListUsers)
-	mux.HandleFunc("GET /api/v1/users/{id}", authHandler.GetUserByID)
+	mux.Handle("GET /api/v1/user/profile", active(authHandler.GetProfile))
+	mux.Handle("PUT /api/v1/user/profile", active(authHandler.UpdateProfile))
+	mux.Handle("PUT /api/v1/user/password", active(authHandler.ChangePassword))
+	mux.Handle("PUT /api/v1/user/email", active(authHandler.ChangeEmail))
+	mux.Handle("POST /api/v1/user/upgrade", active(guestHandler.Upgrade))
+	mux.Handle("POST /api/v1/user/upgrade/phone", active(guestHandler.UpgradeWithPhone))
+	mux.Handle("GET /api/v1/user/identities", active(identityHandler.ListIdentities))
+	mux.Handle("POST /api/v1/user/identities/email", active(identityHandler.LinkEmail))
+	mux.Handle("POST /api/v1/user/identities/phone", active(identityHandler.LinkPhone))
+	mux.Handle("POST /api/v1/user/identities/password", active(identityHandler.LinkPassword))
+	mux.Handle("DELETE /api/v1/user/identities/{method}", active(identityHandler.UnlinkIdentity))
+	mux.Handle("GET /api/v1/user/usage", active(authHandler.GetUsage))
+	mux.Handle("POST /api/v1/user/consents", active(legalHandler.AcceptConsents))
+	mux.Handle("GET /api/v1/user/consents", active(legalHandler.ListConsents))
+	mux.Handle("GET /api/v1/user/preferences", active(preferencesHandler.GetPreferences))
+	mux.Handle("PATCH /api/v1/user/preferences", active(preferencesHandler.UpdatePreferences))
+	mux.Handle("POST /api/v1/user/exports", active(exportHandler.RequestExport))
+	mux.Handle("GET /api/v1/user/exports", active(exportHandler.ListExports))
+	mux.Handle("GET /api/v1/user/exports/{id}", active(exportHandler.GetExport))
+	mux.Handle("GET /api/v1/user/login-history", active(loginHistoryHandler.GetLoginHistory))
+	mux.Handle("GET /api/v1/user/devices", active(authHandler.ListTrustedDevices))
+	mux.Handle("DELETE /api/v1/user/devices", active(authHandler.RevokeAllTrustedDevices))
+	mux.Handle("DELETE /api/v1/user/devices/{id}", active(authHandler.RevokeTrustedDevice))
+	mux.Handle("GET /api/v1/user/invitations", active(orgHandler.ListMyInvitations))
+	mux.Handle("POST /api/v1/user/invitations/accept", active(orgHandler.AcceptInvitation))
+	mux.Handle("POST /api/v1/user/invitations/decline", active(orgHandler.DeclineInvitation))
+	mux.Handle("GET /api/v1/users", active(authHandler.ListUsers))
+	mux.Handle("GET /api/v1/users/{id}", active(authHandler.GetUserByID))
+
+	// Organization endpoints
+	mux.Handle("POST /api/v1/orgs", active(orgHandler.CreateOrganization))
+	mux.Handle("GET /api/v1/orgs", active(orgHandler.ListOrganizations))
+	mux.Handle("POST /api/v1/orgs/{id}/switch", active(orgHandler.SwitchOrganization))
+	mux.Handle("GET /api/v1/orgs/{id}/members", active(orgHandler.ListMembers))
+	mux.Handle("PUT /api/v1/orgs/{id}/members/{userId}", active(orgHandler.UpdateMember))
+	mux.Handle("DELETE /api/v1/orgs/{id}/members/{userId}", active(orgHandler.RemoveMember))
+	mux.Handle("POST /api/v1/orgs/{id}/invitations", active(orgHandler.InviteMember))
+	mux.Handle("GET /api/v1/orgs/{id}/invitations", active(orgHandler.ListInvitations))
+	mux.Handle("DELETE /api/v1/orgs/{id}/invitations/{invitationId}", active(orgHandler.RevokeInvitation))
+
+	// Admin endpoints
+	mux.Handle("GET /api/v1/admin/users/{id}/login-history", active(loginHistoryHandler.GetUserLoginHistory))
+	mux.Handle("POST /api/v1/admin/users/{id}/restore", active(authHandler.RestoreUser))
+	mux.Handle("GET /api/v1/admin/audit-events", active(auditHandler.ListEvents))
+	mux.Handle("GET /api/v1/admin/audit-events/verify", active(auditHandler.VerifyChain))
+	mux.Handle("POST /api/v1/admin/legal-documents", active(legalHandler.PublishDocument))
+	mux.Handle("POST /api/v1/admin/translations/{locale}", active(translationHandler.UploadBundle))
+	mux.Handle("GET /api/v1/admin/translations/{locale}", active(translationHandler.ListBundles))
+	mux.Handle("GET /api/v1/admin/translations/{locale}/{version}", active(translationHandler.GetBundleVersion))
+	mux.Handle("POST /api/v1/admin/translations/{locale}/{version}/publish", active(translationHandler.PublishBundle))
+	mux.Handle("POST /api/v1/admin/translations/{locale}/{version}/reject", active(translationHandler.RejectBundle))
+	mux.Handle("GET /api/v1/admin/emails", active(emailHandler.ListTemplates))
+	mux.Handle("GET /api/v1/admin/emails/{name}/preview", active(emailHandler.Preview))
+	mux.Handle("POST /api/v1/admin/invitations", active(invitationHandler.CreateInvitation))
+	mux.Handle("GET /api/v1/admin/invitations", active(invitationHandler.ListInvitations))
+	mux.Handle("DELETE /api/v1/admin/invitations/{id}", active(invitationHandler.RevokeInvitation))
 
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%s", cfg.Port),
-		Handler:      mux,
+		Handler:      middleware.RequestID(mux),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -114,3 +346,18 @@ func main() {
 	db.Close()
 	log.Println("✅ Auth service stopped gracefully")
 }
+
+// mailTransport returns the mailer the mail queue delivers through, as
+// configured.
+func mailTransport(cfg config.MailConfig) (mailer.Mailer, error) {
+	switch cfg.Transport {
+	case "log":
+		return mailer.NewLogMailer(), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.OutboxFile), nil
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.From, cfg.SMTP.TLS)
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q, expected log, file or smtp", cfg.Transport)
+	}
+}
@@ -7,6 +7,12 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
+	github.com/oschwald/maxminddb-golang v1.13.1
+	github.com/rivo/uniseg v0.4.7
 	golang.org/x/crypto v0.31.0
+	golang.org/x/net v0.33.0
+	golang.org/x/text v0.21.0
 	golang.org/x/time v0.8.0
 )
+
+require golang.org/x/sys v0.28.0 // indirect
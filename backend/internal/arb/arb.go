@@ -0,0 +1,75 @@
+// Package arb reads the Application Resource Bundles the Flutter app is
+// translated with (lib/l10n/app_*.arb) and the ICU messages in them.
+package arb
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidFile = errors.New("not an ARB file")
+
+// File is the content of an ARB file that matters to the server.
+type File struct {
+	// Locale is the file's "@@locale", if it has one
+	Locale string
+
+	// Messages by key
+	Messages map[string]string
+
+	// Placeholders declares the names of the placeholders of each message
+	// that has metadata, by key
+	Placeholders map[string][]string
+}
+
+// Parse reads an ARB file: a JSON object of messages, the "@key" metadata
+// of each and "@@" attributes of the whole file.
+func Parse(data []byte) (*File, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, ErrInvalidFile
+	}
+
+	file := &File{
+		Messages:     make(map[string]string),
+		Placeholders: make(map[string][]string),
+	}
+	for key, value := range raw {
+		switch {
+		case key == "@@locale":
+			if err := json.Unmarshal(value, &file.Locale); err != nil {
+				return nil, ErrInvalidFile
+			}
+		case strings.HasPrefix(key, "@@"):
+			// Other attributes, such as "@@last_modified"
+		case strings.HasPrefix(key, "@"):
+			var metadata struct {
+				Placeholders map[string]json.RawMessage `json:"placeholders"`
+			}
+			if err := json.Unmarshal(value, &metadata); err != nil {
+				return nil, ErrInvalidFile
+			}
+			if metadata.Placeholders == nil {
+				continue
+			}
+			names := make([]string, 0, len(metadata.Placeholders))
+			for name := range metadata.Placeholders {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			file.Placeholders[key[1:]] = names
+		case key == "":
+			return nil, ErrInvalidFile
+		default:
+			var message string
+			if err := json.Unmarshal(value, &message); err != nil {
+				return nil, ErrInvalidFile
+			}
+			file.Messages[key] = message
+		}
+	}
+
+	return file, nil
+}
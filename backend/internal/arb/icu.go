@@ -0,0 +1,177 @@
+package arb
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidMessage = errors.New("invalid ICU message")
+
+// Arguments returns the sorted names of the arguments message uses, e.g.
+// "count" for "{count, plural, =1{One item} other{{count} items}}". Like
+// the Flutter tools by default, it treats apostrophes as plain text rather
+// than as escapes.
+func Arguments(message string) ([]string, error) {
+	p := &parser{input: message, names: make(map[string]bool)}
+	if err := p.message(0); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.input) {
+		// A "}" that closes nothing
+		return nil, ErrInvalidMessage
+	}
+
+	names := make([]string, 0, len(p.names))
+	for name := range p.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// parser reads an ICU message, collecting the names of its arguments.
+type parser struct {
+	input string
+	pos   int
+	names map[string]bool
+}
+
+// message reads text and arguments up to the "}" closing a message nested
+// depth levels deep, or to the end of the input at depth 0.
+func (p *parser) message(depth int) error {
+	for p.pos < len(p.input) {
+		switch p.input[p.pos] {
+		case '{':
+			p.pos++
+			if err := p.argument(depth); err != nil {
+				return err
+			}
+		case '}':
+			// Closes the message, or nothing at depth 0, which Arguments
+			// reports
+			return nil
+		default:
+			p.pos++
+		}
+	}
+	if depth > 0 {
+		return ErrInvalidMessage
+	}
+	return nil
+}
+
+// argument reads an argument after its "{": "{name}", "{name, type}",
+// "{name, type, style}" or "{name, plural|select|selectordinal, cases}".
+func (p *parser) argument(depth int) error {
+	name, end := p.until(",}")
+	if !isIdentifier(name) {
+		return ErrInvalidMessage
+	}
+	p.names[name] = true
+	if end == '}' {
+		return nil
+	}
+
+	kind, end := p.until(",}")
+	switch {
+	case kind == "plural" || kind == "selectordinal" || kind == "select":
+		if end != ',' {
+			return ErrInvalidMessage
+		}
+		return p.cases(depth, kind != "select")
+	case kind == "" || strings.ContainsAny(kind, " \t\n"):
+		return ErrInvalidMessage
+	case end == ',':
+		// A style such as "currency" or "yMMMd"
+		style, end := p.until("{}")
+		if end != '}' || style == "" {
+			return ErrInvalidMessage
+		}
+	}
+	return nil
+}
+
+// cases reads the cases of a plural or select argument up to its closing
+// "}". Plural selectors may be "=n" and be preceded by "offset:n".
+func (p *parser) cases(depth int, plural bool) error {
+	hasOther := false
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.input) {
+			return ErrInvalidMessage
+		}
+		if p.input[p.pos] == '}' {
+			p.pos++
+			if !hasOther {
+				return ErrInvalidMessage
+			}
+			return nil
+		}
+
+		selector, end := p.until("{}")
+		if end != '{' {
+			return ErrInvalidMessage
+		}
+		if plural {
+			if rest, ok := strings.CutPrefix(selector, "offset:"); ok {
+				fields := strings.Fields(rest)
+				if len(fields) != 2 {
+					return ErrInvalidMessage
+				}
+				selector = fields[1]
+			}
+		}
+		if selector == "" || strings.ContainsAny(selector, " \t\n") {
+			return ErrInvalidMessage
+		}
+		hasOther = hasOther || selector == "other"
+
+		if err := p.message(depth + 1); err != nil {
+			return err
+		}
+		if p.pos >= len(p.input) || p.input[p.pos] != '}' {
+			return ErrInvalidMessage
+		}
+		p.pos++
+	}
+}
+
+// until reads up to the first of stops, which it consumes, and returns
+// what it read without surrounding spaces and the stop it found, or 0 at
+// the end of the input.
+func (p *parser) until(stops string) (string, byte) {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if strings.IndexByte(stops, c) >= 0 {
+			p.pos++
+			return strings.TrimSpace(p.input[start : p.pos-1]), c
+		}
+		p.pos++
+	}
+	return strings.TrimSpace(p.input[start:]), 0
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && strings.IndexByte(" \t\n\r", p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// isIdentifier reports whether name can name an argument, which the Flutter
+// tools turn into a Dart parameter.
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"backend/internal/models"
+)
+
+// Sink receives audit events once they are committed, e.g. to forward them
+// to a SIEM.
+type Sink interface {
+	Write(event *models.AuditEvent) error
+}
+
+// NopSink drops every event. The events are still in the audit_events
+// table.
+type NopSink struct{}
+
+func (NopSink) Write(event *models.AuditEvent) error {
+	return nil
+}
+
+// FileSink appends every event as a JSON line to a file, for log shippers
+// to pick up. The file is reopened for every event so it can be rotated
+// underneath.
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+func (s *FileSink) Write(event *models.AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
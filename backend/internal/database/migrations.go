@@ -3,25 +3,36 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/fieldcrypt"
+	"backend/internal/textnorm"
 )
 
-func RunMigrations(db *sql.DB) error {
+// fieldUserEmail is the field users.email is encrypted and indexed as by
+// the user repository.
+const fieldUserEmail = "users.email"
+
+// RunMigrations brings the schema up to date. ring is the field encryption
+// key ring, which data migrations need to find and index email addresses.
+func RunMigrations(db *sql.DB, ring *fieldcrypt.KeyRing) error {
 	migrations := []string{
 		`CREATE TABLE IF NOT EXISTS users (
 			id VARCHAR(36) PRIMARY KEY,
 			email VARCHAR(255) UNIQUE NOT NULL,
 			password_hash VARCHAR(255) NOT NULL,
 			name VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)`,
 		`CREATE TABLE IF NOT EXISTS refresh_tokens (
 			id VARCHAR(36) PRIMARY KEY,
 			user_id VARCHAR(36) NOT NULL,
 			token VARCHAR(500) UNIQUE NOT NULL,
-			expires_at TIMESTAMP NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id)`,
@@ -31,11 +42,309 @@ func RunMigrations(db *sql.DB) error {
 			user_id VARCHAR(36) NOT NULL,
 			feature VARCHAR(100) NOT NULL,
 			count INTEGER DEFAULT 0,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
 			UNIQUE(user_id, feature)
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_user_usage_user_id ON user_usage(user_id)`,
+		`CREATE TABLE IF NOT EXISTS magic_links (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			code_hash VARCHAR(64) NOT NULL,
+			device_id VARCHAR(255) NOT NULL,
+			attempts INTEGER DEFAULT 0,
+			expires_at TIMESTAMPTZ NOT NULL,
+			used_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_magic_links_email ON magic_links(email)`,
+		// Phone-only accounts have no email address
+		`ALTER TABLE users ALTER COLUMN email DROP NOT NULL`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(16) UNIQUE`,
+		`CREATE TABLE IF NOT EXISTS phone_otps (
+			id VARCHAR(36) PRIMARY KEY,
+			phone VARCHAR(16) NOT NULL,
+			code_hash VARCHAR(64) NOT NULL,
+			attempts INTEGER DEFAULT 0,
+			expires_at TIMESTAMPTZ NOT NULL,
+			used_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_phone_otps_phone ON phone_otps(phone)`,
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMPTZ`,
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS amr VARCHAR(100) NOT NULL DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS trusted_devices (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			name VARCHAR(100) NOT NULL DEFAULT '',
+			user_agent VARCHAR(500) NOT NULL DEFAULT '',
+			expires_at TIMESTAMPTZ NOT NULL,
+			last_used_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trusted_devices_user_id ON trusted_devices(user_id)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'`,
+		`CREATE TABLE IF NOT EXISTS login_events (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			method VARCHAR(20) NOT NULL,
+			outcome VARCHAR(30) NOT NULL,
+			ip VARCHAR(45) NOT NULL DEFAULT '',
+			network VARCHAR(50) NOT NULL DEFAULT '',
+			user_agent VARCHAR(500) NOT NULL DEFAULT '',
+			new_device BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_login_events_user_id_created_at ON login_events(user_id, created_at DESC)`,
+		`ALTER TABLE login_events ADD COLUMN IF NOT EXISTS country VARCHAR(2) NOT NULL DEFAULT ''`,
+		`ALTER TABLE login_events ADD COLUMN IF NOT EXISTS city VARCHAR(100) NOT NULL DEFAULT ''`,
+		`ALTER TABLE login_events ADD COLUMN IF NOT EXISTS asn BIGINT NOT NULL DEFAULT 0`,
+		`CREATE INDEX IF NOT EXISTS idx_login_events_method_ip ON login_events(method, ip, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_login_events_method_network ON login_events(method, network, created_at)`,
+		`CREATE TABLE IF NOT EXISTS registration_challenges (
+			id VARCHAR(64) PRIMARY KEY,
+			difficulty INTEGER NOT NULL,
+			ip VARCHAR(45) NOT NULL DEFAULT '',
+			expires_at TIMESTAMPTZ NOT NULL,
+			used_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS risk_score SMALLINT NOT NULL DEFAULT 0`,
+		`CREATE TABLE IF NOT EXISTS invitation_codes (
+			id VARCHAR(36) PRIMARY KEY,
+			code_hash VARCHAR(64) UNIQUE NOT NULL,
+			email VARCHAR(255) NOT NULL DEFAULT '',
+			max_uses INTEGER NOT NULL DEFAULT 1,
+			uses INTEGER NOT NULL DEFAULT 0,
+			created_by VARCHAR(36) NOT NULL DEFAULT '',
+			expires_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email))`,
+		`CREATE TABLE IF NOT EXISTS email_collisions (
+			user_id VARCHAR(36) PRIMARY KEY,
+			email VARCHAR(255) NOT NULL,
+			canonical_email VARCHAR(255) NOT NULL,
+			detected_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS organizations (
+			id VARCHAR(36) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			created_by VARCHAR(36) NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS organization_members (
+			org_id VARCHAR(36) NOT NULL,
+			user_id VARCHAR(36) NOT NULL,
+			role VARCHAR(20) NOT NULL DEFAULT 'member',
+			last_active_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (org_id, user_id),
+			FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members(user_id)`,
+		`CREATE TABLE IF NOT EXISTS organization_invitations (
+			id VARCHAR(36) PRIMARY KEY,
+			org_id VARCHAR(36) NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			role VARCHAR(20) NOT NULL DEFAULT 'member',
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			invited_by VARCHAR(36) NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			accepted_at TIMESTAMPTZ,
+			declined_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_organization_invitations_email ON organization_invitations(LOWER(email))`,
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS org_id VARCHAR(36) NOT NULL DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS email_verifications (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			code_hash VARCHAR(64) NOT NULL,
+			attempts INTEGER DEFAULT 0,
+			expires_at TIMESTAMPTZ NOT NULL,
+			used_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications(user_id)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`,
+		`CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL`,
+		`CREATE TABLE IF NOT EXISTS data_exports (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			file_path VARCHAR(500) NOT NULL DEFAULT '',
+			size BIGINT NOT NULL DEFAULT 0,
+			expires_at TIMESTAMPTZ,
+			completed_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_data_exports_user_id_created_at ON data_exports(user_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_data_exports_status ON data_exports(status)`,
+		// Audit events outlive the accounts they are about, so there is no
+		// foreign key to users
+		`CREATE TABLE IF NOT EXISTS audit_events (
+			seq BIGINT PRIMARY KEY,
+			id VARCHAR(36) UNIQUE NOT NULL,
+			occurred_at TIMESTAMPTZ NOT NULL,
+			action VARCHAR(50) NOT NULL,
+			actor_id VARCHAR(36) NOT NULL DEFAULT '',
+			user_id VARCHAR(36) NOT NULL DEFAULT '',
+			ip VARCHAR(45) NOT NULL DEFAULT '',
+			user_agent VARCHAR(500) NOT NULL DEFAULT '',
+			details JSONB NOT NULL DEFAULT '{}',
+			prev_hash VARCHAR(64) NOT NULL,
+			hash VARCHAR(64) NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id, seq)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events(actor_id, seq)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action, seq)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON audit_events(occurred_at)`,
+		// Only the retention job may delete events, see RetentionRepository
+		`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'DELETE' AND current_setting('audit.retention', true) = 'on' THEN
+				RETURN OLD;
+			END IF;
+			RAISE EXCEPTION 'audit_events is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE TRIGGER audit_events_no_change
+			BEFORE UPDATE OR DELETE ON audit_events
+			FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()`,
+		`CREATE OR REPLACE TRIGGER audit_events_no_truncate
+			BEFORE TRUNCATE ON audit_events
+			FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only()`,
+		`CREATE TABLE IF NOT EXISTS legal_documents (
+			id VARCHAR(36) PRIMARY KEY,
+			kind VARCHAR(20) NOT NULL,
+			version INTEGER NOT NULL,
+			locale VARCHAR(10) NOT NULL,
+			title VARCHAR(255) NOT NULL,
+			body TEXT NOT NULL,
+			mandatory BOOLEAN NOT NULL DEFAULT TRUE,
+			published_by VARCHAR(36) NOT NULL DEFAULT '',
+			published_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (kind, version, locale)
+		)`,
+		`CREATE TABLE IF NOT EXISTS consents (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			kind VARCHAR(20) NOT NULL,
+			version INTEGER NOT NULL,
+			locale VARCHAR(10) NOT NULL,
+			ip VARCHAR(45) NOT NULL DEFAULT '',
+			user_agent VARCHAR(500) NOT NULL DEFAULT '',
+			accepted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_consents_user_id_kind ON consents(user_id, kind, version DESC)`,
+		// Encrypted personal data doesn't fit the original column sizes;
+		// the blind indexes take over lookups and uniqueness
+		`ALTER TABLE users ALTER COLUMN email TYPE TEXT`,
+		`ALTER TABLE users ALTER COLUMN phone TYPE TEXT`,
+		`ALTER TABLE users ALTER COLUMN name TYPE TEXT`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_index VARCHAR(64) UNIQUE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_index VARCHAR(64) UNIQUE`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_login_events_created_at ON login_events(created_at)`,
+		`CREATE TABLE IF NOT EXISTS user_preferences (
+			user_id VARCHAR(36) PRIMARY KEY,
+			locale VARCHAR(10) NOT NULL DEFAULT 'en',
+			theme VARCHAR(10) NOT NULL DEFAULT 'system',
+			time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+			notify_email BOOLEAN NOT NULL DEFAULT TRUE,
+			notify_push BOOLEAN NOT NULL DEFAULT TRUE,
+			notify_marketing BOOLEAN NOT NULL DEFAULT FALSE,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS translation_bundles (
+			id VARCHAR(36) PRIMARY KEY,
+			locale VARCHAR(10) NOT NULL,
+			version INTEGER NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			messages JSONB NOT NULL DEFAULT '{}',
+			uploaded_by VARCHAR(36) NOT NULL DEFAULT '',
+			uploaded_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			reviewed_by VARCHAR(36) NOT NULL DEFAULT '',
+			reviewed_at TIMESTAMPTZ,
+			review_note TEXT NOT NULL DEFAULT '',
+			UNIQUE (locale, version)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_translation_bundles_published ON translation_bundles(locale, version DESC) WHERE status = 'published'`,
+		// The message (recipient, subject and bodies) is stored as one
+		// encrypted JSON document, as it holds sign-in links and codes
+		`CREATE TABLE IF NOT EXISTS mail_queue (
+			id VARCHAR(36) PRIMARY KEY,
+			message TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			sent_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_mail_queue_due ON mail_queue(next_attempt_at) WHERE status = 'pending'`,
+		`CREATE INDEX IF NOT EXISTS idx_mail_queue_status_created_at ON mail_queue(status, created_at)`,
+		// Set once the user has shown they receive mail at their address,
+		// cleared when the address changes
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ`,
+		// When a worker claimed an email it is sending, so emails left
+		// behind by a stopped worker can be told from ones still in flight
+		`ALTER TABLE mail_queue ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ`,
+		// The login methods linked to an account, one row per method. Email
+		// addresses and phone numbers are encrypted and indexed like the
+		// users columns they mirror, so existing ones are copied as they are
+		`CREATE TABLE IF NOT EXISTS user_identities (
+			user_id VARCHAR(36) NOT NULL,
+			method VARCHAR(20) NOT NULL,
+			identifier TEXT NOT NULL DEFAULT '',
+			identifier_index VARCHAR(64),
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, method),
+			UNIQUE (method, identifier_index),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`INSERT INTO user_identities (user_id, method, identifier, identifier_index, created_at)
+			SELECT id, 'email', email, email_index, created_at FROM users WHERE email IS NOT NULL
+			UNION ALL
+			SELECT id, 'password', '', NULL, created_at FROM users WHERE email IS NOT NULL AND password_hash <> ''
+			UNION ALL
+			SELECT id, 'phone', phone, phone_index, created_at FROM users WHERE phone IS NOT NULL
+			ON CONFLICT DO NOTHING`,
+		`ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS country VARCHAR(2) NOT NULL DEFAULT ''`,
+		`ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS city VARCHAR(100) NOT NULL DEFAULT ''`,
+		`ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS asn BIGINT NOT NULL DEFAULT 0`,
+		// Times used to be stored without a time zone, as the wall-clock
+		// time of whoever wrote them. The services run in UTC, so that is
+		// what the times stored so far are taken to be.
+		`DO $$
+		DECLARE
+			col record;
+		BEGIN
+			FOR col IN
+				SELECT table_name, column_name FROM information_schema.columns
+				WHERE table_schema = current_schema() AND data_type = 'timestamp without time zone'
+			LOOP
+				EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ USING %I AT TIME ZONE ''UTC''',
+					col.table_name, col.column_name, col.column_name);
+			END LOOP;
+		END;
+		$$`,
 	}
 
 	for i, migration := range migrations {
@@ -44,5 +353,123 @@ func RunMigrations(db *sql.DB) error {
 		}
 	}
 
+	if err := canonicalizeEmails(db, ring); err != nil {
+		return fmt.Errorf("email canonicalization failed: %w", err)
+	}
+
+	return nil
+}
+
+// canonicalizeEmails rewrites addresses stored before emails were
+// normalized into their canonical form (see textnorm.Email). An address
+// whose canonical form another account already uses, ignoring case, is
+// left as it is and recorded in email_collisions for an administrator to
+// resolve; until then login by email picks the exact match. Encrypted
+// addresses were canonical when they were stored, but are looked for by
+// their blind index when checking for collisions. Safe to run on every
+// start.
+func canonicalizeEmails(db *sql.DB, ring *fieldcrypt.KeyRing) error {
+	rows, err := db.Query(`
+		SELECT id, email
+		FROM users
+		WHERE email NOT LIKE 'enc:%'
+			AND (email <> LOWER(TRIM(email)) OR OCTET_LENGTH(email) <> CHAR_LENGTH(email))
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return err
+	}
+
+	type candidate struct{ id, email string }
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.email); err != nil {
+			rows.Close()
+			return err
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	collisions := 0
+	for _, c := range candidates {
+		canonical, err := textnorm.Email(c.email)
+		if err != nil {
+			log.Printf("⚠️  User %s has an invalid email address %q, leaving it unchanged", c.id, c.email)
+			continue
+		}
+		if canonical == c.email {
+			continue
+		}
+
+		index := sql.NullString{String: ring.Index(fieldUserEmail, canonical)}
+		index.Valid = index.String != ""
+
+		var otherID string
+		err = db.QueryRow(`
+			SELECT id FROM users
+			WHERE id <> $1 AND (email_index = $3 OR (email_index IS NULL AND LOWER(email) = LOWER($2)))
+			LIMIT 1
+		`, c.id, canonical, index).Scan(&otherID)
+
+		if err == sql.ErrNoRows {
+			// The index has to follow the address, or lookups by it miss
+			// the user until the row is re-encrypted
+			if err := rewriteEmail(db, c.id, canonical, index); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		collisions++
+		_, err = db.Exec(`
+			INSERT INTO email_collisions (user_id, email, canonical_email)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id) DO NOTHING
+		`, c.id, c.email, canonical)
+		if err != nil {
+			return err
+		}
+	}
+
+	if collisions > 0 {
+		log.Printf("⚠️  %d user(s) have email addresses that collide with another account; see the email_collisions table", collisions)
+	}
+
 	return nil
 }
+
+// rewriteEmail sets the email address of userID and of the login method
+// that mirrors it to email, with blind index index.
+func rewriteEmail(db *sql.DB, userID, email string, index sql.NullString) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`UPDATE users SET email = $1, email_index = $2, updated_at = $3 WHERE id = $4`,
+		email, index, time.Now(), userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE user_identities SET identifier = $1, identifier_index = $2 WHERE user_id = $3 AND method = 'email'`,
+		email, index, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
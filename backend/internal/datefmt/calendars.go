@@ -0,0 +1,89 @@
+package datefmt
+
+import "time"
+
+// julianDay returns the Julian day number of the date of t.
+func julianDay(t time.Time) int {
+	year, month, day := t.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return int(midnight.Unix()/86400) + 2440588
+}
+
+// persianBreaks are the Jalali years in which the pattern of leap years
+// changes; see https://github.com/jalaali/jalaali-js.
+var persianBreaks = []int{
+	-61, 9, 38, 199, 426, 686, 756, 818, 1111, 1181, 1210,
+	1635, 2060, 2097, 2192, 2262, 2324, 2394, 2456, 3178,
+}
+
+// persianYear returns the day of March the Jalali year jy starts on and
+// the number of years since the last leap year, 0 if jy is one.
+func persianYear(jy int) (march, sinceLeap int) {
+	gy := jy + 621
+	leapJ := -14
+	jp := persianBreaks[0]
+	jump := 0
+	for _, jm := range persianBreaks[1:] {
+		jump = jm - jp
+		if jy < jm {
+			break
+		}
+		leapJ += jump/33*8 + jump%33/4
+		jp = jm
+	}
+	n := jy - jp
+
+	leapJ += n/33*8 + (n%33+3)/4
+	if jump%33 == 4 && jump-n == 4 {
+		leapJ++
+	}
+	leapG := gy/4 - (gy/100+1)*3/4 - 150
+	march = 20 + leapJ - leapG
+
+	if jump-n < 6 {
+		n = n - jump + (jump+4)/33*33
+	}
+	sinceLeap = ((n+1)%33 - 1) % 4
+	if sinceLeap == -1 {
+		sinceLeap = 4
+	}
+	return march, sinceLeap
+}
+
+// persianDate returns the Jalali year, month and day of the date of t.
+func persianDate(t time.Time) (int, int, int) {
+	gy := t.Year()
+	jy := gy - 621
+
+	march, sinceLeap := persianYear(jy)
+	k := julianDay(t) - julianDay(time.Date(gy, time.March, march, 0, 0, 0, 0, time.UTC))
+	switch {
+	case k < 0:
+		// Before Nowruz, in the last months of the previous year, which
+		// has 30 days in Esfand if it is a leap year
+		jy--
+		k += 179
+		if sinceLeap == 1 {
+			k++
+		}
+	case k <= 185:
+		return jy, 1 + k/31, k%31 + 1
+	default:
+		k -= 186
+	}
+	return jy, 7 + k/30, k%30 + 1
+}
+
+// islamicDate returns the year, month and day of the date of t in the
+// tabular Islamic calendar.
+func islamicDate(t time.Time) (int, int, int) {
+	l := julianDay(t) - 1948440 + 10632
+	n := (l - 1) / 10631
+	l = l - 10631*n + 354
+	j := (10985-l)/5316*(50*l/17719) + l/5670*(43*l/15238)
+	l = l - (30-j)/15*(17719*j/50) - j/16*(15238*j/43) + 29
+	month := 24 * l / 709
+	day := l - 709*month/24
+	year := 30*n + j - 30
+	return year, month, day
+}
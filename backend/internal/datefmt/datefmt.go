@@ -0,0 +1,136 @@
+// Package datefmt formats the times the API returns: as RFC 3339
+// timestamps in UTC for clients to parse, and for display in the user's
+// language, calendar and time zone.
+package datefmt
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar is a calendar dates can be displayed in, named by its Unicode
+// calendar identifier as used in the "u-ca" extension of language tags.
+type Calendar string
+
+const (
+	Gregorian Calendar = "gregory"
+
+	// Persian is the Solar Hijri (Jalali) calendar used in Iran and
+	// Afghanistan
+	Persian Calendar = "persian"
+
+	// Islamic is the tabular Hijri calendar. It is computed rather than
+	// based on sighting the moon, so it can be a day off from the
+	// calendars announced in some countries.
+	Islamic Calendar = "islamic"
+)
+
+// Timestamp formats t as an RFC 3339 timestamp in UTC, the format of every
+// time the API returns.
+func Timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Number writes n in the digits of locale, e.g. "۱۵" in Persian.
+func Number(n int, locale string) string {
+	return localizeDigits(strconv.Itoa(n), locale)
+}
+
+// DefaultCalendar returns the calendar dates are displayed in for locale, a
+// supported language such as "fa".
+func DefaultCalendar(locale string) Calendar {
+	if locale == "fa" {
+		return Persian
+	}
+	return Gregorian
+}
+
+// ParseCalendar returns the calendar asked for by the first language tag in
+// acceptLanguage, an Accept-Language header value, with a "u-ca" extension,
+// as in "ar-SA-u-ca-islamic". It returns "" if there is none or the
+// calendar isn't supported.
+func ParseCalendar(acceptLanguage string) Calendar {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		subtags := strings.Split(strings.ToLower(strings.TrimSpace(tag)), "-")
+		for i := 0; i+2 < len(subtags); i++ {
+			if subtags[i] != "u" || subtags[i+1] != "ca" {
+				continue
+			}
+			switch subtags[i+2] {
+			case "gregory":
+				return Gregorian
+			case "persian":
+				return Persian
+			case "islamic":
+				// islamic-civil, islamic-umalqura and the other variants
+				// are all shown as the tabular calendar
+				return Islamic
+			}
+		}
+	}
+	return ""
+}
+
+// Formatter displays times in a language, calendar and time zone.
+type Formatter struct {
+	locale   string
+	calendar Calendar
+	location *time.Location
+}
+
+// New returns a formatter for locale, a supported language such as "fa".
+// An empty calendar is the locale's default and a nil location is UTC.
+func New(locale string, calendar Calendar, location *time.Location) *Formatter {
+	if calendar == "" {
+		calendar = DefaultCalendar(locale)
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	return &Formatter{
+		locale:   locale,
+		calendar: calendar,
+		location: location,
+	}
+}
+
+// Format displays the date and time of t, e.g. "۲۳ مهر ۱۴۰۳، ۱۵:۰۴" in
+// Persian or "14 Ekim 2024 15:04" in Turkish.
+func (f *Formatter) Format(t time.Time) string {
+	t = t.In(f.location)
+
+	year, month, day := f.date(t)
+	clock := t.Format("15:04")
+	monthName := monthNames(f.calendar, f.locale)[month-1]
+
+	var s string
+	switch f.locale {
+	case "en":
+		s = monthName + " " + strconv.Itoa(day) + ", " + strconv.Itoa(year) + " " + clock
+	case "ar", "fa":
+		s = strconv.Itoa(day) + " " + monthName + " " + strconv.Itoa(year)
+		if f.calendar == Islamic {
+			s += " هـ"
+		}
+		s += "، " + clock
+	default:
+		s = strconv.Itoa(day) + " " + monthName + " " + strconv.Itoa(year) + " " + clock
+	}
+	return localizeDigits(s, f.locale)
+}
+
+// date returns the year, month (1-12) and day of t in the formatter's
+// calendar.
+func (f *Formatter) date(t time.Time) (int, int, int) {
+	switch f.calendar {
+	case Persian:
+		return persianDate(t)
+	case Islamic:
+		return islamicDate(t)
+	default:
+		year, month, day := t.Date()
+		return year, int(month), day
+	}
+}
@@ -0,0 +1,63 @@
+package datefmt
+
+import "strings"
+
+// months holds the month names of each calendar by language. Languages
+// without their own names for a calendar's months use its first entry.
+var months = map[Calendar][]struct {
+	locale string
+	names  [12]string
+}{
+	Gregorian: {
+		{"en", [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}},
+		{"ar", [12]string{"يناير", "فبراير", "مارس", "أبريل", "مايو", "يونيو", "يوليو", "أغسطس", "سبتمبر", "أكتوبر", "نوفمبر", "ديسمبر"}},
+		{"fa", [12]string{"ژانویه", "فوریه", "مارس", "آوریل", "مه", "ژوئن", "ژوئیه", "اوت", "سپتامبر", "اکتبر", "نوامبر", "دسامبر"}},
+		{"tr", [12]string{"Ocak", "Şubat", "Mart", "Nisan", "Mayıs", "Haziran", "Temmuz", "Ağustos", "Eylül", "Ekim", "Kasım", "Aralık"}},
+	},
+	Persian: {
+		{"en", [12]string{"Farvardin", "Ordibehesht", "Khordad", "Tir", "Mordad", "Shahrivar", "Mehr", "Aban", "Azar", "Dey", "Bahman", "Esfand"}},
+		{"fa", [12]string{"فروردین", "اردیبهشت", "خرداد", "تیر", "مرداد", "شهریور", "مهر", "آبان", "آذر", "دی", "بهمن", "اسفند"}},
+		{"ar", [12]string{"فروردين", "أرديبهشت", "خرداد", "تير", "مرداد", "شهريور", "مهر", "آبان", "آذر", "دي", "بهمن", "اسفند"}},
+		{"tr", [12]string{"Ferverdin", "Ordibehişt", "Hordad", "Tir", "Mordad", "Şehriver", "Mehr", "Aban", "Azer", "Dey", "Behmen", "Esfend"}},
+	},
+	Islamic: {
+		{"en", [12]string{"Muharram", "Safar", "Rabi' al-awwal", "Rabi' al-thani", "Jumada al-awwal", "Jumada al-thani", "Rajab", "Sha'ban", "Ramadan", "Shawwal", "Dhu al-Qi'dah", "Dhu al-Hijjah"}},
+		{"ar", [12]string{"محرم", "صفر", "ربيع الأول", "ربيع الآخر", "جمادى الأولى", "جمادى الآخرة", "رجب", "شعبان", "رمضان", "شوال", "ذو القعدة", "ذو الحجة"}},
+		{"fa", [12]string{"محرم", "صفر", "ربیع‌الاول", "ربیع‌الثانی", "جمادی‌الاول", "جمادی‌الثانی", "رجب", "شعبان", "رمضان", "شوال", "ذیقعده", "ذیحجه"}},
+		{"tr", [12]string{"Muharrem", "Safer", "Rebiülevvel", "Rebiülahir", "Cemaziyelevvel", "Cemaziyelahir", "Recep", "Şaban", "Ramazan", "Şevval", "Zilkade", "Zilhicce"}},
+	},
+}
+
+// monthNames returns the names of the months of calendar in locale.
+func monthNames(calendar Calendar, locale string) [12]string {
+	names := months[calendar]
+	for _, n := range names {
+		if n.locale == locale {
+			return n.names
+		}
+	}
+	return names[0].names
+}
+
+// digits holds the digits zero to nine of the languages that don't write
+// numbers in Latin digits.
+var digits = map[string]string{
+	"ar": "٠١٢٣٤٥٦٧٨٩",
+	"fa": "۰۱۲۳۴۵۶۷۸۹",
+}
+
+// localizeDigits replaces the Latin digits in s with those of locale.
+func localizeDigits(s, locale string) string {
+	native, ok := digits[locale]
+	if !ok {
+		return s
+	}
+	zero := []rune(native)
+
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return zero[r-'0']
+		}
+		return r
+	}, s)
+}
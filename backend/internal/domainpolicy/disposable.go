@@ -0,0 +1,70 @@
+package domainpolicy
+
+// disposableDomains lists widely used throwaway email providers. Domains
+// that need blocking beyond these belong in the deny list file.
+var disposableDomains = map[string]bool{
+	"10minutemail.com":       true,
+	"10minutemail.net":       true,
+	"20minutemail.com":       true,
+	"33mail.com":             true,
+	"anonaddy.me":            true,
+	"burnermail.io":          true,
+	"discard.email":          true,
+	"dispostable.com":        true,
+	"dropmail.me":            true,
+	"emailondeck.com":        true,
+	"fakeinbox.com":          true,
+	"fakemail.net":           true,
+	"getairmail.com":         true,
+	"getnada.com":            true,
+	"guerrillamail.biz":      true,
+	"guerrillamail.com":      true,
+	"guerrillamail.de":       true,
+	"guerrillamail.info":     true,
+	"guerrillamail.net":      true,
+	"guerrillamail.org":      true,
+	"guerrillamailblock.com": true,
+	"harakirimail.com":       true,
+	"inboxbear.com":          true,
+	"inboxkitten.com":        true,
+	"incognitomail.org":      true,
+	"jetable.org":            true,
+	"mail-temp.com":          true,
+	"mailcatch.com":          true,
+	"maildrop.cc":            true,
+	"mailinator.com":         true,
+	"mailinator.net":         true,
+	"mailinator2.com":        true,
+	"mailnesia.com":          true,
+	"mailpoof.com":           true,
+	"mailsac.com":            true,
+	"mintemail.com":          true,
+	"mohmal.com":             true,
+	"mytemp.email":           true,
+	"mytrashmail.com":        true,
+	"nada.email":             true,
+	"sharklasers.com":        true,
+	"spam4.me":               true,
+	"spambox.us":             true,
+	"spamgourmet.com":        true,
+	"spamex.com":             true,
+	"tempail.com":            true,
+	"tempinbox.com":          true,
+	"tempmail.com":           true,
+	"tempmail.net":           true,
+	"tempmail.plus":          true,
+	"tempmailaddress.com":    true,
+	"tempmailo.com":          true,
+	"temp-mail.io":           true,
+	"temp-mail.org":          true,
+	"tempr.email":            true,
+	"throwawaymail.com":      true,
+	"trashmail.com":          true,
+	"trashmail.de":           true,
+	"trashmail.net":          true,
+	"trbvm.com":              true,
+	"wegwerfmail.de":         true,
+	"yopmail.com":            true,
+	"yopmail.fr":             true,
+	"yopmail.net":            true,
+}
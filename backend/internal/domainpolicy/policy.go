@@ -0,0 +1,199 @@
+package domainpolicy
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/textnorm"
+)
+
+// Verdict is the outcome of checking an email domain against the policy.
+type Verdict int
+
+const (
+	Allowed Verdict = iota
+
+	// NotAllowed means an allow list is configured and the domain isn't on it.
+	NotAllowed
+
+	// Denied means the domain is on the deny list.
+	Denied
+
+	// Disposable means the domain belongs to a throwaway email provider.
+	Disposable
+)
+
+// list is a set of domains read from a file with one domain per line. Blank
+// lines and lines starting with # are ignored.
+type list struct {
+	path    string
+	domains map[string]bool
+	modTime time.Time
+}
+
+// Policy decides which email domains may be used to register. Allow and
+// deny lists are read from files and reloaded when they change on disk.
+type Policy struct {
+	allow           *list
+	deny            *list
+	blockDisposable bool
+	mu              sync.RWMutex
+}
+
+// NewPolicy loads the allow and deny list files, either of which may be
+// empty to skip it. Files that change are reloaded every reloadInterval.
+// With blockDisposable, domains on the built-in list of disposable email
+// providers are rejected too.
+func NewPolicy(allowFile, denyFile string, blockDisposable bool, reloadInterval time.Duration) (*Policy, error) {
+	p := &Policy{blockDisposable: blockDisposable}
+
+	var err error
+	if p.allow, err = openList(allowFile); err != nil {
+		return nil, err
+	}
+	if p.deny, err = openList(denyFile); err != nil {
+		return nil, err
+	}
+
+	if p.allow != nil || p.deny != nil {
+		go p.watch(reloadInterval)
+	}
+
+	return p, nil
+}
+
+// Restricted reports whether an allow list is configured, so that only
+// addresses at its domains may register.
+func (p *Policy) Restricted() bool {
+	return p.allow != nil
+}
+
+// Check returns the verdict for the domain of email. A domain on a list
+// also covers its subdomains, so "example.com" matches "mail.example.com".
+// The deny list wins over the allow list, and allow-listed domains are
+// never treated as disposable.
+func (p *Policy) Check(email string) Verdict {
+	domain := Domain(email)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.deny != nil && matches(p.deny.domains, domain) {
+		return Denied
+	}
+
+	if p.allow != nil {
+		if matches(p.allow.domains, domain) {
+			return Allowed
+		}
+		return NotAllowed
+	}
+
+	if p.blockDisposable && matches(disposableDomains, domain) {
+		return Disposable
+	}
+
+	return Allowed
+}
+
+// Domain returns the domain part of email in lower-case ASCII form.
+func Domain(email string) string {
+	return canonicalDomain(email[strings.LastIndex(email, "@")+1:])
+}
+
+// canonicalDomain converts internationalized domains to ASCII so list
+// entries and addresses compare equal however they were written.
+func canonicalDomain(domain string) string {
+	if ascii, err := textnorm.Domain(domain); err == nil {
+		return ascii
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+func matches(domains map[string]bool, domain string) bool {
+	for domain != "" {
+		if domains[domain] {
+			return true
+		}
+
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
+
+func (p *Policy) watch(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+
+		for _, l := range []*list{p.allow, p.deny} {
+			if l == nil {
+				continue
+			}
+
+			info, err := os.Stat(l.path)
+			if err != nil || info.ModTime().Equal(l.modTime) {
+				continue
+			}
+
+			domains, err := readDomains(l.path)
+			if err != nil {
+				// Keep enforcing the old list, e.g. while it's being rewritten
+				log.Printf("Reload of email domain list %s failed: %v", l.path, err)
+				continue
+			}
+
+			p.mu.Lock()
+			l.domains = domains
+			l.modTime = info.ModTime()
+			p.mu.Unlock()
+
+			log.Printf("📧 Email domain list %s reloaded (%d domains)", l.path, len(domains))
+		}
+	}
+}
+
+func openList(path string) (*list, error) {
+	if path = strings.TrimSpace(path); path == "" {
+		return nil, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	domains, err := readDomains(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list{path: path, domains: domains, modTime: info.ModTime()}, nil
+}
+
+func readDomains(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	domains := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[canonicalDomain(strings.TrimPrefix(line, "@"))] = true
+	}
+
+	return domains, scanner.Err()
+}
@@ -0,0 +1,309 @@
+package fieldcrypt
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Prefix starts every encrypted value, so values stored before encryption
+// was turned on can be told apart and read as they are.
+const Prefix = "enc:"
+
+const keySize = 32
+
+var (
+	ErrUnknownKey = errors.New("value is encrypted with a key that is not in the key ring")
+	ErrMalformed  = errors.New("malformed encrypted value")
+)
+
+// KeyRing encrypts and decrypts personal data stored in database columns.
+//
+// Values are envelope-encrypted: each one gets a fresh data key that
+// encrypts it with AES-256-GCM, and the data key is stored alongside,
+// encrypted with the active key encryption key of the ring. The format is
+//
+//	enc:v<key version>:<wrapped data key>:<nonce and ciphertext>
+//
+// Keys are versioned so they can be rotated: new values use the active
+// key, older keys stay in the ring to decrypt what they encrypted until it
+// has been re-encrypted. A field's name is authenticated with its value,
+// so ciphertext can't be moved to another column.
+//
+// Encrypted values can't be searched, so the ring also computes blind
+// indexes: keyed hashes of a value that can be compared for equality.
+//
+// An empty ring stores values as they are.
+type KeyRing struct {
+	keys     map[int][]byte
+	active   int
+	indexKey []byte
+}
+
+// LoadKeyRing builds a ring from keys, a comma-separated list of
+// "<version>:<base64 key>" pairs, and file, which holds such pairs one per
+// line. A pair named "index" instead of a version is the blind index key;
+// indexKey sets it too. active picks the version new values are encrypted
+// with, 0 meaning the highest. With no keys at all the ring is empty.
+func LoadKeyRing(keys, file string, active int, indexKey string) (*KeyRing, error) {
+	entries := splitList(keys)
+	if file != "" {
+		fileEntries, err := readKeyFile(file)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	if indexKey != "" {
+		entries = append(entries, "index:"+indexKey)
+	}
+
+	ring := &KeyRing{keys: map[int][]byte{}}
+	for _, entry := range entries {
+		name, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, errors.New("field encryption keys must be given as <version>:<base64 key>")
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("field encryption key %s must be %d bytes, base64-encoded", name, keySize)
+		}
+
+		name = strings.TrimSpace(name)
+		if name == "index" {
+			ring.indexKey = key
+			continue
+		}
+
+		version, err := strconv.Atoi(name)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("field encryption key version %q must be a positive number", name)
+		}
+		if _, ok := ring.keys[version]; ok {
+			return nil, fmt.Errorf("field encryption key version %d is defined twice", version)
+		}
+		ring.keys[version] = key
+		ring.active = max(ring.active, version)
+	}
+
+	if len(ring.keys) == 0 {
+		if ring.indexKey != nil || active != 0 {
+			return nil, errors.New("field encryption needs at least one key")
+		}
+		return ring, nil
+	}
+
+	if ring.indexKey == nil {
+		return nil, errors.New("field encryption needs a blind index key")
+	}
+
+	if active != 0 {
+		if _, ok := ring.keys[active]; !ok {
+			return nil, fmt.Errorf("active field encryption key %d is not in the key ring", active)
+		}
+		ring.active = active
+	}
+
+	return ring, nil
+}
+
+// Enabled reports whether the ring has keys.
+func (k *KeyRing) Enabled() bool {
+	return len(k.keys) > 0
+}
+
+// ActiveVersion returns the version of the key new values are encrypted
+// with, or 0 if the ring is empty.
+func (k *KeyRing) ActiveVersion() int {
+	return k.active
+}
+
+// ActivePrefix returns how values encrypted with the active key start.
+func (k *KeyRing) ActivePrefix() string {
+	return k.prefix(k.active)
+}
+
+// Prefixes returns how values encrypted with any key of the ring start.
+func (k *KeyRing) Prefixes() []string {
+	prefixes := make([]string, 0, len(k.keys))
+	for version := range k.keys {
+		prefixes = append(prefixes, k.prefix(version))
+	}
+	return prefixes
+}
+
+func (k *KeyRing) prefix(version int) string {
+	return Prefix + "v" + strconv.Itoa(version) + ":"
+}
+
+// Encrypt encrypts value of field with the active key. Empty values and
+// values given to an empty ring are returned as they are.
+func (k *KeyRing) Encrypt(field, value string) (string, error) {
+	if value == "" || !k.Enabled() {
+		return value, nil
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(k.keys[k.active], dataKey, []byte(field))
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(dataKey, []byte(value), []byte(field))
+	if err != nil {
+		return "", err
+	}
+
+	return k.ActivePrefix() +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt returns the plaintext of value, which Encrypt produced for
+// field. Values without Prefix were stored unencrypted and are returned as
+// they are.
+func (k *KeyRing) Decrypt(field, value string) (string, error) {
+	if !strings.HasPrefix(value, Prefix) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, Prefix), ":")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "v") {
+		return "", ErrMalformed
+	}
+
+	version, err := strconv.Atoi(parts[0][1:])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	key, ok := k.keys[version]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	dataKey, err := open(key, wrappedKey, []byte(field))
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dataKey, ciphertext, []byte(field))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// Index returns the blind index of value of field: equal values give equal
+// indexes, but the index doesn't reveal the value. It returns "" for empty
+// values and for an empty ring. The index key can't be rotated without
+// recomputing every index.
+func (k *KeyRing) Index(field, value string) string {
+	if value == "" || !k.Enabled() {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal encrypts plaintext with AES-GCM under key, prepending the nonce.
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open reverses seal.
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readKeyFile reads the key pairs in path, skipping blank lines and
+// comments.
+func readKeyFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+
+	return entries, scanner.Err()
+}
+
+func splitList(value string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
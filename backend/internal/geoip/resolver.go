@@ -0,0 +1,204 @@
+package geoip
+
+import (
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is what the databases know about an IP address. Fields are left
+// empty when no database is configured or the address isn't found.
+type Location struct {
+	Country string `json:"country,omitempty"`
+	City    string `json:"city,omitempty"`
+	ASN     uint   `json:"asn,omitempty"`
+	ASOrg   string `json:"as_org,omitempty"`
+}
+
+// record covers the fields of the GeoLite2/GeoIP2 City, Country and ASN
+// databases, so one struct can decode any of them or a combined file.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN   uint   `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+type database struct {
+	path    string
+	reader  *maxminddb.Reader
+	modTime time.Time
+}
+
+// Resolver looks up IP addresses in local MaxMind-format (MMDB) files and
+// reloads a file when it changes on disk.
+type Resolver struct {
+	databases []*database
+	mu        sync.RWMutex
+
+	// Closed by Close to stop the reload goroutine
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewResolver opens the MMDB files at paths, ignoring empty entries. With no
+// paths every lookup returns an empty Location. Files that change are
+// reloaded every reloadInterval.
+func NewResolver(paths []string, reloadInterval time.Duration) (*Resolver, error) {
+	r := &Resolver{done: make(chan struct{})}
+
+	for _, path := range paths {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+
+		db := &database{path: path}
+		if err := db.open(); err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.databases = append(r.databases, db)
+	}
+
+	if len(r.databases) > 0 {
+		go r.watch(reloadInterval)
+	}
+
+	return r, nil
+}
+
+// Enabled reports whether any database is loaded.
+func (r *Resolver) Enabled() bool {
+	return len(r.databases) > 0
+}
+
+// Lookup resolves ip, which may carry a port or be an X-Forwarded-For list
+// (the last entry, added by the nearest proxy, is used; the ones before it
+// can be forged by the client).
+func (r *Resolver) Lookup(ip string) Location {
+	var loc Location
+	if !r.Enabled() {
+		return loc
+	}
+
+	parsed := parseIP(ip)
+	if parsed == nil {
+		return loc
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, db := range r.databases {
+		var rec record
+		if err := db.reader.Lookup(parsed, &rec); err != nil {
+			log.Printf("GeoIP lookup in %s failed: %v", db.path, err)
+			continue
+		}
+
+		if loc.Country == "" {
+			loc.Country = rec.Country.ISOCode
+		}
+		if loc.City == "" {
+			loc.City = rec.City.Names["en"]
+		}
+		if loc.ASN == 0 {
+			loc.ASN = rec.ASN
+			loc.ASOrg = rec.ASOrg
+		}
+	}
+
+	return loc
+}
+
+// Close stops reloading and closes the databases.
+func (r *Resolver) Close() {
+	r.closeOnce.Do(func() { close(r.done) })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, db := range r.databases {
+		if db.reader != nil {
+			db.reader.Close()
+		}
+	}
+}
+
+func (r *Resolver) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
+
+		for _, db := range r.databases {
+			info, err := os.Stat(db.path)
+			if err != nil || info.ModTime().Equal(db.modTime) {
+				continue
+			}
+
+			reader, err := maxminddb.Open(db.path)
+			if err != nil {
+				// Keep serving from the old file, e.g. while it's being copied
+				log.Printf("GeoIP reload of %s failed: %v", db.path, err)
+				continue
+			}
+
+			r.mu.Lock()
+			select {
+			case <-r.done:
+				// Closed while the new file was being opened
+				r.mu.Unlock()
+				reader.Close()
+				return
+			default:
+			}
+			old := db.reader
+			db.reader = reader
+			db.modTime = info.ModTime()
+			r.mu.Unlock()
+
+			old.Close()
+			log.Printf("🌍 GeoIP database %s reloaded", db.path)
+		}
+	}
+}
+
+func (db *database) open() error {
+	info, err := os.Stat(db.path)
+	if err != nil {
+		return err
+	}
+
+	reader, err := maxminddb.Open(db.path)
+	if err != nil {
+		return err
+	}
+
+	db.reader = reader
+	db.modTime = info.ModTime()
+	return nil
+}
+
+func parseIP(ip string) net.IP {
+	entries := strings.Split(ip, ",")
+	ip = strings.TrimSpace(entries[len(entries)-1])
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return net.ParseIP(ip)
+}
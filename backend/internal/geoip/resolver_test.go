@@ -0,0 +1,171 @@
+package geoip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// The fixtures in testdata are built by testdata/generate.go and know one
+// network, 81.2.69.0/24.
+const (
+	cityDB = "testdata/city.mmdb"
+	asnDB  = "testdata/asn.mmdb"
+)
+
+var london = Location{Country: "GB", City: "London", ASN: 20712, ASOrg: "Andrews & Arnold Ltd"}
+
+func TestLookup(t *testing.T) {
+	r, err := NewResolver([]string{cityDB, " ", asnDB}, time.Hour)
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+	defer r.Close()
+
+	if !r.Enabled() {
+		t.Fatal("Enabled() = false with two databases")
+	}
+
+	tests := []struct {
+		name string
+		ip   string
+		want Location
+	}{
+		{"address", "81.2.69.142", london},
+		{"address with port", "81.2.69.142:51234", london},
+		{"forwarded by a proxy", "203.0.113.7, 81.2.69.142", london},
+		{"forged forwarded entry", "81.2.69.142, 203.0.113.7", Location{}},
+		{"private", "10.0.0.1", Location{}},
+		{"private class C", "192.168.1.20", Location{}},
+		{"loopback", "127.0.0.1", Location{}},
+		{"unknown", "8.8.8.8", Location{}},
+		{"not an address", "example.com", Location{}},
+		{"empty", "", Location{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Lookup(tt.ip); got != tt.want {
+				t.Errorf("Lookup(%q) = %+v, want %+v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupWithoutDatabases(t *testing.T) {
+	r, err := NewResolver(nil, time.Hour)
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+	defer r.Close()
+
+	if r.Enabled() {
+		t.Error("Enabled() = true without databases")
+	}
+	if got := r.Lookup("81.2.69.142"); got != (Location{}) {
+		t.Errorf("Lookup = %+v, want an empty location", got)
+	}
+}
+
+func TestNewResolverMissingDatabase(t *testing.T) {
+	if _, err := NewResolver([]string{"testdata/missing.mmdb"}, time.Hour); err == nil {
+		t.Error("NewResolver with a missing file succeeded")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geo.mmdb")
+	copyFile(t, cityDB, path)
+
+	r, err := NewResolver([]string{path}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+	defer r.Close()
+
+	if got := r.Lookup("81.2.69.142"); got.Country != "GB" || got.ASN != 0 {
+		t.Fatalf("Lookup before reload = %+v, want the city database", got)
+	}
+
+	// Replace the file the way updaters do, by renaming a new one over it
+	replace(t, path, asnDB, time.Minute)
+	asn := Location{ASN: london.ASN, ASOrg: london.ASOrg}
+	waitFor(t, func() bool { return r.Lookup("81.2.69.142") == asn })
+
+	// A broken file is not loaded; the old one keeps serving
+	corrupt := filepath.Join(t.TempDir(), "corrupt.mmdb")
+	if err := os.WriteFile(corrupt, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	replace(t, path, corrupt, 2*time.Minute)
+	time.Sleep(100 * time.Millisecond)
+	if got := r.Lookup("81.2.69.142"); got != asn {
+		t.Errorf("Lookup after a broken update = %+v, want %+v", got, asn)
+	}
+}
+
+func TestCloseStopsReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geo.mmdb")
+	copyFile(t, cityDB, path)
+
+	r, err := NewResolver([]string{path}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+	r.Close()
+	r.Close()
+
+	r.mu.RLock()
+	closed := r.databases[0].reader
+	r.mu.RUnlock()
+
+	replace(t, path, asnDB, time.Minute)
+	time.Sleep(100 * time.Millisecond)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.databases[0].reader != closed {
+		t.Error("database reloaded after Close")
+	}
+}
+
+// replace renames a copy of src over path and moves its modification time
+// ahead by skew, so the change is seen even on coarse file system clocks.
+func replace(t *testing.T, path, src string, skew time.Duration) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	copyFile(t, src, tmp)
+	future := time.Now().Add(skew)
+	if err := os.Chtimes(tmp, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitFor(t *testing.T, done func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the database to reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
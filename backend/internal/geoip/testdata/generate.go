@@ -0,0 +1,199 @@
+//go:build ignore
+
+// Generates the MMDB fixtures of the geoip tests:
+//
+//	go run generate.go
+//
+// city.mmdb places 81.2.69.0/24 in London, GB, and asn.mmdb in AS 20712.
+// Both are IPv4 databases in the MaxMind DB format, version 2.0.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"time"
+)
+
+func main() {
+	write("city.mmdb", "GeoIP2-City-Test", map[string]interface{}{
+		"81.2.69.0/24": map[string]interface{}{
+			"country": map[string]interface{}{"iso_code": "GB"},
+			"city":    map[string]interface{}{"names": map[string]interface{}{"en": "London"}},
+		},
+	})
+	write("asn.mmdb", "GeoLite2-ASN-Test", map[string]interface{}{
+		"81.2.69.0/24": map[string]interface{}{
+			"autonomous_system_number":       uint32(20712),
+			"autonomous_system_organization": "Andrews & Arnold Ltd",
+		},
+	})
+}
+
+// node is a node of the search tree. A child is another node, data (an
+// offset into the data section), or nothing.
+type node struct {
+	children [2]*node
+	data     [2]int
+	number   int
+}
+
+func write(path, databaseType string, networks map[string]interface{}) {
+	root := &node{data: [2]int{-1, -1}}
+	var section bytes.Buffer
+
+	cidrs := make([]string, 0, len(networks))
+	for cidr := range networks {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ip := network.IP.To4()
+		bits, _ := network.Mask.Size()
+
+		offset := section.Len()
+		encode(&section, networks[cidr])
+
+		n := root
+		for i := 0; i < bits; i++ {
+			bit := ip[i/8] >> (7 - i%8) & 1
+			if i == bits-1 {
+				n.data[bit] = offset
+				break
+			}
+			if n.children[bit] == nil {
+				n.children[bit] = &node{data: [2]int{-1, -1}}
+			}
+			n = n.children[bit]
+		}
+	}
+
+	// Number the nodes breadth first, the root being 0
+	var nodes []*node
+	queue := []*node{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		n.number = len(nodes)
+		nodes = append(nodes, n)
+		for _, child := range n.children {
+			if child != nil {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	count := len(nodes)
+	for _, n := range nodes {
+		for bit := 0; bit < 2; bit++ {
+			record := count // no data
+			switch {
+			case n.children[bit] != nil:
+				record = n.children[bit].number
+			case n.data[bit] >= 0:
+				record = count + 16 + n.data[bit]
+			}
+			out.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(section.Bytes())
+
+	out.WriteString("\xab\xcd\xefMaxMind.com")
+	encode(&out, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()),
+		"database_type":               databaseType,
+		"description":                 map[string]interface{}{"en": "Fixture of the geoip tests"},
+		"ip_version":                  uint16(4),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+	})
+
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Types of the MaxMind DB data section
+const (
+	typeString = 2
+	typeUint16 = 5
+	typeUint32 = 6
+	typeMap    = 7
+	typeUint64 = 9
+	typeArray  = 11
+)
+
+func encode(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		control(buf, typeString, len(v))
+		buf.WriteString(v)
+	case uint16:
+		writeUint(buf, typeUint16, uint64(v))
+	case uint32:
+		writeUint(buf, typeUint32, uint64(v))
+	case uint64:
+		writeUint(buf, typeUint64, v)
+	case []interface{}:
+		control(buf, typeArray, len(v))
+		for _, item := range v {
+			encode(buf, item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		control(buf, typeMap, len(v))
+		for _, key := range keys {
+			encode(buf, key)
+			encode(buf, v[key])
+		}
+	default:
+		log.Fatalf("can't encode %T", value)
+	}
+}
+
+func writeUint(buf *bytes.Buffer, typ int, v uint64) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	b = bytes.TrimLeft(b, "\x00")
+	control(buf, typ, len(b))
+	buf.Write(b)
+}
+
+// control writes the control byte of a field of typ and size.
+func control(buf *bytes.Buffer, typ, size int) {
+	sizeBits, extra := size, -1
+	if size >= 29 {
+		if size > 284 {
+			log.Fatalf("size %d is too large for the fixtures", size)
+		}
+		sizeBits, extra = 29, size-29
+	}
+
+	if typ > 7 {
+		buf.WriteByte(byte(sizeBits))
+		buf.WriteByte(byte(typ - 7))
+	} else {
+		buf.WriteByte(byte(typ<<5 | sizeBits))
+	}
+	if extra >= 0 {
+		buf.WriteByte(byte(extra))
+	}
+}
@@ -0,0 +1,124 @@
+// Package i18n holds the languages the app ships in and the messages of
+// API errors in each of them, keyed by the errors' stable codes.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the language used when none of the requested ones is
+// supported.
+const Default = "en"
+
+// supported are the languages the Flutter app ships in.
+var supported = map[string]bool{
+	"en": true,
+	"ar": true,
+	"fa": true,
+	"tr": true,
+}
+
+// catalog holds the error messages by language and code. English has every
+// code; the others fall back to it.
+var catalog = map[string]map[string]string{
+	"en": messagesEN,
+	"ar": messagesAR,
+	"fa": messagesFA,
+	"tr": messagesTR,
+}
+
+// Supported maps a BCP 47 tag such as "fa-IR" to a supported language, or
+// returns "" if its language isn't one.
+func Supported(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if supported[tag] {
+		return tag
+	}
+	return ""
+}
+
+// Match maps a BCP 47 tag such as "fa-IR" to a supported language, falling
+// back to Default.
+func Match(tag string) string {
+	if locale := Supported(tag); locale != "" {
+		return locale
+	}
+	return Default
+}
+
+// rtl are the supported languages written right to left.
+var rtl = map[string]bool{
+	"ar": true,
+	"fa": true,
+}
+
+// Direction returns the direction locale, a supported language, is written
+// in: "rtl" or "ltr", as in the HTML dir attribute.
+func Direction(locale string) string {
+	if rtl[locale] {
+		return "rtl"
+	}
+	return "ltr"
+}
+
+// Negotiate picks the language to respond in: preferred, the user's stored
+// locale, if it is supported, otherwise the supported language ranked
+// highest by acceptLanguage, an Accept-Language header value, otherwise
+// Default.
+func Negotiate(preferred, acceptLanguage string) string {
+	if locale := Supported(preferred); locale != "" {
+		return locale
+	}
+
+	type candidate struct {
+		locale string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if locale := Supported(tag); locale != "" && q > 0 {
+			candidates = append(candidates, candidate{locale, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return Default
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].locale
+}
+
+// Message returns the message for the error code in locale, formatted with
+// args if it has placeholders. Codes without a translation get the English
+// message, and unknown codes are returned as they are.
+func Message(locale, code string, args ...interface{}) string {
+	message, ok := catalog[locale][code]
+	if !ok {
+		message, ok = catalog[Default][code]
+	}
+	if !ok {
+		return code
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
@@ -0,0 +1,225 @@
+package i18n
+
+var messagesAR = map[string]string{
+	// Gateway
+	"missing_authorization":        "ترويسة التفويض مفقودة",
+	"invalid_authorization_header": "تنسيق ترويسة التفويض غير صالح",
+	"invalid_token":                "الرمز غير صالح أو منتهي الصلاحية",
+	"invalid_token_claims":         "بيانات الرمز غير صالحة",
+	"rate_limited":                 "تم تجاوز حد الطلبات. يرجى المحاولة لاحقًا.",
+	"consent_required":             "عليك قبول أحدث الشروط للمتابعة",
+	"internal_error":               "خطأ داخلي في الخادم",
+	"service_unavailable":          "الخدمة غير متاحة",
+
+	// Requests
+	"unauthorized":             "غير مصرح",
+	"forbidden":                "ممنوع",
+	"field_required":           "هذا الحقل مطلوب",
+	"invalid_request_body":     "نص الطلب غير صالح",
+	"invalid_limit":            "يجب أن تكون قيمة limit بين 1 و100",
+	"invalid_offset":           "يجب ألا تكون قيمة offset سالبة",
+	"invalid_timestamp":        "يجب أن تكون قيمة %s طابعًا زمنيًا بتنسيق RFC 3339",
+	"check_permissions_failed": "تعذّر التحقق من الصلاحيات",
+
+	// Registration and login
+	"registration_fields_required": "البريد الإلكتروني وكلمة المرور والاسم مطلوبة",
+	"credentials_required":         "البريد الإلكتروني وكلمة المرور مطلوبان",
+	"password_required":            "كلمة المرور مطلوبة",
+	"password_too_short":           "يجب أن تتكون كلمة المرور من 8 أحرف على الأقل",
+	"email_exists":                 "البريد الإلكتروني مستخدم بالفعل",
+	"invalid_credentials":          "بيانات الدخول غير صحيحة",
+	"refresh_token_required":       "رمز التحديث مطلوب",
+	"invalid_refresh_token":        "رمز التحديث غير صالح",
+	"reauthentication_required":    "يلزم التحقق من هويتك مجددًا",
+	"challenge_failed":             "تحدي إثبات العمل مفقود أو منتهي الصلاحية أو لم يُحل",
+	"registration_rate_limited":    "عدد كبير جدًا من التسجيلات من شبكتك. يرجى المحاولة لاحقًا.",
+	"create_challenge_failed":      "تعذّر إنشاء التحدي",
+	"register_failed":              "تعذّر تسجيل المستخدم",
+	"login_failed":                 "تعذّر تسجيل الدخول",
+	"refresh_failed":               "تعذّر تحديث الرمز",
+	"reauthenticate_failed":        "تعذّر التحقق من الهوية مجددًا",
+
+	// Registration policy
+	"email_domain_not_allowed":  "التسجيل مقتصر على نطاقات البريد الإلكتروني المعتمدة",
+	"email_domain_blocked":      "لا يمكن استخدام نطاق البريد الإلكتروني هذا",
+	"disposable_email":          "لا يمكن استخدام عناوين البريد الإلكتروني المؤقتة",
+	"invitation_required":       "يلزم رمز دعوة للتسجيل",
+	"phone_invitation_required": "يلزم رمز دعوة للتسجيل برقم هاتف",
+	"invalid_invitation":        "رمز الدعوة غير صالح أو منتهي الصلاحية",
+
+	// Names and email addresses
+	"invalid_email":           "عنوان البريد الإلكتروني غير صالح",
+	"name_required":           "الاسم مطلوب",
+	"name_too_long":           "يجب ألا يتجاوز الاسم %d حرفًا",
+	"name_invalid_characters": "يحتوي الاسم على أحرف غير مسموح بها",
+
+	// Users and accounts
+	"user_id_required":         "معرّف المستخدم مطلوب",
+	"user_not_found":           "المستخدم غير موجود",
+	"email_address_required":   "البريد الإلكتروني مطلوب",
+	"account_pending_deletion": "هذا الحساب مجدول للحذف. سجّل الدخول مع cancel_deletion لاستعادته.",
+	"account_not_deleted":      "الحساب غير مجدول للحذف",
+	"get_profile_failed":       "تعذّر جلب الملف الشخصي",
+	"update_profile_failed":    "تعذّر تحديث الملف الشخصي",
+	"list_users_failed":        "تعذّر جلب قائمة المستخدمين",
+	"get_user_failed":          "تعذّر جلب المستخدم",
+	"change_password_failed":   "تعذّر تغيير كلمة المرور",
+	"change_email_failed":      "تعذّر تغيير البريد الإلكتروني",
+	"delete_account_failed":    "تعذّر حذف الحساب",
+	"restore_account_failed":   "تعذّر استعادة الحساب",
+	"get_usage_failed":         "تعذّر جلب إحصاءات الاستخدام",
+
+	// Phone numbers and codes
+	"phone_required":          "رقم الهاتف مطلوب",
+	"phone_and_code_required": "رقم الهاتف والرمز مطلوبان",
+	"invalid_phone":           "يجب أن يكون رقم الهاتف رقمًا دوليًا صالحًا",
+	"phone_exists":            "رقم الهاتف مستخدم بالفعل",
+	"signup_name_required":    "الاسم مطلوب لإنشاء حساب",
+	"invalid_code":            "الرمز غير صالح أو منتهي الصلاحية",
+	"code_cooldown":           "يرجى الانتظار قبل طلب رمز آخر",
+	"code_rate_limited":       "تم طلب عدد كبير جدًا من الرموز. يرجى المحاولة لاحقًا.",
+	"send_code_failed":        "تعذّر إرسال الرمز",
+	"verify_code_failed":      "تعذّر التحقق من الرمز",
+
+	// Login links
+	"email_and_device_id_required": "البريد الإلكتروني وdevice_id مطلوبان",
+	"magic_link_fields_required":   "يلزم device_id مع الرمز المميز أو البريد الإلكتروني والرمز",
+	"invalid_magic_link":           "رابط الدخول غير صالح أو منتهي الصلاحية",
+	"send_magic_link_failed":       "تعذّر إرسال رابط الدخول",
+	"verify_magic_link_failed":     "تعذّر التحقق من رابط الدخول",
+
+	// Guest accounts
+	"guest_access_disabled": "الدخول كضيف معطّل. يرجى إنشاء حساب بدلًا من ذلك.",
+	"guest_not_allowed":     "يجب ترقية حسابات الضيوف أولًا",
+	"not_guest":             "هذا الحساب ليس حساب ضيف",
+	"create_guest_failed":   "تعذّر إنشاء حساب الضيف",
+	"upgrade_failed":        "تعذّر ترقية الحساب",
+
+	// Login methods
+	"identity_already_linked":       "طريقة تسجيل الدخول هذه مرتبطة بالفعل",
+	"identity_not_linked":           "طريقة تسجيل الدخول هذه غير مرتبطة",
+	"last_identity":                 "لا يمكنك إزالة آخر طريقة لتسجيل الدخول",
+	"email_required":                "اربط عنوان بريد إلكتروني قبل تعيين كلمة مرور",
+	"invalid_verification_code":     "الرمز غير صالح أو منتهي الصلاحية",
+	"list_identities_failed":        "تعذّر جلب طرق تسجيل الدخول",
+	"send_verification_code_failed": "تعذّر إرسال رمز التحقق",
+	"link_email_failed":             "تعذّر ربط البريد الإلكتروني",
+	"link_phone_failed":             "تعذّر ربط رقم الهاتف",
+	"set_password_failed":           "تعذّر تعيين كلمة المرور",
+	"remove_identity_failed":        "تعذّر إزالة طريقة تسجيل الدخول",
+
+	// Devices and sign-ins
+	"device_id_required":       "معرّف الجهاز مطلوب",
+	"device_not_found":         "الجهاز غير موجود",
+	"list_devices_failed":      "تعذّر جلب قائمة الأجهزة",
+	"revoke_device_failed":     "تعذّر إلغاء الجهاز",
+	"revoke_devices_failed":    "تعذّر إلغاء الأجهزة",
+	"get_login_history_failed": "تعذّر جلب سجل تسجيلات الدخول",
+
+	// Data exports
+	"export_rate_limited":    "لقد طلبت نسخة من بياناتك مؤخرًا. يرجى المحاولة لاحقًا.",
+	"export_not_found":       "تصدير البيانات غير موجود",
+	"export_not_ready":       "نسخة بياناتك ليست جاهزة بعد",
+	"export_ready_sms":       "نسخة بياناتك جاهزة. قم بتنزيلها من التطبيق قبل %s.",
+	"invalid_export_link":    "رابط التنزيل هذا غير صالح أو منتهي الصلاحية",
+	"request_export_failed":  "تعذّر طلب تصدير البيانات",
+	"list_exports_failed":    "تعذّر جلب قائمة تصديرات البيانات",
+	"get_export_failed":      "تعذّر جلب تصدير البيانات",
+	"download_export_failed": "تعذّر تنزيل تصدير البيانات",
+
+	// Invitation codes
+	"invalid_invitation_limits": "يجب ألا تكون قيمتا max_uses وexpires_in سالبتين",
+	"invitation_not_found":      "الدعوة غير موجودة",
+	"create_invitation_failed":  "تعذّر إنشاء الدعوة",
+	"list_invitations_failed":   "تعذّر جلب قائمة الدعوات",
+	"revoke_invitation_failed":  "تعذّر إلغاء الدعوة",
+
+	// Organizations
+	"organization_not_found":          "المؤسسة غير موجودة",
+	"organization_permission_denied":  "دورك في هذه المؤسسة لا يسمح بذلك",
+	"invalid_role":                    "يجب أن يكون الدور owner أو admin أو member",
+	"last_owner":                      "تحتاج المؤسسة إلى مالك واحد على الأقل",
+	"member_not_found":                "العضو غير موجود",
+	"already_member":                  "المستخدم عضو بالفعل",
+	"invalid_org_invitation":          "الدعوة غير صالحة أو منتهية الصلاحية",
+	"invitation_email_mismatch":       "أُرسلت هذه الدعوة إلى عنوان بريد إلكتروني مختلف",
+	"org_invitation_token_required":   "اقبل هذه الدعوة من خلال الرابط الوارد في رسالتها، أو تحقّق من عنوان بريدك الإلكتروني أولًا",
+	"invitation_token_or_id_required": "يلزم الرمز المميز أو المعرّف",
+	"create_organization_failed":      "تعذّر إنشاء المؤسسة",
+	"list_organizations_failed":       "تعذّر جلب قائمة المؤسسات",
+	"switch_organization_failed":      "تعذّر تبديل المؤسسة",
+	"list_members_failed":             "تعذّر جلب قائمة الأعضاء",
+	"update_member_failed":            "تعذّر تحديث العضو",
+	"remove_member_failed":            "تعذّر إزالة العضو",
+	"send_invitation_failed":          "تعذّر إرسال الدعوة",
+	"accept_invitation_failed":        "تعذّر قبول الدعوة",
+	"decline_invitation_failed":       "تعذّر رفض الدعوة",
+
+	// Legal documents and consents
+	"unknown_legal_document":        "مستند قانوني غير معروف",
+	"consent_outdated":              "نُشر إصدار أحدث من المستند؛ اجلبه واقبله",
+	"consents_required":             "الموافقات مطلوبة",
+	"unsupported_locale":            "يجب أن تكون اللغة en أو ar أو fa أو tr",
+	"english_translation_required":  "الترجمة الإنجليزية مطلوبة",
+	"translation_incomplete":        "تحتاج كل ترجمة إلى عنوان ونص",
+	"get_legal_documents_failed":    "تعذّر جلب المستندات القانونية",
+	"get_legal_versions_failed":     "تعذّر جلب إصدارات المستندات القانونية",
+	"record_consent_failed":         "تعذّر تسجيل الموافقة",
+	"list_consents_failed":          "تعذّر جلب قائمة الموافقات",
+	"publish_legal_document_failed": "تعذّر نشر المستند القانوني",
+
+	// Preferences
+	"invalid_theme":             "يجب أن يكون المظهر system أو light أو dark",
+	"invalid_time_zone":         "يجب أن تكون المنطقة الزمنية منطقة IANA مثل Asia/Riyadh",
+	"get_preferences_failed":    "تعذّر جلب التفضيلات",
+	"update_preferences_failed": "تعذّر تحديث التفضيلات",
+
+	// Translation bundles
+	"invalid_arb":                    "يجب أن يكون نص الطلب ملف ARB",
+	"arb_locale_mismatch":            "قيمة @@locale في الملف لا تطابق اللغة التي يُرفع لها",
+	"translation_template_missing":   "انشر القالب الإنجليزي قبل رفع الترجمات",
+	"translation_bundle_not_found":   "حزمة الترجمة غير موجودة",
+	"translation_bundle_not_pending": "تمت مراجعة حزمة الترجمة بالفعل",
+	"invalid_translation_bundle":     "بعض رسائل حزمة الترجمة غير صالحة",
+	"unknown_message_key":            "لا يحتوي القالب الإنجليزي على رسالة بهذا المفتاح",
+	"invalid_icu_message":            "ليست رسالة ICU صالحة",
+	"placeholder_mismatch":           "يجب أن تستخدم الرسالة العناصر النائبة %s",
+	"placeholders_not_allowed":       "لا تحتوي الرسالة الإنجليزية على عناصر نائبة",
+	"placeholder_undeclared":         "العنصر النائب {%s} غير مُعرَّف في بيانات الرسالة الوصفية",
+	"get_translations_failed":        "فشل الحصول على الترجمات",
+	"upload_translations_failed":     "فشل رفع الترجمات",
+	"list_translations_failed":       "فشل عرض الترجمات",
+	"review_translations_failed":     "فشلت مراجعة الترجمات",
+
+	// Email templates
+	"email_template_not_found": "قالب البريد الإلكتروني غير موجود",
+	"invalid_preview_format":   "يجب أن يكون التنسيق html أو text",
+	"preview_email_failed":     "فشلت معاينة البريد الإلكتروني",
+
+	// Audit log
+	"get_audit_events_failed": "تعذّر جلب أحداث التدقيق",
+	"verify_audit_log_failed": "تعذّر التحقق من سجل التدقيق",
+
+	// Success messages
+	"password_reset_sent":        "تم إرسال بريد إعادة تعيين كلمة المرور",
+	"profile_updated":            "تم تحديث الملف الشخصي بنجاح",
+	"email_updated":              "تم تحديث البريد الإلكتروني بنجاح",
+	"account_deleted":            "تم حذف الحساب بنجاح",
+	"account_deletion_scheduled": "تمت جدولة حذف الحساب. سجّل الدخول مرة أخرى قبل حذفه نهائيًا لإلغاء ذلك.",
+	"account_restored":           "تمت استعادة الحساب",
+	"otp_sent":                   "تم إرسال الرمز",
+	"magic_link_sent":            "إذا كان البريد الإلكتروني مسجلًا، فقد تم إرسال رابط تسجيل الدخول",
+	"verification_code_sent":     "تم إرسال رمز التحقق",
+	"email_linked":               "تم ربط البريد الإلكتروني بنجاح",
+	"phone_linked":               "تم ربط رقم الهاتف بنجاح",
+	"password_set":               "تم تعيين كلمة المرور بنجاح",
+	"login_method_removed":       "تمت إزالة طريقة تسجيل الدخول",
+	"device_revoked":             "تم إلغاء الجهاز بنجاح",
+	"all_devices_revoked":        "تم إلغاء جميع الأجهزة بنجاح",
+	"invitation_revoked":         "تم إلغاء الدعوة",
+	"member_updated":             "تم تحديث العضو بنجاح",
+	"member_removed":             "تمت إزالة العضو بنجاح",
+	"org_invitation_revoked":     "تم إلغاء الدعوة",
+	"org_invitation_accepted":    "تم قبول الدعوة",
+	"org_invitation_declined":    "تم رفض الدعوة",
+}
@@ -0,0 +1,225 @@
+package i18n
+
+var messagesEN = map[string]string{
+	// Gateway
+	"missing_authorization":        "Missing authorization header",
+	"invalid_authorization_header": "Invalid authorization header format",
+	"invalid_token":                "Invalid or expired token",
+	"invalid_token_claims":         "Invalid token claims",
+	"rate_limited":                 "Rate limit exceeded. Please try again later.",
+	"consent_required":             "You need to accept the latest terms to continue",
+	"internal_error":               "Internal server error",
+	"service_unavailable":          "Service unavailable",
+
+	// Requests
+	"unauthorized":             "Unauthorized",
+	"forbidden":                "Forbidden",
+	"field_required":           "This field is required",
+	"invalid_request_body":     "Invalid request body",
+	"invalid_limit":            "limit must be between 1 and 100",
+	"invalid_offset":           "offset must not be negative",
+	"invalid_timestamp":        "%s must be an RFC 3339 timestamp",
+	"check_permissions_failed": "Failed to check permissions",
+
+	// Registration and login
+	"registration_fields_required": "Email, password, and name are required",
+	"credentials_required":         "Email and password are required",
+	"password_required":            "Password is required",
+	"password_too_short":           "Password must be at least 8 characters",
+	"email_exists":                 "Email already exists",
+	"invalid_credentials":          "Invalid credentials",
+	"refresh_token_required":       "Refresh token is required",
+	"invalid_refresh_token":        "Invalid refresh token",
+	"reauthentication_required":    "Recent authentication required",
+	"challenge_failed":             "Proof-of-work challenge missing, expired or not solved",
+	"registration_rate_limited":    "Too many registrations from your network. Please try again later.",
+	"create_challenge_failed":      "Failed to create challenge",
+	"register_failed":              "Failed to register user",
+	"login_failed":                 "Failed to login",
+	"refresh_failed":               "Failed to refresh token",
+	"reauthenticate_failed":        "Failed to reauthenticate",
+
+	// Registration policy
+	"email_domain_not_allowed":  "Registration is limited to approved email domains",
+	"email_domain_blocked":      "This email domain cannot be used",
+	"disposable_email":          "Disposable email addresses cannot be used",
+	"invitation_required":       "An invitation code is required to register",
+	"phone_invitation_required": "An invitation code is required to register with a phone number",
+	"invalid_invitation":        "Invalid or expired invitation code",
+
+	// Names and email addresses
+	"invalid_email":           "Email address is not valid",
+	"name_required":           "Name is required",
+	"name_too_long":           "Name must be at most %d characters",
+	"name_invalid_characters": "Name contains characters that are not allowed",
+
+	// Users and accounts
+	"user_id_required":         "User id is required",
+	"user_not_found":           "User not found",
+	"email_address_required":   "Email is required",
+	"account_pending_deletion": "This account is scheduled for deletion. Log in with cancel_deletion to restore it.",
+	"account_not_deleted":      "Account is not scheduled for deletion",
+	"get_profile_failed":       "Failed to get profile",
+	"update_profile_failed":    "Failed to update profile",
+	"list_users_failed":        "Failed to list users",
+	"get_user_failed":          "Failed to get user",
+	"change_password_failed":   "Failed to change password",
+	"change_email_failed":      "Failed to change email",
+	"delete_account_failed":    "Failed to delete account",
+	"restore_account_failed":   "Failed to restore account",
+	"get_usage_failed":         "Failed to get usage stats",
+
+	// Phone numbers and codes
+	"phone_required":          "Phone is required",
+	"phone_and_code_required": "Phone and code are required",
+	"invalid_phone":           "Phone must be a valid international number",
+	"phone_exists":            "Phone already exists",
+	"signup_name_required":    "Name is required to sign up",
+	"invalid_code":            "Invalid or expired code",
+	"code_cooldown":           "Please wait before requesting another code",
+	"code_rate_limited":       "Too many codes requested. Please try again later.",
+	"send_code_failed":        "Failed to send code",
+	"verify_code_failed":      "Failed to verify code",
+
+	// Login links
+	"email_and_device_id_required": "Email and device_id are required",
+	"magic_link_fields_required":   "device_id and either token or email and code are required",
+	"invalid_magic_link":           "Invalid or expired login link",
+	"send_magic_link_failed":       "Failed to send login link",
+	"verify_magic_link_failed":     "Failed to verify login link",
+
+	// Guest accounts
+	"guest_access_disabled": "Guest access is disabled. Please sign up instead.",
+	"guest_not_allowed":     "Guest accounts have to be upgraded first",
+	"not_guest":             "Account is not a guest account",
+	"create_guest_failed":   "Failed to create guest account",
+	"upgrade_failed":        "Failed to upgrade account",
+
+	// Login methods
+	"identity_already_linked":       "This login method is already linked",
+	"identity_not_linked":           "This login method is not linked",
+	"last_identity":                 "You can't remove your last way to sign in",
+	"email_required":                "Link an email address before setting a password",
+	"invalid_verification_code":     "Invalid or expired code",
+	"list_identities_failed":        "Failed to list login methods",
+	"send_verification_code_failed": "Failed to send verification code",
+	"link_email_failed":             "Failed to link email",
+	"link_phone_failed":             "Failed to link phone",
+	"set_password_failed":           "Failed to set password",
+	"remove_identity_failed":        "Failed to remove login method",
+
+	// Devices and sign-ins
+	"device_id_required":       "Device id is required",
+	"device_not_found":         "Device not found",
+	"list_devices_failed":      "Failed to list devices",
+	"revoke_device_failed":     "Failed to revoke device",
+	"revoke_devices_failed":    "Failed to revoke devices",
+	"get_login_history_failed": "Failed to get login history",
+
+	// Data exports
+	"export_rate_limited":    "You already requested a copy of your data recently. Please try again later.",
+	"export_not_found":       "Data export not found",
+	"export_not_ready":       "Your data export is not ready yet",
+	"export_ready_sms":       "Your data export is ready. Download it in the app before %s.",
+	"invalid_export_link":    "This download link is invalid or has expired",
+	"request_export_failed":  "Failed to request data export",
+	"list_exports_failed":    "Failed to list data exports",
+	"get_export_failed":      "Failed to get data export",
+	"download_export_failed": "Failed to download data export",
+
+	// Invitation codes
+	"invalid_invitation_limits": "max_uses and expires_in must not be negative",
+	"invitation_not_found":      "Invitation not found",
+	"create_invitation_failed":  "Failed to create invitation",
+	"list_invitations_failed":   "Failed to list invitations",
+	"revoke_invitation_failed":  "Failed to revoke invitation",
+
+	// Organizations
+	"organization_not_found":          "Organization not found",
+	"organization_permission_denied":  "Your role in this organization does not allow this",
+	"invalid_role":                    "Role must be owner, admin or member",
+	"last_owner":                      "An organization needs at least one owner",
+	"member_not_found":                "Member not found",
+	"already_member":                  "User is already a member",
+	"invalid_org_invitation":          "Invalid or expired invitation",
+	"invitation_email_mismatch":       "This invitation was sent to a different email address",
+	"org_invitation_token_required":   "Accept this invitation with the link from its email, or verify your email address first",
+	"invitation_token_or_id_required": "Either token or id is required",
+	"create_organization_failed":      "Failed to create organization",
+	"list_organizations_failed":       "Failed to list organizations",
+	"switch_organization_failed":      "Failed to switch organization",
+	"list_members_failed":             "Failed to list members",
+	"update_member_failed":            "Failed to update member",
+	"remove_member_failed":            "Failed to remove member",
+	"send_invitation_failed":          "Failed to send invitation",
+	"accept_invitation_failed":        "Failed to accept invitation",
+	"decline_invitation_failed":       "Failed to decline invitation",
+
+	// Legal documents and consents
+	"unknown_legal_document":        "Unknown legal document",
+	"consent_outdated":              "A newer version of the document was published; fetch and accept that one",
+	"consents_required":             "Consents are required",
+	"unsupported_locale":            "Locale must be en, ar, fa or tr",
+	"english_translation_required":  "An English translation is required",
+	"translation_incomplete":        "Every translation needs a title and a body",
+	"get_legal_documents_failed":    "Failed to get legal documents",
+	"get_legal_versions_failed":     "Failed to get legal document versions",
+	"record_consent_failed":         "Failed to record consent",
+	"list_consents_failed":          "Failed to list consents",
+	"publish_legal_document_failed": "Failed to publish legal document",
+
+	// Preferences
+	"invalid_theme":             "Theme must be system, light or dark",
+	"invalid_time_zone":         "Time zone must be an IANA time zone such as Europe/Istanbul",
+	"get_preferences_failed":    "Failed to get preferences",
+	"update_preferences_failed": "Failed to update preferences",
+
+	// Translation bundles
+	"invalid_arb":                    "The request body must be an ARB file",
+	"arb_locale_mismatch":            "The file's @@locale doesn't match the locale it is uploaded for",
+	"translation_template_missing":   "Publish the English template before uploading translations",
+	"translation_bundle_not_found":   "Translation bundle not found",
+	"translation_bundle_not_pending": "The translation bundle was already reviewed",
+	"invalid_translation_bundle":     "Some messages of the translation bundle are invalid",
+	"unknown_message_key":            "The English template has no message with this key",
+	"invalid_icu_message":            "Not a valid ICU message",
+	"placeholder_mismatch":           "The message must use the placeholders %s",
+	"placeholders_not_allowed":       "The English message has no placeholders",
+	"placeholder_undeclared":         "Placeholder {%s} is not declared in the message's metadata",
+	"get_translations_failed":        "Failed to get translations",
+	"upload_translations_failed":     "Failed to upload translations",
+	"list_translations_failed":       "Failed to list translations",
+	"review_translations_failed":     "Failed to review translations",
+
+	// Email templates
+	"email_template_not_found": "Email template not found",
+	"invalid_preview_format":   "Format must be html or text",
+	"preview_email_failed":     "Failed to preview email",
+
+	// Audit log
+	"get_audit_events_failed": "Failed to get audit events",
+	"verify_audit_log_failed": "Failed to verify audit log",
+
+	// Success messages
+	"password_reset_sent":        "Password reset email sent",
+	"profile_updated":            "Profile updated successfully",
+	"email_updated":              "Email updated successfully",
+	"account_deleted":            "Account deleted successfully",
+	"account_deletion_scheduled": "Account scheduled for deletion. Log in again before it is purged to cancel.",
+	"account_restored":           "Account restored",
+	"otp_sent":                   "Code sent",
+	"magic_link_sent":            "If the email is registered, a login link has been sent",
+	"verification_code_sent":     "Verification code sent",
+	"email_linked":               "Email linked successfully",
+	"phone_linked":               "Phone linked successfully",
+	"password_set":               "Password set successfully",
+	"login_method_removed":       "Login method removed",
+	"device_revoked":             "Device revoked successfully",
+	"all_devices_revoked":        "All devices revoked successfully",
+	"invitation_revoked":         "Invitation revoked",
+	"member_updated":             "Member updated successfully",
+	"member_removed":             "Member removed successfully",
+	"org_invitation_revoked":     "Invitation revoked",
+	"org_invitation_accepted":    "Invitation accepted",
+	"org_invitation_declined":    "Invitation declined",
+}
@@ -0,0 +1,225 @@
+package i18n
+
+var messagesFA = map[string]string{
+	// Gateway
+	"missing_authorization":        "هدر احراز هویت وجود ندارد",
+	"invalid_authorization_header": "قالب هدر احراز هویت نامعتبر است",
+	"invalid_token":                "توکن نامعتبر است یا منقضی شده است",
+	"invalid_token_claims":         "اطلاعات توکن نامعتبر است",
+	"rate_limited":                 "تعداد درخواست‌ها از حد مجاز گذشته است. لطفاً بعداً دوباره تلاش کنید.",
+	"consent_required":             "برای ادامه باید آخرین نسخهٔ شرایط را بپذیرید",
+	"internal_error":               "خطای داخلی سرور",
+	"service_unavailable":          "سرویس در دسترس نیست",
+
+	// Requests
+	"unauthorized":             "دسترسی غیرمجاز",
+	"forbidden":                "دسترسی ممنوع است",
+	"field_required":           "این فیلد الزامی است",
+	"invalid_request_body":     "بدنهٔ درخواست نامعتبر است",
+	"invalid_limit":            "مقدار limit باید بین 1 و 100 باشد",
+	"invalid_offset":           "مقدار offset نباید منفی باشد",
+	"invalid_timestamp":        "مقدار %s باید یک زمان با قالب RFC 3339 باشد",
+	"check_permissions_failed": "بررسی دسترسی‌ها ناموفق بود",
+
+	// Registration and login
+	"registration_fields_required": "ایمیل، رمز عبور و نام الزامی است",
+	"credentials_required":         "ایمیل و رمز عبور الزامی است",
+	"password_required":            "رمز عبور الزامی است",
+	"password_too_short":           "رمز عبور باید دست‌کم ۸ نویسه باشد",
+	"email_exists":                 "این ایمیل قبلاً ثبت شده است",
+	"invalid_credentials":          "اطلاعات ورود نادرست است",
+	"refresh_token_required":       "توکن تازه‌سازی الزامی است",
+	"invalid_refresh_token":        "توکن تازه‌سازی نامعتبر است",
+	"reauthentication_required":    "باید دوباره هویت خود را تأیید کنید",
+	"challenge_failed":             "چالش اثبات کار وجود ندارد، منقضی شده یا حل نشده است",
+	"registration_rate_limited":    "ثبت‌نام‌های زیادی از شبکهٔ شما انجام شده است. لطفاً بعداً دوباره تلاش کنید.",
+	"create_challenge_failed":      "ایجاد چالش ناموفق بود",
+	"register_failed":              "ثبت‌نام کاربر ناموفق بود",
+	"login_failed":                 "ورود ناموفق بود",
+	"refresh_failed":               "تازه‌سازی توکن ناموفق بود",
+	"reauthenticate_failed":        "تأیید دوبارهٔ هویت ناموفق بود",
+
+	// Registration policy
+	"email_domain_not_allowed":  "ثبت‌نام فقط با دامنه‌های ایمیل تأییدشده امکان‌پذیر است",
+	"email_domain_blocked":      "از این دامنهٔ ایمیل نمی‌توان استفاده کرد",
+	"disposable_email":          "از ایمیل‌های موقت نمی‌توان استفاده کرد",
+	"invitation_required":       "برای ثبت‌نام کد دعوت لازم است",
+	"phone_invitation_required": "برای ثبت‌نام با شماره تلفن کد دعوت لازم است",
+	"invalid_invitation":        "کد دعوت نامعتبر است یا منقضی شده است",
+
+	// Names and email addresses
+	"invalid_email":           "نشانی ایمیل معتبر نیست",
+	"name_required":           "نام الزامی است",
+	"name_too_long":           "نام باید حداکثر %d نویسه باشد",
+	"name_invalid_characters": "نام شامل نویسه‌های غیرمجاز است",
+
+	// Users and accounts
+	"user_id_required":         "شناسهٔ کاربر الزامی است",
+	"user_not_found":           "کاربر پیدا نشد",
+	"email_address_required":   "ایمیل الزامی است",
+	"account_pending_deletion": "این حساب برای حذف زمان‌بندی شده است. برای بازگرداندن آن با cancel_deletion وارد شوید.",
+	"account_not_deleted":      "این حساب برای حذف زمان‌بندی نشده است",
+	"get_profile_failed":       "دریافت نمایه ناموفق بود",
+	"update_profile_failed":    "به‌روزرسانی نمایه ناموفق بود",
+	"list_users_failed":        "دریافت فهرست کاربران ناموفق بود",
+	"get_user_failed":          "دریافت کاربر ناموفق بود",
+	"change_password_failed":   "تغییر رمز عبور ناموفق بود",
+	"change_email_failed":      "تغییر ایمیل ناموفق بود",
+	"delete_account_failed":    "حذف حساب ناموفق بود",
+	"restore_account_failed":   "بازگرداندن حساب ناموفق بود",
+	"get_usage_failed":         "دریافت آمار استفاده ناموفق بود",
+
+	// Phone numbers and codes
+	"phone_required":          "شمارهٔ تلفن الزامی است",
+	"phone_and_code_required": "شمارهٔ تلفن و کد الزامی است",
+	"invalid_phone":           "شمارهٔ تلفن باید یک شمارهٔ بین‌المللی معتبر باشد",
+	"phone_exists":            "این شمارهٔ تلفن قبلاً ثبت شده است",
+	"signup_name_required":    "برای ثبت‌نام، نام الزامی است",
+	"invalid_code":            "کد نامعتبر است یا منقضی شده است",
+	"code_cooldown":           "لطفاً پیش از درخواست کد دیگر کمی صبر کنید",
+	"code_rate_limited":       "کدهای زیادی درخواست شده است. لطفاً بعداً دوباره تلاش کنید.",
+	"send_code_failed":        "ارسال کد ناموفق بود",
+	"verify_code_failed":      "تأیید کد ناموفق بود",
+
+	// Login links
+	"email_and_device_id_required": "ایمیل و device_id الزامی است",
+	"magic_link_fields_required":   "device_id به‌همراه توکن یا ایمیل و کد الزامی است",
+	"invalid_magic_link":           "پیوند ورود نامعتبر است یا منقضی شده است",
+	"send_magic_link_failed":       "ارسال پیوند ورود ناموفق بود",
+	"verify_magic_link_failed":     "تأیید پیوند ورود ناموفق بود",
+
+	// Guest accounts
+	"guest_access_disabled": "ورود مهمان غیرفعال است. لطفاً ثبت‌نام کنید.",
+	"guest_not_allowed":     "حساب‌های مهمان ابتدا باید ارتقا یابند",
+	"not_guest":             "این حساب، حساب مهمان نیست",
+	"create_guest_failed":   "ایجاد حساب مهمان ناموفق بود",
+	"upgrade_failed":        "ارتقای حساب ناموفق بود",
+
+	// Login methods
+	"identity_already_linked":       "این روش ورود قبلاً متصل شده است",
+	"identity_not_linked":           "این روش ورود متصل نیست",
+	"last_identity":                 "نمی‌توانید آخرین روش ورود خود را حذف کنید",
+	"email_required":                "پیش از تعیین رمز عبور، یک نشانی ایمیل متصل کنید",
+	"invalid_verification_code":     "کد نامعتبر است یا منقضی شده است",
+	"list_identities_failed":        "دریافت روش‌های ورود ناموفق بود",
+	"send_verification_code_failed": "ارسال کد تأیید ناموفق بود",
+	"link_email_failed":             "اتصال ایمیل ناموفق بود",
+	"link_phone_failed":             "اتصال شمارهٔ تلفن ناموفق بود",
+	"set_password_failed":           "تعیین رمز عبور ناموفق بود",
+	"remove_identity_failed":        "حذف روش ورود ناموفق بود",
+
+	// Devices and sign-ins
+	"device_id_required":       "شناسهٔ دستگاه الزامی است",
+	"device_not_found":         "دستگاه پیدا نشد",
+	"list_devices_failed":      "دریافت فهرست دستگاه‌ها ناموفق بود",
+	"revoke_device_failed":     "لغو دستگاه ناموفق بود",
+	"revoke_devices_failed":    "لغو دستگاه‌ها ناموفق بود",
+	"get_login_history_failed": "دریافت تاریخچهٔ ورود ناموفق بود",
+
+	// Data exports
+	"export_rate_limited":    "به‌تازگی یک نسخه از داده‌های خود را درخواست کرده‌اید. لطفاً بعداً دوباره تلاش کنید.",
+	"export_not_found":       "خروجی داده پیدا نشد",
+	"export_not_ready":       "نسخهٔ داده‌های شما هنوز آماده نیست",
+	"export_ready_sms":       "نسخهٔ داده‌های شما آماده است. آن را پیش از %s در برنامه دانلود کنید.",
+	"invalid_export_link":    "این پیوند دانلود نامعتبر است یا منقضی شده است",
+	"request_export_failed":  "درخواست خروجی داده ناموفق بود",
+	"list_exports_failed":    "دریافت فهرست خروجی‌های داده ناموفق بود",
+	"get_export_failed":      "دریافت خروجی داده ناموفق بود",
+	"download_export_failed": "دانلود خروجی داده ناموفق بود",
+
+	// Invitation codes
+	"invalid_invitation_limits": "مقدارهای max_uses و expires_in نباید منفی باشند",
+	"invitation_not_found":      "دعوت‌نامه پیدا نشد",
+	"create_invitation_failed":  "ایجاد دعوت‌نامه ناموفق بود",
+	"list_invitations_failed":   "دریافت فهرست دعوت‌نامه‌ها ناموفق بود",
+	"revoke_invitation_failed":  "لغو دعوت‌نامه ناموفق بود",
+
+	// Organizations
+	"organization_not_found":          "سازمان پیدا نشد",
+	"organization_permission_denied":  "نقش شما در این سازمان اجازهٔ این کار را نمی‌دهد",
+	"invalid_role":                    "نقش باید owner، admin یا member باشد",
+	"last_owner":                      "هر سازمان دست‌کم به یک مالک نیاز دارد",
+	"member_not_found":                "عضو پیدا نشد",
+	"already_member":                  "این کاربر قبلاً عضو شده است",
+	"invalid_org_invitation":          "دعوت‌نامه نامعتبر است یا منقضی شده است",
+	"invitation_email_mismatch":       "این دعوت‌نامه به نشانی ایمیل دیگری فرستاده شده است",
+	"org_invitation_token_required":   "این دعوت را با پیوند داخل ایمیل آن بپذیرید یا ابتدا نشانی ایمیل خود را تأیید کنید",
+	"invitation_token_or_id_required": "توکن یا شناسه الزامی است",
+	"create_organization_failed":      "ایجاد سازمان ناموفق بود",
+	"list_organizations_failed":       "دریافت فهرست سازمان‌ها ناموفق بود",
+	"switch_organization_failed":      "تغییر سازمان ناموفق بود",
+	"list_members_failed":             "دریافت فهرست اعضا ناموفق بود",
+	"update_member_failed":            "به‌روزرسانی عضو ناموفق بود",
+	"remove_member_failed":            "حذف عضو ناموفق بود",
+	"send_invitation_failed":          "ارسال دعوت‌نامه ناموفق بود",
+	"accept_invitation_failed":        "پذیرفتن دعوت‌نامه ناموفق بود",
+	"decline_invitation_failed":       "رد دعوت‌نامه ناموفق بود",
+
+	// Legal documents and consents
+	"unknown_legal_document":        "سند حقوقی ناشناخته است",
+	"consent_outdated":              "نسخهٔ جدیدتری از سند منتشر شده است؛ آن را دریافت کنید و بپذیرید",
+	"consents_required":             "رضایت‌ها الزامی است",
+	"unsupported_locale":            "زبان باید en، ar، fa یا tr باشد",
+	"english_translation_required":  "ترجمهٔ انگلیسی الزامی است",
+	"translation_incomplete":        "هر ترجمه به عنوان و متن نیاز دارد",
+	"get_legal_documents_failed":    "دریافت اسناد حقوقی ناموفق بود",
+	"get_legal_versions_failed":     "دریافت نسخه‌های اسناد حقوقی ناموفق بود",
+	"record_consent_failed":         "ثبت رضایت ناموفق بود",
+	"list_consents_failed":          "دریافت فهرست رضایت‌ها ناموفق بود",
+	"publish_legal_document_failed": "انتشار سند حقوقی ناموفق بود",
+
+	// Preferences
+	"invalid_theme":             "پوسته باید system، light یا dark باشد",
+	"invalid_time_zone":         "منطقهٔ زمانی باید یک منطقهٔ زمانی IANA مانند Asia/Tehran باشد",
+	"get_preferences_failed":    "دریافت تنظیمات ناموفق بود",
+	"update_preferences_failed": "به‌روزرسانی تنظیمات ناموفق بود",
+
+	// Translation bundles
+	"invalid_arb":                    "بدنهٔ درخواست باید یک فایل ARB باشد",
+	"arb_locale_mismatch":            "مقدار @@locale فایل با زبانی که برای آن بارگذاری می‌شود یکی نیست",
+	"translation_template_missing":   "پیش از بارگذاری ترجمه‌ها، الگوی انگلیسی را منتشر کنید",
+	"translation_bundle_not_found":   "بستهٔ ترجمه پیدا نشد",
+	"translation_bundle_not_pending": "بستهٔ ترجمه قبلاً بررسی شده است",
+	"invalid_translation_bundle":     "برخی از پیام‌های بستهٔ ترجمه نامعتبرند",
+	"unknown_message_key":            "الگوی انگلیسی پیامی با این کلید ندارد",
+	"invalid_icu_message":            "یک پیام ICU معتبر نیست",
+	"placeholder_mismatch":           "پیام باید از جانگهدارهای %s استفاده کند",
+	"placeholders_not_allowed":       "پیام انگلیسی جانگهدار ندارد",
+	"placeholder_undeclared":         "جانگهدار {%s} در فراداده‌های پیام تعریف نشده است",
+	"get_translations_failed":        "دریافت ترجمه‌ها ناموفق بود",
+	"upload_translations_failed":     "بارگذاری ترجمه‌ها ناموفق بود",
+	"list_translations_failed":       "فهرست کردن ترجمه‌ها ناموفق بود",
+	"review_translations_failed":     "بررسی ترجمه‌ها ناموفق بود",
+
+	// Email templates
+	"email_template_not_found": "قالب ایمیل پیدا نشد",
+	"invalid_preview_format":   "قالب‌بندی باید html یا text باشد",
+	"preview_email_failed":     "پیش‌نمایش ایمیل ناموفق بود",
+
+	// Audit log
+	"get_audit_events_failed": "دریافت رویدادهای ممیزی ناموفق بود",
+	"verify_audit_log_failed": "بررسی گزارش ممیزی ناموفق بود",
+
+	// Success messages
+	"password_reset_sent":        "ایمیل بازنشانی رمز عبور ارسال شد",
+	"profile_updated":            "نمایه با موفقیت به‌روزرسانی شد",
+	"email_updated":              "ایمیل با موفقیت به‌روزرسانی شد",
+	"account_deleted":            "حساب با موفقیت حذف شد",
+	"account_deletion_scheduled": "حذف حساب زمان‌بندی شد. برای لغو آن، پیش از حذف نهایی دوباره وارد شوید.",
+	"account_restored":           "حساب بازیابی شد",
+	"otp_sent":                   "کد ارسال شد",
+	"magic_link_sent":            "اگر این ایمیل ثبت شده باشد، پیوند ورود ارسال شده است",
+	"verification_code_sent":     "کد تأیید ارسال شد",
+	"email_linked":               "ایمیل با موفقیت متصل شد",
+	"phone_linked":               "شماره تلفن با موفقیت متصل شد",
+	"password_set":               "رمز عبور با موفقیت تنظیم شد",
+	"login_method_removed":       "روش ورود حذف شد",
+	"device_revoked":             "دسترسی دستگاه با موفقیت لغو شد",
+	"all_devices_revoked":        "دسترسی همه دستگاه‌ها با موفقیت لغو شد",
+	"invitation_revoked":         "دعوت‌نامه لغو شد",
+	"member_updated":             "عضو با موفقیت به‌روزرسانی شد",
+	"member_removed":             "عضو با موفقیت حذف شد",
+	"org_invitation_revoked":     "دعوت‌نامه لغو شد",
+	"org_invitation_accepted":    "دعوت‌نامه پذیرفته شد",
+	"org_invitation_declined":    "دعوت‌نامه رد شد",
+}
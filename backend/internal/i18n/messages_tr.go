@@ -0,0 +1,225 @@
+package i18n
+
+var messagesTR = map[string]string{
+	// Gateway
+	"missing_authorization":        "Yetkilendirme başlığı eksik",
+	"invalid_authorization_header": "Yetkilendirme başlığının biçimi geçersiz",
+	"invalid_token":                "Belirteç geçersiz veya süresi dolmuş",
+	"invalid_token_claims":         "Belirteç bilgileri geçersiz",
+	"rate_limited":                 "İstek sınırı aşıldı. Lütfen daha sonra tekrar deneyin.",
+	"consent_required":             "Devam etmek için en son koşulları kabul etmeniz gerekiyor",
+	"internal_error":               "Sunucu hatası",
+	"service_unavailable":          "Hizmet kullanılamıyor",
+
+	// Requests
+	"unauthorized":             "Yetkisiz",
+	"forbidden":                "Erişim engellendi",
+	"field_required":           "Bu alan gereklidir",
+	"invalid_request_body":     "İstek gövdesi geçersiz",
+	"invalid_limit":            "limit 1 ile 100 arasında olmalıdır",
+	"invalid_offset":           "offset negatif olmamalıdır",
+	"invalid_timestamp":        "%s bir RFC 3339 zaman damgası olmalıdır",
+	"check_permissions_failed": "İzinler kontrol edilemedi",
+
+	// Registration and login
+	"registration_fields_required": "E-posta, şifre ve ad gereklidir",
+	"credentials_required":         "E-posta ve şifre gereklidir",
+	"password_required":            "Şifre gereklidir",
+	"password_too_short":           "Şifre en az 8 karakter olmalıdır",
+	"email_exists":                 "Bu e-posta zaten kayıtlı",
+	"invalid_credentials":          "Giriş bilgileri hatalı",
+	"refresh_token_required":       "Yenileme belirteci gereklidir",
+	"invalid_refresh_token":        "Yenileme belirteci geçersiz",
+	"reauthentication_required":    "Kimliğinizi yeniden doğrulamanız gerekiyor",
+	"challenge_failed":             "İş kanıtı sorgusu eksik, süresi dolmuş veya çözülmemiş",
+	"registration_rate_limited":    "Ağınızdan çok fazla kayıt yapıldı. Lütfen daha sonra tekrar deneyin.",
+	"create_challenge_failed":      "Sorgu oluşturulamadı",
+	"register_failed":              "Kullanıcı kaydedilemedi",
+	"login_failed":                 "Oturum açılamadı",
+	"refresh_failed":               "Belirteç yenilenemedi",
+	"reauthenticate_failed":        "Kimlik yeniden doğrulanamadı",
+
+	// Registration policy
+	"email_domain_not_allowed":  "Kayıt yalnızca onaylı e-posta alan adlarıyla yapılabilir",
+	"email_domain_blocked":      "Bu e-posta alan adı kullanılamaz",
+	"disposable_email":          "Geçici e-posta adresleri kullanılamaz",
+	"invitation_required":       "Kayıt için davet kodu gereklidir",
+	"phone_invitation_required": "Telefon numarasıyla kayıt için davet kodu gereklidir",
+	"invalid_invitation":        "Davet kodu geçersiz veya süresi dolmuş",
+
+	// Names and email addresses
+	"invalid_email":           "E-posta adresi geçerli değil",
+	"name_required":           "Ad gereklidir",
+	"name_too_long":           "Ad en fazla %d karakter olmalıdır",
+	"name_invalid_characters": "Ad izin verilmeyen karakterler içeriyor",
+
+	// Users and accounts
+	"user_id_required":         "Kullanıcı kimliği gereklidir",
+	"user_not_found":           "Kullanıcı bulunamadı",
+	"email_address_required":   "E-posta gereklidir",
+	"account_pending_deletion": "Bu hesap silinmek üzere planlandı. Geri yüklemek için cancel_deletion ile oturum açın.",
+	"account_not_deleted":      "Hesap silinmek üzere planlanmamış",
+	"get_profile_failed":       "Profil alınamadı",
+	"update_profile_failed":    "Profil güncellenemedi",
+	"list_users_failed":        "Kullanıcılar listelenemedi",
+	"get_user_failed":          "Kullanıcı alınamadı",
+	"change_password_failed":   "Şifre değiştirilemedi",
+	"change_email_failed":      "E-posta değiştirilemedi",
+	"delete_account_failed":    "Hesap silinemedi",
+	"restore_account_failed":   "Hesap geri yüklenemedi",
+	"get_usage_failed":         "Kullanım istatistikleri alınamadı",
+
+	// Phone numbers and codes
+	"phone_required":          "Telefon numarası gereklidir",
+	"phone_and_code_required": "Telefon numarası ve kod gereklidir",
+	"invalid_phone":           "Telefon numarası geçerli bir uluslararası numara olmalıdır",
+	"phone_exists":            "Bu telefon numarası zaten kayıtlı",
+	"signup_name_required":    "Kaydolmak için ad gereklidir",
+	"invalid_code":            "Kod geçersiz veya süresi dolmuş",
+	"code_cooldown":           "Yeni bir kod istemeden önce lütfen bekleyin",
+	"code_rate_limited":       "Çok fazla kod istendi. Lütfen daha sonra tekrar deneyin.",
+	"send_code_failed":        "Kod gönderilemedi",
+	"verify_code_failed":      "Kod doğrulanamadı",
+
+	// Login links
+	"email_and_device_id_required": "E-posta ve device_id gereklidir",
+	"magic_link_fields_required":   "device_id ile birlikte belirteç ya da e-posta ve kod gereklidir",
+	"invalid_magic_link":           "Giriş bağlantısı geçersiz veya süresi dolmuş",
+	"send_magic_link_failed":       "Giriş bağlantısı gönderilemedi",
+	"verify_magic_link_failed":     "Giriş bağlantısı doğrulanamadı",
+
+	// Guest accounts
+	"guest_access_disabled": "Misafir girişi devre dışı. Lütfen bunun yerine kaydolun.",
+	"guest_not_allowed":     "Misafir hesaplarının önce yükseltilmesi gerekir",
+	"not_guest":             "Bu hesap bir misafir hesabı değil",
+	"create_guest_failed":   "Misafir hesabı oluşturulamadı",
+	"upgrade_failed":        "Hesap yükseltilemedi",
+
+	// Login methods
+	"identity_already_linked":       "Bu giriş yöntemi zaten bağlı",
+	"identity_not_linked":           "Bu giriş yöntemi bağlı değil",
+	"last_identity":                 "Son giriş yönteminizi kaldıramazsınız",
+	"email_required":                "Şifre belirlemeden önce bir e-posta adresi bağlayın",
+	"invalid_verification_code":     "Kod geçersiz veya süresi dolmuş",
+	"list_identities_failed":        "Giriş yöntemleri listelenemedi",
+	"send_verification_code_failed": "Doğrulama kodu gönderilemedi",
+	"link_email_failed":             "E-posta bağlanamadı",
+	"link_phone_failed":             "Telefon numarası bağlanamadı",
+	"set_password_failed":           "Şifre belirlenemedi",
+	"remove_identity_failed":        "Giriş yöntemi kaldırılamadı",
+
+	// Devices and sign-ins
+	"device_id_required":       "Cihaz kimliği gereklidir",
+	"device_not_found":         "Cihaz bulunamadı",
+	"list_devices_failed":      "Cihazlar listelenemedi",
+	"revoke_device_failed":     "Cihaz kaldırılamadı",
+	"revoke_devices_failed":    "Cihazlar kaldırılamadı",
+	"get_login_history_failed": "Oturum açma geçmişi alınamadı",
+
+	// Data exports
+	"export_rate_limited":    "Verilerinizin bir kopyasını kısa süre önce istediniz. Lütfen daha sonra tekrar deneyin.",
+	"export_not_found":       "Veri dışa aktarımı bulunamadı",
+	"export_not_ready":       "Veri dışa aktarımınız henüz hazır değil",
+	"export_ready_sms":       "Veri dışa aktarımınız hazır. %s tarihinden önce uygulamadan indirin.",
+	"invalid_export_link":    "Bu indirme bağlantısı geçersiz veya süresi dolmuş",
+	"request_export_failed":  "Veri dışa aktarımı istenemedi",
+	"list_exports_failed":    "Veri dışa aktarımları listelenemedi",
+	"get_export_failed":      "Veri dışa aktarımı alınamadı",
+	"download_export_failed": "Veri dışa aktarımı indirilemedi",
+
+	// Invitation codes
+	"invalid_invitation_limits": "max_uses ve expires_in negatif olmamalıdır",
+	"invitation_not_found":      "Davet bulunamadı",
+	"create_invitation_failed":  "Davet oluşturulamadı",
+	"list_invitations_failed":   "Davetler listelenemedi",
+	"revoke_invitation_failed":  "Davet iptal edilemedi",
+
+	// Organizations
+	"organization_not_found":          "Kuruluş bulunamadı",
+	"organization_permission_denied":  "Bu kuruluştaki rolünüz buna izin vermiyor",
+	"invalid_role":                    "Rol owner, admin veya member olmalıdır",
+	"last_owner":                      "Bir kuruluşun en az bir sahibi olmalıdır",
+	"member_not_found":                "Üye bulunamadı",
+	"already_member":                  "Kullanıcı zaten üye",
+	"invalid_org_invitation":          "Davet geçersiz veya süresi dolmuş",
+	"invitation_email_mismatch":       "Bu davet farklı bir e-posta adresine gönderildi",
+	"org_invitation_token_required":   "Bu daveti e-postasındaki bağlantıyla kabul edin veya önce e-posta adresinizi doğrulayın",
+	"invitation_token_or_id_required": "Belirteç veya kimlik gereklidir",
+	"create_organization_failed":      "Kuruluş oluşturulamadı",
+	"list_organizations_failed":       "Kuruluşlar listelenemedi",
+	"switch_organization_failed":      "Kuruluş değiştirilemedi",
+	"list_members_failed":             "Üyeler listelenemedi",
+	"update_member_failed":            "Üye güncellenemedi",
+	"remove_member_failed":            "Üye çıkarılamadı",
+	"send_invitation_failed":          "Davet gönderilemedi",
+	"accept_invitation_failed":        "Davet kabul edilemedi",
+	"decline_invitation_failed":       "Davet reddedilemedi",
+
+	// Legal documents and consents
+	"unknown_legal_document":        "Bilinmeyen yasal belge",
+	"consent_outdated":              "Belgenin daha yeni bir sürümü yayımlandı; onu alıp kabul edin",
+	"consents_required":             "Onaylar gereklidir",
+	"unsupported_locale":            "Dil en, ar, fa veya tr olmalıdır",
+	"english_translation_required":  "İngilizce çeviri gereklidir",
+	"translation_incomplete":        "Her çevirinin bir başlığı ve metni olmalıdır",
+	"get_legal_documents_failed":    "Yasal belgeler alınamadı",
+	"get_legal_versions_failed":     "Yasal belge sürümleri alınamadı",
+	"record_consent_failed":         "Onay kaydedilemedi",
+	"list_consents_failed":          "Onaylar listelenemedi",
+	"publish_legal_document_failed": "Yasal belge yayımlanamadı",
+
+	// Preferences
+	"invalid_theme":             "Tema system, light veya dark olmalıdır",
+	"invalid_time_zone":         "Saat dilimi Europe/Istanbul gibi bir IANA saat dilimi olmalıdır",
+	"get_preferences_failed":    "Tercihler alınamadı",
+	"update_preferences_failed": "Tercihler güncellenemedi",
+
+	// Translation bundles
+	"invalid_arb":                    "İstek gövdesi bir ARB dosyası olmalıdır",
+	"arb_locale_mismatch":            "Dosyanın @@locale değeri yüklendiği dille eşleşmiyor",
+	"translation_template_missing":   "Çeviri yüklemeden önce İngilizce şablonu yayınlayın",
+	"translation_bundle_not_found":   "Çeviri paketi bulunamadı",
+	"translation_bundle_not_pending": "Çeviri paketi zaten incelendi",
+	"invalid_translation_bundle":     "Çeviri paketindeki bazı mesajlar geçersiz",
+	"unknown_message_key":            "İngilizce şablonda bu anahtara sahip bir mesaj yok",
+	"invalid_icu_message":            "Geçerli bir ICU mesajı değil",
+	"placeholder_mismatch":           "Mesaj %s yer tutucularını kullanmalıdır",
+	"placeholders_not_allowed":       "İngilizce mesajda yer tutucu yok",
+	"placeholder_undeclared":         "{%s} yer tutucusu mesajın meta verilerinde tanımlanmamış",
+	"get_translations_failed":        "Çeviriler alınamadı",
+	"upload_translations_failed":     "Çeviriler yüklenemedi",
+	"list_translations_failed":       "Çeviriler listelenemedi",
+	"review_translations_failed":     "Çeviriler incelenemedi",
+
+	// Email templates
+	"email_template_not_found": "E-posta şablonu bulunamadı",
+	"invalid_preview_format":   "Biçim html veya text olmalıdır",
+	"preview_email_failed":     "E-posta önizlemesi oluşturulamadı",
+
+	// Audit log
+	"get_audit_events_failed": "Denetim olayları alınamadı",
+	"verify_audit_log_failed": "Denetim kaydı doğrulanamadı",
+
+	// Success messages
+	"password_reset_sent":        "Şifre sıfırlama e-postası gönderildi",
+	"profile_updated":            "Profil başarıyla güncellendi",
+	"email_updated":              "E-posta başarıyla güncellendi",
+	"account_deleted":            "Hesap başarıyla silindi",
+	"account_deletion_scheduled": "Hesap silinmek üzere planlandı. İptal etmek için hesap kalıcı olarak silinmeden önce yeniden giriş yapın.",
+	"account_restored":           "Hesap geri yüklendi",
+	"otp_sent":                   "Kod gönderildi",
+	"magic_link_sent":            "E-posta kayıtlıysa bir giriş bağlantısı gönderildi",
+	"verification_code_sent":     "Doğrulama kodu gönderildi",
+	"email_linked":               "E-posta başarıyla bağlandı",
+	"phone_linked":               "Telefon başarıyla bağlandı",
+	"password_set":               "Şifre başarıyla belirlendi",
+	"login_method_removed":       "Giriş yöntemi kaldırıldı",
+	"device_revoked":             "Cihaz başarıyla iptal edildi",
+	"all_devices_revoked":        "Tüm cihazlar başarıyla iptal edildi",
+	"invitation_revoked":         "Davet iptal edildi",
+	"member_updated":             "Üye başarıyla güncellendi",
+	"member_removed":             "Üye başarıyla kaldırıldı",
+	"org_invitation_revoked":     "Davet iptal edildi",
+	"org_invitation_accepted":    "Davet kabul edildi",
+	"org_invitation_declined":    "Davet reddedildi",
+}
@@ -0,0 +1,150 @@
+package mailer
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers outbound email.
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// PermanentError is a failed send that would fail the same way if it were
+// retried, such as an address the server rejects.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent reports whether err is a PermanentError, so the send shouldn't
+// be retried.
+func Permanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// LogMailer writes messages to the service log instead of delivering them.
+// It is meant for local development where no mail server is available.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(msg *Message) error {
+	log.Printf("📧 Mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// FileMailer appends every message as a JSON line to a file, so tests and
+// local tooling can read the links and codes that would have been sent.
+type FileMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+type fileMessage struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+	HTML    string    `json:"html,omitempty"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+func (m *FileMailer) Send(msg *Message) error {
+	line, err := json.Marshal(fileMessage{
+		To:      msg.To,
+		Subject: msg.Subject,
+		Text:    msg.Text,
+		HTML:    msg.HTML,
+		SentAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// MemoryMailer keeps the messages it is given in memory for tests to
+// inspect.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := make([]Message, len(m.messages))
+	copy(messages, m.messages)
+	return messages
+}
+
+// Reset forgets the messages sent so far.
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
+
+// FailWith makes every send fail with err until it is called with nil, to
+// test how failures are handled.
+func (m *MemoryMailer) FailWith(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
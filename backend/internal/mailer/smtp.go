@@ -0,0 +1,235 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How the connection to the SMTP server is secured.
+const (
+	// TLSStartTLS upgrades the connection with STARTTLS and refuses servers
+	// that don't offer it, usually on port 587
+	TLSStartTLS = "starttls"
+
+	// TLSImplicit connects with TLS from the start, usually on port 465
+	TLSImplicit = "tls"
+
+	// TLSNone sends in plain text, for a local server such as Mailpit
+	TLSNone = "none"
+)
+
+// smtpTimeout bounds a whole delivery, from connecting to QUIT.
+const smtpTimeout = 30 * time.Second
+
+var ErrUnknownTLSMode = errors.New("SMTP TLS mode must be starttls, tls or none")
+
+// SMTPMailer delivers messages through an SMTP server, as text with an
+// HTML alternative when the message has one.
+type SMTPMailer struct {
+	host    string
+	addr    string
+	from    *mail.Address
+	auth    smtp.Auth
+	tlsMode string
+}
+
+// NewSMTPMailer returns a mailer that sends from from, an address such as
+// "MultiLangBloc <no-reply@example.com>", through the server at host and
+// port. Without a username it doesn't authenticate.
+func NewSMTPMailer(host string, port int, username, password, from, tlsMode string) (*SMTPMailer, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", from, err)
+	}
+	if tlsMode != TLSStartTLS && tlsMode != TLSImplicit && tlsMode != TLSNone {
+		return nil, ErrUnknownTLSMode
+	}
+
+	m := &SMTPMailer{
+		host:    host,
+		addr:    net.JoinHostPort(host, strconv.Itoa(port)),
+		from:    sender,
+		tlsMode: tlsMode,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+func (m *SMTPMailer) Send(msg *Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return &PermanentError{Err: fmt.Errorf("invalid recipient address: %w", err)}
+	}
+
+	data, err := m.compose(msg, to)
+	if err != nil {
+		return err
+	}
+
+	return classify(m.deliver(to.Address, data))
+}
+
+// deliver runs the SMTP conversation that hands data to the server.
+func (m *SMTPMailer) deliver(to string, data []byte) error {
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	tlsConfig := &tls.Config{ServerName: m.host}
+
+	var conn net.Conn
+	var err error
+	if m.tlsMode == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", m.addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", m.addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if m.tlsMode == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP server doesn't support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if err := client.Auth(m.auth); err != nil {
+			// Rejected credentials are a configuration problem; once it is
+			// fixed, the retries go through
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// compose writes msg as a MIME message: its text, and its HTML as an
+// alternative if it has any, both quoted-printable UTF-8.
+func (m *SMTPMailer) compose(msg *Message, to *mail.Address) ([]byte, error) {
+	id, err := messageID(m.from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", m.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", id)
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/alternative; boundary="`+parts.Boundary()+`"`)
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID returns a unique Message-ID in the domain of the sender
+// address from.
+func messageID(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndexByte(from, '@'); at >= 0 {
+		domain = from[at+1:]
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">", nil
+}
+
+// classify marks the errors of replies in the 5xx range, which the server
+// would give again, as permanent. Other errors, such as 4xx replies and
+// network failures, are worth retrying.
+func classify(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,273 @@
+package mailer
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpServer is an SMTP server for one delivery that accepts whatever it
+// is sent, except for recipients when rcptReply is set.
+type smtpServer struct {
+	listener  net.Listener
+	rcptReply string
+
+	// What the client sent, once done is closed
+	from string
+	to   string
+	data string
+	done chan struct{}
+}
+
+func newSMTPServer(t *testing.T, rcptReply string) *smtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpServer{listener: listener, rcptReply: rcptReply, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+func (s *smtpServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "MAIL":
+			s.from = line
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			s.to = line
+			if s.rcptReply != "" {
+				text.PrintfLine("%s", s.rcptReply)
+				continue
+			}
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 Go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data = string(data)
+			text.PrintfLine("250 Queued")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) mailer(t *testing.T) *SMTPMailer {
+	t.Helper()
+
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	m, err := NewSMTPMailer(host, portNumber, "", "", "MultiLangBloc <no-reply@example.com>", TLSNone)
+	if err != nil {
+		t.Fatalf("NewSMTPMailer: %v", err)
+	}
+	return m
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	server := newSMTPServer(t, "")
+
+	err := server.mailer(t).Send(&Message{
+		To:      "Ada <ada@example.org>",
+		Subject: "Grüße",
+		Text:    "Your code is 123456",
+		HTML:    "<p>Your code is <b>123456</b></p>",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-server.done
+
+	if server.from != "MAIL FROM:<no-reply@example.com>" {
+		t.Errorf("MAIL = %q", server.from)
+	}
+	if server.to != "RCPT TO:<ada@example.org>" {
+		t.Errorf("RCPT = %q", server.to)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(server.data))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Grüße" {
+		t.Errorf("Subject = %q, %v, want Grüße", subject, err)
+	}
+	if to := msg.Header.Get("To"); to != `"Ada" <ada@example.org>` {
+		t.Errorf("To = %q", to)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q, want one in the sender's domain", id)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v, want multipart/alternative", mediaType, err)
+	}
+
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Your code is 123456"},
+		{"text/html; charset=utf-8", "<p>Your code is <b>123456</b></p>"},
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range want {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		// The reader decodes quoted-printable parts
+		body, _ := io.ReadAll(part)
+		if contentType := part.Header.Get("Content-Type"); contentType != want.contentType {
+			t.Errorf("part Content-Type = %q, want %q", contentType, want.contentType)
+		}
+		if string(body) != want.body {
+			t.Errorf("%s part = %q, want %q", want.contentType, body, want.body)
+		}
+	}
+	if _, err := parts.NextPart(); err != io.EOF {
+		t.Errorf("NextPart after the HTML part = %v, want io.EOF", err)
+	}
+}
+
+func TestSMTPMailerSendText(t *testing.T) {
+	server := newSMTPServer(t, "")
+
+	text := "Ссылка для входа: https://example.com/magic?token=abc"
+	if err := server.mailer(t).Send(&Message{To: "ada@example.org", Subject: "Sign in", Text: text}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-server.done
+
+	msg, err := mail.ReadMessage(strings.NewReader(server.data))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if contentType := msg.Header.Get("Content-Type"); contentType != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/plain without an HTML alternative", contentType)
+	}
+	if encoding := msg.Header.Get("Content-Transfer-Encoding"); encoding != "quoted-printable" {
+		t.Errorf("Content-Transfer-Encoding = %q", encoding)
+	}
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("decoding the body: %v", err)
+	}
+	// The DATA terminator adds a line break after the body
+	if got := strings.TrimSuffix(string(body), "\n"); got != text {
+		t.Errorf("body = %q, want %q", got, text)
+	}
+}
+
+func TestSMTPMailerSendRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		rcptReply string
+		permanent bool
+	}{
+		{"unknown mailbox", "550 5.1.1 No such user", true},
+		{"mailbox full", "452 4.2.2 Mailbox full", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSMTPServer(t, tt.rcptReply)
+
+			err := server.mailer(t).Send(&Message{To: "ada@example.org", Subject: "Hi", Text: "Hi"})
+			if err == nil {
+				t.Fatal("Send succeeded, want the rejection")
+			}
+			if Permanent(err) != tt.permanent {
+				t.Errorf("Permanent(%v) = %v, want %v", err, !tt.permanent, tt.permanent)
+			}
+		})
+	}
+}
+
+func TestSMTPMailerSendInvalidRecipient(t *testing.T) {
+	server := newSMTPServer(t, "")
+
+	err := server.mailer(t).Send(&Message{To: "not an address", Subject: "Hi", Text: "Hi"})
+	if !Permanent(err) {
+		t.Errorf("Send = %v, want a permanent error", err)
+	}
+}
+
+func TestSMTPMailerSendUnreachable(t *testing.T) {
+	server := newSMTPServer(t, "")
+	m := server.mailer(t)
+	server.listener.Close()
+	<-server.done
+
+	err := m.Send(&Message{To: "ada@example.org", Subject: "Hi", Text: "Hi"})
+	if err == nil || Permanent(err) {
+		t.Errorf("Send = %v, want an error worth retrying", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{"success", nil, false},
+		{"5xx reply", &textproto.Error{Code: 554, Msg: "Transaction failed"}, true},
+		{"4xx reply", &textproto.Error{Code: 421, Msg: "Try again later"}, false},
+		{"network failure", io.ErrUnexpectedEOF, false},
+		{"wrapped 5xx reply", errors.Join(errors.New("rcpt"), &textproto.Error{Code: 550}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classify(tt.err)
+			if (err == nil) != (tt.err == nil) {
+				t.Fatalf("classify(%v) = %v", tt.err, err)
+			}
+			if Permanent(err) != tt.permanent {
+				t.Errorf("Permanent(classify(%v)) = %v, want %v", tt.err, !tt.permanent, tt.permanent)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("classify(%v) = %v, which doesn't wrap the error", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+// Package mailtmpl renders the emails the services send, in every
+// supported language, as HTML and as plain text.
+//
+// Templates live in templates/: layout.html.tmpl and layout.txt.tmpl are
+// the layouts every email inherits, and each language has a directory with
+// common.html.tmpl and common.txt.tmpl, which fill in the layouts' shared
+// blocks such as the footer, and a pair of files per email, such as
+// magic_link.html.tmpl and magic_link.txt.tmpl. An email defines its
+// "subject" in the text file and its "content" in both. Emails without a
+// translation are sent in English.
+package mailtmpl
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"backend/internal/datefmt"
+	"backend/internal/i18n"
+)
+
+//go:embed templates
+var files embed.FS
+
+var ErrUnknownEmail = errors.New("unknown email template")
+
+// Emails the services send, by template name.
+const (
+	MagicLink         = "magic_link"
+	EmailVerification = "email_verification"
+	OrgInvitation     = "org_invitation"
+	NewSignIn         = "new_sign_in"
+	ExportReady       = "export_ready"
+)
+
+// MagicLinkData fills in the MagicLink email.
+type MagicLinkData struct {
+	Name    string
+	URL     string
+	Code    string
+	Minutes int
+}
+
+// EmailVerificationData fills in the EmailVerification email.
+type EmailVerificationData struct {
+	Name    string
+	Code    string
+	Minutes int
+}
+
+// OrgInvitationData fills in the OrgInvitation email.
+type OrgInvitationData struct {
+	InviterName string
+	OrgName     string
+	Role        string
+	URL         string
+	Days        int
+}
+
+// NewSignInData fills in the NewSignIn email. Time is displayed in the
+// recipient's language and time zone already.
+type NewSignInData struct {
+	Name     string
+	Time     string
+	IP       string
+	Location string
+	Device   string
+}
+
+// ExportReadyData fills in the ExportReady email. Expires is displayed in
+// the recipient's language and time zone already.
+type ExportReadyData struct {
+	Name    string
+	Expires string
+}
+
+// samples are example data of every email, which the templates are checked
+// with when they are loaded and previewed with. Render takes data of the
+// same type.
+var samples = map[string]interface{}{
+	MagicLink: MagicLinkData{
+		Name:    "Sara",
+		URL:     "https://app.example.com/auth/magic?token=sample",
+		Code:    "482913",
+		Minutes: 15,
+	},
+	EmailVerification: EmailVerificationData{
+		Name:    "Sara",
+		Code:    "482913",
+		Minutes: 15,
+	},
+	OrgInvitation: OrgInvitationData{
+		InviterName: "Ali",
+		OrgName:     "Acme",
+		Role:        "admin",
+		URL:         "https://app.example.com/invitations?token=sample",
+		Days:        7,
+	},
+	NewSignIn: NewSignInData{
+		Name:     "Sara",
+		Time:     "October 14, 2024 15:30",
+		IP:       "203.0.113.7",
+		Location: "Istanbul, TR",
+		Device:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)",
+	},
+	ExportReady: ExportReadyData{
+		Name:    "Sara",
+		Expires: "October 21, 2024 15:30",
+	},
+}
+
+// Email is a rendered email.
+type Email struct {
+	// Locale is the language the email is in, English if it has no
+	// translation into the requested one
+	Locale  string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Templates are the parsed email templates.
+type Templates struct {
+	// templates by email name and locale
+	templates map[string]map[string]*template
+}
+
+// template is an email in one language.
+type template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Load parses the templates and renders each with its sample data, so that
+// a broken template or translation stops the service from starting rather
+// than failing a send. Every email needs an English template.
+func Load() (*Templates, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) (*Templates, error) {
+	t := &Templates{templates: make(map[string]map[string]*template)}
+	for name := range samples {
+		t.templates[name] = make(map[string]*template)
+	}
+
+	dirs, err := fs.ReadDir(fsys, "templates")
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		locale := dir.Name()
+		if i18n.Supported(locale) != locale {
+			return nil, fmt.Errorf("templates/%s: not a supported locale", locale)
+		}
+		if err := t.loadLocale(fsys, locale); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, byLocale := range t.templates {
+		if byLocale[i18n.Default] == nil {
+			return nil, fmt.Errorf("email %s has no English template", name)
+		}
+	}
+	return t, nil
+}
+
+// loadLocale parses and checks the templates of locale.
+func (t *Templates) loadLocale(fsys fs.FS, locale string) error {
+	dir := path.Join("templates", locale)
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	// Each email needs both variants
+	variants := make(map[string]int)
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".html.tmpl")
+		if !ok {
+			name, ok = strings.CutSuffix(entry.Name(), ".txt.tmpl")
+		}
+		if !ok {
+			return fmt.Errorf("%s/%s: not an email template", dir, entry.Name())
+		}
+		if name == "common" {
+			continue
+		}
+		if _, ok := samples[name]; !ok {
+			return fmt.Errorf("%s/%s: %w", dir, entry.Name(), ErrUnknownEmail)
+		}
+		variants[name]++
+	}
+
+	for name, count := range variants {
+		if count != 2 {
+			return fmt.Errorf("%s/%s: needs both an .html.tmpl and a .txt.tmpl file", dir, name)
+		}
+
+		tmpl, err := parse(fsys, locale, name)
+		if err != nil {
+			return err
+		}
+		if _, err := tmpl.render(locale, samples[name]); err != nil {
+			return fmt.Errorf("%s/%s: %w", dir, name, err)
+		}
+		t.templates[name][locale] = tmpl
+	}
+	return nil
+}
+
+// parse parses email name in locale on top of the layouts and the common
+// blocks of locale.
+func parse(fsys fs.FS, locale, name string) (*template, error) {
+	files := func(ext string) []string {
+		return []string{
+			"templates/layout" + ext,
+			path.Join("templates", locale, "common"+ext),
+			path.Join("templates", locale, name+ext),
+		}
+	}
+
+	text, err := texttemplate.New(name).
+		Funcs(textFuncs(locale)).
+		Option("missingkey=error").
+		ParseFS(fsys, files(".txt.tmpl")...)
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New(name).
+		Funcs(htmlFuncs(locale)).
+		Option("missingkey=error").
+		ParseFS(fsys, files(".html.tmpl")...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &template{text: text, html: html}, nil
+}
+
+// render renders the email in locale with data.
+func (tmpl *template) render(locale string, data interface{}) (*Email, error) {
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "layout.txt.tmpl", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout.html.tmpl", data); err != nil {
+		return nil, err
+	}
+
+	email := &Email{
+		Locale:  locale,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}
+	if email.Subject == "" || strings.ContainsAny(email.Subject, "\r\n") {
+		return nil, errors.New("subject must be a single line")
+	}
+	return email, nil
+}
+
+// Render renders email name in locale, a supported language, or in English
+// if it has no translation. data is the email's data type, such as
+// MagicLinkData for MagicLink.
+func (t *Templates) Render(name, locale string, data interface{}) (*Email, error) {
+	byLocale, ok := t.templates[name]
+	if !ok {
+		return nil, ErrUnknownEmail
+	}
+	if reflect.TypeOf(data) != reflect.TypeOf(samples[name]) {
+		return nil, fmt.Errorf("email %s takes %T, not %T", name, samples[name], data)
+	}
+
+	tmpl, ok := byLocale[locale]
+	if !ok {
+		locale = i18n.Default
+		tmpl = byLocale[locale]
+	}
+	return tmpl.render(locale, data)
+}
+
+// Preview renders email name in locale with sample data.
+func (t *Templates) Preview(name, locale string) (*Email, error) {
+	sample, ok := samples[name]
+	if !ok {
+		return nil, ErrUnknownEmail
+	}
+	return t.Render(name, locale, sample)
+}
+
+// Names returns the names of the emails, sorted.
+func (t *Templates) Names() []string {
+	names := make([]string, 0, len(t.templates))
+	for name := range t.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locales returns the languages email name is translated into, sorted.
+func (t *Templates) Locales(name string) []string {
+	locales := make([]string, 0, len(t.templates[name]))
+	for locale := range t.templates[name] {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// textFuncs are the functions of the text templates of locale.
+func textFuncs(locale string) texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		"number": func(n int) string {
+			return datefmt.Number(n, locale)
+		},
+		// ltr isolates left-to-right text such as links and codes in
+		// right-to-left text, so punctuation around it stays in place
+		"ltr": func(s string) string {
+			if i18n.Direction(locale) == "rtl" {
+				return "\u2066" + s + "\u2069"
+			}
+			return s
+		},
+	}
+}
+
+// htmlFuncs are the functions of the HTML templates of locale.
+func htmlFuncs(locale string) htmltemplate.FuncMap {
+	dir := i18n.Direction(locale)
+	align := "left"
+	if dir == "rtl" {
+		align = "right"
+	}
+
+	return htmltemplate.FuncMap{
+		"lang": func() string { return locale },
+		"dir":  func() string { return dir },
+		// align is the side text starts on; email clients that ignore dir
+		// still honor align attributes and text-align
+		"align": func() string { return align },
+		"number": func(n int) string {
+			return datefmt.Number(n, locale)
+		},
+		"ltr": func(s string) htmltemplate.HTML {
+			return htmltemplate.HTML(`<span dir="ltr">` + htmltemplate.HTMLEscapeString(s) + `</span>`)
+		},
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,7 +12,12 @@ import (
 
 type contextKey string
 
-const UserIDKey contextKey = "userID"
+const (
+	UserIDKey contextKey = "userID"
+
+	// ClaimsKey holds the jwt.MapClaims of the request's access token.
+	ClaimsKey contextKey = "claims"
+)
 
 type AuthMiddleware struct {
 	jwtSecret string
@@ -27,18 +33,14 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			respondJSON(w, http.StatusUnauthorized, map[string]string{
-				"error": "Missing authorization header",
-			})
+			RespondError(w, r, http.StatusUnauthorized, "missing_authorization")
 			return
 		}
 
 		// Extract token from "Bearer <token>"
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			respondJSON(w, http.StatusUnauthorized, map[string]string{
-				"error": "Invalid authorization header format",
-			})
+			RespondError(w, r, http.StatusUnauthorized, "invalid_authorization_header")
 			return
 		}
 
@@ -53,9 +55,7 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 		})
 
 		if err != nil || !token.Valid {
-			respondJSON(w, http.StatusUnauthorized, map[string]string{
-				"error": "Invalid or expired token",
-			})
+			RespondError(w, r, http.StatusUnauthorized, "invalid_token")
 			return
 		}
 
@@ -64,15 +64,60 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 			if userID, ok := claims["user_id"].(string); ok {
 				// Add user ID to context
 				ctx := context.WithValue(r.Context(), UserIDKey, userID)
+				ctx = context.WithValue(ctx, ClaimsKey, claims)
 				// Add user ID to header for downstream services
 				r.Header.Set("X-User-ID", userID)
+				setAuthenticationHeaders(r, claims)
 				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
 		}
 
-		respondJSON(w, http.StatusUnauthorized, map[string]string{
-			"error": "Invalid token claims",
-		})
+		RespondError(w, r, http.StatusUnauthorized, "invalid_token_claims")
 	})
 }
+
+// setAuthenticationHeaders forwards when and how the user authenticated so
+// downstream services can demand recent authentication, the user's active
+// organization so they can scope data to it, whether the user is a guest
+// so they can hold back features, and the user's preferred locale and time
+// zone so they can localize what they send. Values supplied by the client
+// are always replaced.
+func setAuthenticationHeaders(r *http.Request, claims jwt.MapClaims) {
+	r.Header.Del("X-Auth-Time")
+	r.Header.Del("X-Auth-Methods")
+	r.Header.Del("X-Org-ID")
+	r.Header.Del("X-Guest")
+	r.Header.Del("X-User-Locale")
+	r.Header.Del("X-User-Time-Zone")
+
+	if orgID, ok := claims["org_id"].(string); ok && orgID != "" {
+		r.Header.Set("X-Org-ID", orgID)
+	}
+
+	if guest, ok := claims["guest"].(bool); ok && guest {
+		r.Header.Set("X-Guest", "true")
+	}
+
+	if locale, ok := claims["locale"].(string); ok && locale != "" {
+		r.Header.Set("X-User-Locale", locale)
+	}
+
+	if tz, ok := claims["tz"].(string); ok && tz != "" {
+		r.Header.Set("X-User-Time-Zone", tz)
+	}
+
+	if authTime, ok := claims["auth_time"].(float64); ok {
+		r.Header.Set("X-Auth-Time", strconv.FormatInt(int64(authTime), 10))
+	}
+
+	if amr, ok := claims["amr"].([]interface{}); ok {
+		methods := make([]string, 0, len(amr))
+		for _, m := range amr {
+			if method, ok := m.(string); ok {
+				methods = append(methods, method)
+			}
+		}
+		r.Header.Set("X-Auth-Methods", strings.Join(methods, ","))
+	}
+}
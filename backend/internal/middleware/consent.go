@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"backend/internal/problem"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ConsentGate turns away users who haven't accepted the latest mandatory
+// version of a legal document. It compares the "consents" claim of the
+// access token with the required versions, which it polls from the auth
+// service. Until the versions could be fetched once, every request passes.
+type ConsentGate struct {
+	versionsURL string
+	client      *http.Client
+
+	mu       sync.RWMutex
+	required map[string]int
+}
+
+func NewConsentGate(authServiceURL string, refreshInterval time.Duration) *ConsentGate {
+	g := &ConsentGate{
+		versionsURL: authServiceURL + "/api/v1/legal/versions",
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if err := g.refresh(); err != nil {
+		log.Printf("⚠️  Failed to load required legal document versions: %v", err)
+	}
+	go g.watch(refreshInterval)
+
+	return g
+}
+
+// Require must be wrapped in RequireAuth, which provides the token claims.
+func (g *ConsentGate) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := r.Context().Value(ClaimsKey).(jwt.MapClaims)
+
+		if missing := g.missing(claims); len(missing) > 0 {
+			problem.New(requestLanguage(r), http.StatusForbidden, "consent_required").
+				With("documents", missing).
+				Write(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// missing returns the kinds of documents whose required version is newer
+// than the one claims say the user accepted.
+func (g *ConsentGate) missing(claims jwt.MapClaims) []string {
+	accepted, _ := claims["consents"].(map[string]interface{})
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var missing []string
+	for kind, version := range g.required {
+		if v, _ := accepted[kind].(float64); int(v) < version {
+			missing = append(missing, kind)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+func (g *ConsentGate) watch(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+
+		// Keep the versions we have, e.g. while the auth service restarts
+		if err := g.refresh(); err != nil {
+			log.Printf("Refreshing required legal document versions failed: %v", err)
+		}
+	}
+}
+
+func (g *ConsentGate) refresh() error {
+	resp, err := g.client.Get(g.versionsURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth service responded %s", resp.Status)
+	}
+
+	var versions struct {
+		Required map[string]int `json:"required"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	g.required = versions.Required
+	g.mu.Unlock()
+
+	return nil
+}
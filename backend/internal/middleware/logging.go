@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"backend/internal/geoip"
+	"backend/internal/problem"
+)
+
+type AccessLogger struct {
+	geo *geoip.Resolver
+}
+
+func NewAccessLogger(geo *geoip.Resolver) *AccessLogger {
+	return &AccessLogger{geo: geo}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Log writes one line per request with the client's IP enriched by GeoIP.
+func (l *AccessLogger) Log(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		ip := getIP(r)
+		loc := l.geo.Lookup(ip)
+		log.Printf("%s %s %d %s ip=%s country=%s city=%q asn=%d request_id=%s",
+			r.Method,
+			r.URL.Path,
+			rec.status,
+			time.Since(start).Round(time.Millisecond),
+			ip,
+			loc.Country,
+			loc.City,
+			loc.ASN,
+			r.Header.Get(problem.RequestIDHeader),
+		)
+	})
+}
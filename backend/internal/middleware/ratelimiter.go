@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -46,9 +47,7 @@ func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 		rl.mu.Unlock()
 
 		if !v.limiter.Allow() {
-			respondJSON(w, http.StatusTooManyRequests, map[string]string{
-				"error": "Rate limit exceeded. Please try again later.",
-			})
+			RespondError(w, r, http.StatusTooManyRequests, "rate_limited")
 			return
 		}
 
@@ -70,19 +69,12 @@ func (rl *RateLimiter) cleanupVisitors() {
 	}
 }
 
+// getIP returns the address the request came from. The gateway faces
+// clients directly, so X-Forwarded-For and X-Real-IP are whatever the
+// client made them up to be and are ignored.
 func getIP(r *http.Request) string {
-	// Try X-Forwarded-For header first (for proxies)
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		return forwarded
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
 	}
-
-	// Try X-Real-IP header
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
-		return realIP
-	}
-
-	// Fall back to RemoteAddr
 	return r.RemoteAddr
 }
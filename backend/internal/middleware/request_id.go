@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"backend/internal/problem"
+)
+
+// validRequestID matches the request IDs kept when a client or proxy in
+// front of the gateway already set one.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID gives every request an ID in X-Request-ID, which is passed on
+// to the services, returned to the client and included in problems and the
+// access log, so a failed request can be traced.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(problem.RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+			r.Header.Set(problem.RequestIDHeader, id)
+		}
+		w.Header().Set(problem.RequestIDHeader, id)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
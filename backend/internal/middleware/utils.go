@@ -1,12 +1,25 @@
 package middleware
 
 import (
-	"encoding/json"
 	"net/http"
+
+	"backend/internal/i18n"
+	"backend/internal/problem"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
-func respondJSON(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(data)
+// RespondError writes the problem with code in the language of r.
+func RespondError(w http.ResponseWriter, r *http.Request, statusCode int, code string) {
+	problem.New(requestLanguage(r), statusCode, code).Write(w, r)
+}
+
+// requestLanguage returns the language to respond to r in: the user's
+// preferred locale once RequireAuth checked their token, otherwise the
+// best match for Accept-Language.
+func requestLanguage(r *http.Request) string {
+	claims, _ := r.Context().Value(ClaimsKey).(jwt.MapClaims)
+	preferred, _ := claims["locale"].(string)
+
+	return i18n.Negotiate(preferred, r.Header.Get("Accept-Language"))
 }
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+// Audited actions, reported in AuditEvent.Action.
+const (
+	AuditUserRegistered       = "user.registered"
+	AuditGuestCreated         = "user.guest_created"
+	AuditGuestUpgraded        = "user.guest_upgraded"
+	AuditLoginSucceeded       = "auth.login_succeeded"
+	AuditLoginFailed          = "auth.login_failed"
+	AuditTokenRefreshed       = "auth.token_refreshed"
+	AuditOrgSwitched          = "auth.org_switched"
+	AuditProfileUpdated       = "user.profile_updated"
+	AuditPreferencesUpdated   = "user.preferences_updated"
+	AuditPasswordChanged      = "user.password_changed"
+	AuditEmailChanged         = "user.email_changed"
+	AuditIdentityLinked       = "user.identity_linked"
+	AuditIdentityUnlinked     = "user.identity_unlinked"
+	AuditDeletionScheduled    = "user.deletion_scheduled"
+	AuditUserDeleted          = "user.deleted"
+	AuditUserRestored         = "user.restored"
+	AuditConsentAccepted      = "user.consent_accepted"
+	AuditLegalPublished       = "legal.document_published"
+	AuditTranslationUploaded  = "translation.bundle_uploaded"
+	AuditTranslationPublished = "translation.bundle_published"
+	AuditTranslationRejected  = "translation.bundle_rejected"
+	AuditEventsPurged         = "audit.events_purged"
+)
+
+// AuditActorSystem is the ActorID of events caused by background jobs
+// rather than a request.
+const AuditActorSystem = "system"
+
+// AuditEvent records a security-relevant change. Events form a hash
+// chain: Hash covers the event and the Hash of the event before it, so
+// altering or removing an event breaks the chain from there on. The
+// retention job removes the oldest events and records the hash of the last
+// one it removed in an AuditEventsPurged event, which anchors what's left.
+type AuditEvent struct {
+	Seq    int64     `json:"seq"`
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+
+	// ActorID is who made the change: the user, an administrator, or
+	// AuditActorSystem. UserID is the account it was made to.
+	ActorID string `json:"actor_id"`
+	UserID  string `json:"user_id"`
+
+	// Country, City and ASN locate IP, when a GeoIP database knows it.
+	IP        string            `json:"ip"`
+	Country   string            `json:"country,omitempty"`
+	City      string            `json:"city,omitempty"`
+	ASN       uint              `json:"asn,omitempty"`
+	UserAgent string            `json:"user_agent"`
+	Details   map[string]string `json:"details,omitempty"`
+
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
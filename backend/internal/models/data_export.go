@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// Data export states. An export is queued as pending, built by a worker
+// while processing, and downloadable while ready. Once it expires its
+// archive is deleted.
+const (
+	DataExportPending    = "pending"
+	DataExportProcessing = "processing"
+	DataExportReady      = "ready"
+	DataExportFailed     = "failed"
+	DataExportExpired    = "expired"
+)
+
+// DataExport is a user's request for a copy of their personal data. The
+// archive is a ZIP file of JSON documents kept on local disk.
+type DataExport struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	Status      string     `json:"status"`
+	FilePath    string     `json:"-"`
+	Size        int64      `json:"size"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// Login methods an account can have linked, reported in Identity.Method.
+// An email address allows signing in with a magic link, and together with
+// a password also with the password.
+const (
+	IdentityEmail    = "email"
+	IdentityPassword = "password"
+	IdentityPhone    = "phone"
+)
+
+// Identity is a login method linked to a user account. Identifier is the
+// email address or phone number, and empty for the password.
+type Identity struct {
+	Method     string `json:"method"`
+	Identifier string `json:"identifier,omitempty"`
+}
+
+// EmailVerification is a code sent to an email address to prove the user
+// controls it before it is linked to their account.
+type EmailVerification struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Email     string     `json:"email"`
+	CodeHash  string     `json:"-"`
+	Attempts  int        `json:"attempts"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
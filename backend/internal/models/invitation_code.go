@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// InvitationCode lets someone register while registration is invite-only.
+// A code can be limited to one email address and is spent after MaxUses
+// registrations. Only its hash is stored.
+type InvitationCode struct {
+	ID        string     `json:"id"`
+	CodeHash  string     `json:"-"`
+	Email     string     `json:"email,omitempty"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	CreatedBy string     `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
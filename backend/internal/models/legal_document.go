@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Kinds of legal documents users consent to.
+const (
+	LegalTerms   = "terms"
+	LegalPrivacy = "privacy"
+)
+
+// LegalKinds lists every kind of legal document.
+var LegalKinds = []string{LegalTerms, LegalPrivacy}
+
+// LegalDocument is one translation of a version of the terms of service or
+// the privacy policy. Versions count up per kind and are published with all
+// their translations at once. Publishing a mandatory version requires every
+// user to accept it before they can go on using the API; other versions,
+// e.g. wording fixes, don't.
+type LegalDocument struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	Version     int       `json:"version"`
+	Locale      string    `json:"locale"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	Mandatory   bool      `json:"mandatory"`
+	PublishedBy string    `json:"-"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Consent records that a user accepted a version of a legal document, in
+// which language and from where.
+type Consent struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Kind       string    `json:"kind"`
+	Version    int       `json:"version"`
+	Locale     string    `json:"locale"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Login methods recorded in LoginEvent.Method.
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodRegister  = "register"
+	LoginMethodMagicLink = "magic_link"
+	LoginMethodPhoneOTP  = "phone_otp"
+	LoginMethodRefresh   = "refresh"
+)
+
+// Outcomes recorded in LoginEvent.Outcome.
+const (
+	LoginOutcomeSuccess            = "success"
+	LoginOutcomeInvalidCredentials = "invalid_credentials"
+	LoginOutcomePendingDeletion    = "pending_deletion"
+)
+
+// LoginEvent records a single login or token refresh for a user.
+type LoginEvent struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Method    string    `json:"method"`
+	Outcome   string    `json:"outcome"`
+	IP        string    `json:"ip"`
+	Network   string    `json:"-"`
+	UserAgent string    `json:"user_agent"`
+	Country   string    `json:"country,omitempty"`
+	City      string    `json:"city,omitempty"`
+	ASN       uint      `json:"asn,omitempty"`
+	NewDevice bool      `json:"new_device"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// MagicLink is a passwordless login challenge. The link token and the
+// 6-digit code are two ways to redeem the same row, so using either one
+// invalidates both.
+type MagicLink struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Email     string     `json:"email"`
+	TokenHash string     `json:"-"`
+	CodeHash  string     `json:"-"`
+	DeviceID  string     `json:"device_id"`
+	Attempts  int        `json:"attempts"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
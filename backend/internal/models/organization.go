@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Roles a user can have within an organization.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Role of the user the organization was loaded for, if any
+	Role string `json:"role,omitempty"`
+}
+
+// Membership gives a user a role in an organization. Name and Email are
+// filled in from the user when members are listed.
+type Membership struct {
+	OrgID     string    `json:"org_id"`
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	Name      string    `json:"name,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrgInvitation invites an email address to join an organization. The
+// invitee accepts or declines it with the token sent by email, or by ID
+// once signed in with that address.
+type OrgInvitation struct {
+	ID         string     `json:"id"`
+	OrgID      string     `json:"org_id"`
+	OrgName    string     `json:"org_name,omitempty"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	TokenHash  string     `json:"-"`
+	InvitedBy  string     `json:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	DeclinedAt *time.Time `json:"declined_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// PhoneOTP is a one-time code sent by SMS to sign up or log in with a phone
+// number.
+type PhoneOTP struct {
+	ID        string     `json:"id"`
+	Phone     string     `json:"phone"`
+	CodeHash  string     `json:"-"`
+	Attempts  int        `json:"attempts"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
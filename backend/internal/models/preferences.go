@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// Themes the app can be shown in.
+const (
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+// Themes lists every theme.
+var Themes = []string{ThemeSystem, ThemeLight, ThemeDark}
+
+// Preferences are a user's settings that follow them across devices.
+// Users who never saved any get DefaultPreferences.
+type Preferences struct {
+	UserID string `json:"-"`
+
+	// Locale is one of the languages the app ships in, e.g. "fa"
+	Locale string `json:"locale"`
+	Theme  string `json:"theme"`
+
+	// TimeZone is an IANA time zone name, e.g. "Asia/Tehran"
+	TimeZone string `json:"time_zone"`
+
+	Notifications NotificationPreferences `json:"notifications"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotificationPreferences are the messages a user opted in to. Messages
+// about the security of the account are always sent.
+type NotificationPreferences struct {
+	Email     bool `json:"email"`
+	Push      bool `json:"push"`
+	Marketing bool `json:"marketing"`
+}
+
+// DefaultPreferences returns the preferences of a user who hasn't saved
+// any.
+func DefaultPreferences(userID string) *Preferences {
+	return &Preferences{
+		UserID:   userID,
+		Locale:   "en",
+		Theme:    ThemeSystem,
+		TimeZone: "UTC",
+		Notifications: NotificationPreferences{
+			Email: true,
+			Push:  true,
+		},
+	}
+}
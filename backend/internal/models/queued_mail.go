@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Queued email states. An email is pending until the mail worker claims it
+// and is sending while it does. A failed send goes back to pending with a
+// later next attempt, or is dead once it fails permanently or runs out of
+// attempts; dead emails stay until an administrator retries them.
+const (
+	MailPending = "pending"
+	MailSending = "sending"
+	MailSent    = "sent"
+	MailDead    = "dead"
+)
+
+// QueuedMail is an email waiting in, or delivered from, the outbound mail
+// queue.
+type QueuedMail struct {
+	ID      string `json:"id"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"-"`
+	HTML    string `json:"-"`
+
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// RegistrationChallenge is a proof-of-work puzzle a client must solve before
+// it may register. A solution is a nonce such that
+// SHA-256(ID + ":" + nonce) starts with Difficulty zero bits.
+type RegistrationChallenge struct {
+	ID         string     `json:"challenge"`
+	Difficulty int        `json:"difficulty"`
+	IP         string     `json:"-"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	UsedAt     *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"-"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Translation bundle states. An uploaded bundle is pending until an
+// administrator reviews it and either publishes or rejects it.
+const (
+	TranslationPending   = "pending"
+	TranslationPublished = "published"
+	TranslationRejected  = "rejected"
+)
+
+// TranslationBundle is a version of the app's messages in one language,
+// uploaded as an ARB file. Versions count up per locale; the app is served
+// the latest published one. The English bundle is the template the others
+// are checked against and fall back to.
+type TranslationBundle struct {
+	ID       string            `json:"id"`
+	Locale   string            `json:"locale"`
+	Version  int               `json:"version"`
+	Status   string            `json:"status"`
+	Messages map[string]string `json:"messages,omitempty"`
+
+	UploadedBy string    `json:"uploaded_by"`
+	UploadedAt time.Time `json:"uploaded_at"`
+
+	// Set once the bundle was reviewed
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote string     `json:"review_note,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// TrustedDevice is a device the user chose to remember after a successful
+// login. The device proves itself by presenting a token whose hash is
+// stored here.
+type TrustedDevice struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"-"`
+	TokenHash  string    `json:"-"`
+	Name       string    `json:"name"`
+	UserAgent  string    `json:"user_agent"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -4,19 +4,43 @@ import (
 	"time"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+
+	// RoleGuest marks an anonymous account that hasn't been upgraded to a
+	// full one yet. Guests have no email address, phone or password.
+	RoleGuest = "guest"
+)
+
 type User struct {
 	ID           string    `json:"id"`
 	Email        string    `json:"email"`
+	Phone        string    `json:"phone,omitempty"`
 	PasswordHash string    `json:"-"`
 	Name         string    `json:"name"`
+	Role         string    `json:"role"`
+	RiskScore    int       `json:"risk_score"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	// EmailVerifiedAt is set once the user has proven they receive mail at
+	// Email, by signing in with a magic link or linking the address with a
+	// code.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+
+	// DeletedAt is set while the account waits out the deletion grace
+	// period.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type RefreshToken struct {
 	ID        string    `json:"id"`
 	UserID    string    `json:"user_id"`
 	Token     string    `json:"token"`
+	AuthTime  time.Time `json:"auth_time"`
+	AMR       []string  `json:"amr"`
+	OrgID     string    `json:"org_id,omitempty"`
 	ExpiresAt time.Time `json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
 }
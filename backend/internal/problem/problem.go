@@ -0,0 +1,116 @@
+// Package problem writes error responses as problem details (RFC 9457),
+// the one error format of the gateway and the services.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend/internal/i18n"
+)
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// RequestIDHeader carries the ID the gateway gives every request.
+const RequestIDHeader = "X-Request-ID"
+
+// TypeBase followed by a problem's code is its type URI.
+const TypeBase = "/problems/"
+
+// Problem describes why a request failed. Clients act on Code, which is
+// stable, and show Title and the field errors' details, which are in the
+// language of the response.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+
+	// Detail explains this occurrence of the problem, if there is more to
+	// say than Title
+	Detail string `json:"detail,omitempty"`
+
+	// Instance is the path of the request that failed
+	Instance string `json:"instance,omitempty"`
+
+	Code string `json:"code"`
+
+	// Errors lists the fields of the request that are invalid
+	Errors []FieldError `json:"errors,omitempty"`
+
+	RequestID string `json:"request_id,omitempty"`
+
+	// Extensions are further members of the problem type, such as the
+	// documents a user has to accept; they must not reuse the names above
+	Extensions map[string]interface{} `json:"-"`
+
+	locale string
+}
+
+// FieldError says why one field of a request is invalid.
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// New returns the problem with code, titled in locale; args fill in the
+// title's placeholders.
+func New(locale string, status int, code string, args ...interface{}) *Problem {
+	return &Problem{
+		Type:   TypeBase + code,
+		Title:  i18n.Message(locale, code, args...),
+		Status: status,
+		Code:   code,
+		locale: locale,
+	}
+}
+
+// WithField adds an error with code for field, described in the language
+// of p.
+func (p *Problem) WithField(field, code string, args ...interface{}) *Problem {
+	p.Errors = append(p.Errors, FieldError{
+		Field:  field,
+		Code:   code,
+		Detail: i18n.Message(p.locale, code, args...),
+	})
+	return p
+}
+
+// With adds the extension member name.
+func (p *Problem) With(name string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[name] = value
+	return p
+}
+
+// Write sends p as the response to r.
+func (p *Problem) Write(w http.ResponseWriter, r *http.Request) {
+	p.Instance = r.URL.Path
+	p.RequestID = r.Header.Get(RequestIDHeader)
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Language", p.locale)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// MarshalJSON adds the extension members to the standard ones.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	data, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	extensions, err := json.Marshal(p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Join {"type":...} and {"documents":...} into one object
+	data = append(data[:len(data)-1], ',')
+	return append(data, extensions[1:]...), nil
+}
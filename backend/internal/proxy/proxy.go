@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
+
+	"backend/internal/middleware"
 )
 
 type ServiceProxy struct {
@@ -29,7 +32,7 @@ func (sp *ServiceProxy) proxyRequest(w http.ResponseWriter, r *http.Request, tar
 	target, err := url.Parse(targetURL)
 	if err != nil {
 		log.Printf("Error parsing target URL: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		middleware.RespondError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
 
@@ -45,7 +48,7 @@ func (sp *ServiceProxy) proxyRequest(w http.ResponseWriter, r *http.Request, tar
 		bodyBytes, err = io.ReadAll(r.Body)
 		if err != nil {
 			log.Printf("Error reading request body: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			middleware.RespondError(w, r, http.StatusInternalServerError, "internal_error")
 			return
 		}
 		r.Body.Close()
@@ -55,7 +58,7 @@ func (sp *ServiceProxy) proxyRequest(w http.ResponseWriter, r *http.Request, tar
 	proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, proxyURL, bytes.NewReader(bodyBytes))
 	if err != nil {
 		log.Printf("Error creating proxy request: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		middleware.RespondError(w, r, http.StatusInternalServerError, "internal_error")
 		return
 	}
 
@@ -66,21 +69,28 @@ func (sp *ServiceProxy) proxyRequest(w http.ResponseWriter, r *http.Request, tar
 		}
 	}
 
+	// Tell the service which address the request came from. Whatever the
+	// client sent in these headers is dropped, as anyone can set them.
+	proxyReq.Header.Del("X-Forwarded-For")
+	proxyReq.Header.Del("X-Real-IP")
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		proxyReq.Header.Set("X-Forwarded-For", host)
+	}
+
 	// Make request to target service
 	client := &http.Client{}
 	resp, err := client.Do(proxyReq)
 	if err != nil {
 		log.Printf("Error forwarding request: %v", err)
-		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		middleware.RespondError(w, r, http.StatusServiceUnavailable, "service_unavailable")
 		return
 	}
 	defer resp.Body.Close()
 
-	// Copy response headers
+	// Copy response headers, replacing those the gateway set, such as
+	// X-Request-ID, so they are not sent twice
 	for key, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(key, value)
-		}
+		w.Header()[key] = values
 	}
 
 	// Copy status code
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+// auditLockKey identifies the advisory lock that serializes appends to the
+// audit log, so every event links to the one committed before it.
+const auditLockKey = 0x61756469
+
+const auditEventColumns = `seq, id, occurred_at, action, actor_id, user_id, ip, country, city, asn, user_agent, details, prev_hash, hash`
+
+// AuditFilter narrows down a query of the audit log. Empty fields match
+// everything.
+type AuditFilter struct {
+	UserID  string
+	ActorID string
+	Action  string
+	From    time.Time
+	To      time.Time
+}
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Tail locks the audit log for appending until tx ends and returns the
+// sequence number and hash of the last event, or 0 and "" for an empty log.
+func (r *AuditRepository) Tail(tx *sql.Tx) (int64, string, error) {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, auditLockKey); err != nil {
+		return 0, "", err
+	}
+
+	var seq int64
+	var hash string
+	err := tx.QueryRow(`SELECT seq, hash FROM audit_events ORDER BY seq DESC LIMIT 1`).Scan(&seq, &hash)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	return seq, hash, nil
+}
+
+// Append stores event within tx, which must hold the lock taken by Tail.
+func (r *AuditRepository) Append(tx *sql.Tx, event *models.AuditEvent) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_events (` + auditEventColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err = tx.Exec(query,
+		event.Seq,
+		event.ID,
+		event.Time,
+		event.Action,
+		event.ActorID,
+		event.UserID,
+		event.IP,
+		event.Country,
+		event.City,
+		event.ASN,
+		event.UserAgent,
+		details,
+		event.PrevHash,
+		event.Hash,
+	)
+	return err
+}
+
+// List returns a page of the events matching filter, newest first, and the
+// total number of matching events.
+func (r *AuditRepository) List(filter AuditFilter, limit, offset int) ([]*models.AuditEvent, int, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if filter.UserID != "" {
+		where("user_id = ?", filter.UserID)
+	}
+	if filter.ActorID != "" {
+		where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		where("action = ?", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		where("occurred_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		where("occurred_at < ?", filter.To.UTC())
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM audit_events `+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + auditEventColumns + ` FROM audit_events ` + whereClause +
+		` ORDER BY seq DESC LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+
+	events, err := r.query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// ListAfter returns up to limit events following seq, in order.
+func (r *AuditRepository) ListAfter(seq int64, limit int) ([]*models.AuditEvent, error) {
+	query := `SELECT ` + auditEventColumns + ` FROM audit_events WHERE seq > $1 ORDER BY seq LIMIT $2`
+	return r.query(query, seq, limit)
+}
+
+// PurgedThrough returns the hash of event seq, as recorded when the
+// retention job deleted it, or "" if it wasn't.
+func (r *AuditRepository) PurgedThrough(seq int64) (string, error) {
+	query := `
+		SELECT details->>'through_hash'
+		FROM audit_events
+		WHERE action = $1 AND details->>'through_seq' = $2
+		ORDER BY seq DESC
+		LIMIT 1
+	`
+
+	var hash string
+	err := r.db.QueryRow(query, models.AuditEventsPurged, strconv.FormatInt(seq, 10)).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return hash, err
+}
+
+func (r *AuditRepository) query(query string, args ...any) ([]*models.AuditEvent, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*models.AuditEvent, 0)
+	for rows.Next() {
+		event := &models.AuditEvent{}
+		var details []byte
+		if err := rows.Scan(
+			&event.Seq,
+			&event.ID,
+			&event.Time,
+			&event.Action,
+			&event.ActorID,
+			&event.UserID,
+			&event.IP,
+			&event.Country,
+			&event.City,
+			&event.ASN,
+			&event.UserAgent,
+			&details,
+			&event.PrevHash,
+			&event.Hash,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(details, &event.Details); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/internal/fieldcrypt"
+	"backend/internal/models"
+)
+
+var ErrDataExportNotFound = errors.New("data export not found")
+
+// Values personalData queries can look rows up by.
+const (
+	byUserID = iota
+	byEmail
+	byPhone
+)
+
+// personalData lists what goes into a data export: one JSON document per
+// entry, each a list of the rows the query returns for the user's ID,
+// email address or phone number in $1, as set by by. Columns in encrypted
+// are decrypted, keyed by the field their values are bound to. Secrets
+// such as password, token and code hashes are left out. A new table that
+// holds per-user data must be added here.
+var personalData = []struct {
+	name      string
+	by        int
+	query     string
+	encrypted map[string]string
+}{
+	{name: "profile", query: `
+		SELECT id, email, phone, name, role, created_at, updated_at, email_verified_at, deleted_at
+		FROM users WHERE id = $1`,
+		encrypted: map[string]string{"email": fieldEmail, "phone": fieldPhone, "name": fieldName}},
+	{name: "login_methods", query: `
+		SELECT method,
+			CASE WHEN method = 'email' THEN identifier END AS email,
+			CASE WHEN method = 'phone' THEN identifier END AS phone,
+			created_at AS linked_at
+		FROM user_identities WHERE user_id = $1 ORDER BY created_at, method`,
+		encrypted: map[string]string{"email": fieldEmail, "phone": fieldPhone}},
+	{name: "preferences", query: `
+		SELECT locale, theme, time_zone, notify_email, notify_push, notify_marketing, updated_at
+		FROM user_preferences WHERE user_id = $1`},
+	{name: "sessions", query: `
+		SELECT id, auth_time, amr, org_id, expires_at, created_at
+		FROM refresh_tokens WHERE user_id = $1 ORDER BY created_at`},
+	{name: "usage", query: `
+		SELECT feature, count, updated_at
+		FROM user_usage WHERE user_id = $1 ORDER BY feature`},
+	{name: "login_history", query: `
+		SELECT id, method, outcome, ip, user_agent, country, city, asn, new_device, created_at
+		FROM login_events WHERE user_id = $1 ORDER BY created_at`},
+	{name: "trusted_devices", query: `
+		SELECT id, name, user_agent, expires_at, last_used_at, created_at
+		FROM trusted_devices WHERE user_id = $1 ORDER BY created_at`},
+	{name: "organizations", query: `
+		SELECT o.id, o.name, m.role, m.last_active_at, m.created_at AS joined_at
+		FROM organization_members m JOIN organizations o ON o.id = m.org_id
+		WHERE m.user_id = $1 ORDER BY m.created_at`},
+	{name: "organization_invitations_received", by: byEmail, query: `
+		SELECT id, org_id, email, role, invited_by, expires_at, accepted_at, declined_at, created_at
+		FROM organization_invitations WHERE LOWER(email) = LOWER($1) ORDER BY created_at`},
+	{name: "organization_invitations_sent", query: `
+		SELECT id, org_id, email, role, expires_at, accepted_at, declined_at, created_at
+		FROM organization_invitations WHERE invited_by = $1 ORDER BY created_at`},
+	{name: "invitation_codes_created", query: `
+		SELECT id, email, max_uses, uses, expires_at, created_at
+		FROM invitation_codes WHERE created_by = $1 ORDER BY created_at`},
+	{name: "magic_links", query: `
+		SELECT id, email, device_id, attempts, expires_at, used_at, created_at
+		FROM magic_links WHERE user_id = $1 ORDER BY created_at`},
+	{name: "phone_codes", by: byPhone, query: `
+		SELECT id, phone, attempts, expires_at, used_at, created_at
+		FROM phone_otps WHERE phone = $1 ORDER BY created_at`},
+	{name: "email_verifications", query: `
+		SELECT id, email, attempts, expires_at, used_at, created_at
+		FROM email_verifications WHERE user_id = $1 ORDER BY created_at`},
+	{name: "email_collisions", query: `
+		SELECT email, canonical_email, detected_at
+		FROM email_collisions WHERE user_id = $1`},
+	{name: "consents", query: `
+		SELECT kind, version, locale, ip, user_agent, accepted_at
+		FROM consents WHERE user_id = $1 ORDER BY accepted_at`},
+	{name: "audit_events", query: `
+		SELECT id, occurred_at, action, actor_id, ip, country, city, asn, user_agent, details
+		FROM audit_events WHERE user_id = $1 ORDER BY seq`},
+	{name: "data_exports", query: `
+		SELECT id, status, size, expires_at, completed_at, created_at
+		FROM data_exports WHERE user_id = $1 ORDER BY created_at`},
+}
+
+// PersonalDataSet is one document of a data export, as a JSON array.
+type PersonalDataSet struct {
+	Name string
+	JSON []byte
+}
+
+type DataExportRepository struct {
+	db   *sql.DB
+	ring *fieldcrypt.KeyRing
+}
+
+// NewDataExportRepository creates the repository; ring decrypts the
+// personal data that is stored encrypted.
+func NewDataExportRepository(db *sql.DB, ring *fieldcrypt.KeyRing) *DataExportRepository {
+	return &DataExportRepository{db: db, ring: ring}
+}
+
+const dataExportColumns = `id, user_id, status, file_path, size, expires_at, completed_at, created_at`
+
+func (r *DataExportRepository) Create(export *models.DataExport) error {
+	query := `
+		INSERT INTO data_exports (id, user_id, status, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := r.db.Exec(query, export.ID, export.UserID, export.Status, export.CreatedAt)
+	return err
+}
+
+func (r *DataExportRepository) GetByID(id string) (*models.DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE id = $1`
+
+	export, err := scanDataExport(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDataExportNotFound
+	}
+	return export, err
+}
+
+// ListByUserID returns the exports of userID, newest first.
+func (r *DataExportRepository) ListByUserID(userID string) ([]*models.DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []*models.DataExport{}
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+// CountSince returns how many exports userID requested after since.
+func (r *DataExportRepository) CountSince(userID string, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM data_exports WHERE user_id = $1 AND created_at > $2`
+
+	var count int
+	if err := r.db.QueryRow(query, userID, since).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ClaimPending marks the oldest pending export as processing and returns
+// it, or nil if none is waiting. Concurrent workers never claim the same
+// export.
+func (r *DataExportRepository) ClaimPending() (*models.DataExport, error) {
+	query := `
+		UPDATE data_exports SET status = $1
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = $2
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + dataExportColumns
+
+	export, err := scanDataExport(r.db.QueryRow(query, models.DataExportProcessing, models.DataExportPending))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return export, err
+}
+
+// RequeueProcessing puts exports a stopped worker left half-built back in
+// the queue.
+func (r *DataExportRepository) RequeueProcessing() error {
+	query := `UPDATE data_exports SET status = $1 WHERE status = $2`
+	_, err := r.db.Exec(query, models.DataExportPending, models.DataExportProcessing)
+	return err
+}
+
+func (r *DataExportRepository) MarkReady(id, filePath string, size int64, expiresAt time.Time) error {
+	query := `
+		UPDATE data_exports
+		SET status = $1, file_path = $2, size = $3, expires_at = $4, completed_at = $5
+		WHERE id = $6
+	`
+	_, err := r.db.Exec(query, models.DataExportReady, filePath, size, expiresAt, time.Now(), id)
+	return err
+}
+
+func (r *DataExportRepository) MarkFailed(id string) error {
+	query := `UPDATE data_exports SET status = $1, completed_at = $2 WHERE id = $3`
+	_, err := r.db.Exec(query, models.DataExportFailed, time.Now(), id)
+	return err
+}
+
+// ListExpired returns the ready exports whose archives expired before now.
+func (r *DataExportRepository) ListExpired(now time.Time) ([]*models.DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE status = $1 AND expires_at <= $2`
+
+	rows, err := r.db.Query(query, models.DataExportReady, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []*models.DataExport
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+func (r *DataExportRepository) MarkExpired(id string) error {
+	query := `UPDATE data_exports SET status = $1, file_path = '' WHERE id = $2`
+	_, err := r.db.Exec(query, models.DataExportExpired, id)
+	return err
+}
+
+// PersonalData collects everything stored about userID, one data set per
+// entry in personalData.
+func (r *DataExportRepository) PersonalData(userID string) ([]PersonalDataSet, error) {
+	var email, phone sql.NullString
+	err := r.db.QueryRow(`SELECT email, phone FROM users WHERE id = $1`, userID).Scan(&email, &phone)
+	if err != nil {
+		return nil, fmt.Errorf("collecting profile: %w", err)
+	}
+
+	keys := map[int]string{byUserID: userID}
+	if keys[byEmail], err = r.ring.Decrypt(fieldEmail, email.String); err != nil {
+		return nil, err
+	}
+	if keys[byPhone], err = r.ring.Decrypt(fieldPhone, phone.String); err != nil {
+		return nil, err
+	}
+
+	sets := make([]PersonalDataSet, 0, len(personalData))
+	for _, source := range personalData {
+		data := []byte("[]")
+		if key := keys[source.by]; key != "" {
+			query := `SELECT COALESCE(json_agg(t), '[]') FROM (` + source.query + `) t`
+			if err := r.db.QueryRow(query, key).Scan(&data); err != nil {
+				return nil, fmt.Errorf("collecting %s: %w", source.name, err)
+			}
+		}
+
+		if len(source.encrypted) > 0 {
+			if data, err = r.decryptRows(data, source.encrypted); err != nil {
+				return nil, fmt.Errorf("collecting %s: %w", source.name, err)
+			}
+		}
+		sets = append(sets, PersonalDataSet{Name: source.name, JSON: data})
+	}
+
+	return sets, nil
+}
+
+// decryptRows decrypts the columns in encrypted of every row in data, a
+// JSON array of objects.
+func (r *DataExportRepository) decryptRows(data []byte, encrypted map[string]string) ([]byte, error) {
+	var rows []map[string]any
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		for column, field := range encrypted {
+			value, ok := row[column].(string)
+			if !ok {
+				continue
+			}
+			plaintext, err := r.ring.Decrypt(field, value)
+			if err != nil {
+				return nil, err
+			}
+			row[column] = plaintext
+		}
+	}
+
+	return json.Marshal(rows)
+}
+
+func scanDataExport(row rowScanner) (*models.DataExport, error) {
+	export := &models.DataExport{}
+	var expiresAt, completedAt sql.NullTime
+
+	err := row.Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&export.FilePath,
+		&export.Size,
+		&expiresAt,
+		&completedAt,
+		&export.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+
+	return export, nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"backend/internal/models"
+)
+
+type EmailVerificationRepository struct {
+	db *sql.DB
+}
+
+func NewEmailVerificationRepository(db *sql.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+func (r *EmailVerificationRepository) Create(verification *models.EmailVerification) error {
+	query := `
+		INSERT INTO email_verifications (id, user_id, email, code_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(query,
+		verification.ID,
+		verification.UserID,
+		verification.Email,
+		verification.CodeHash,
+		verification.Attempts,
+		verification.ExpiresAt,
+		verification.CreatedAt,
+	)
+	return err
+}
+
+// CountSince returns how many codes userID requested after since.
+func (r *EmailVerificationRepository) CountSince(userID string, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM email_verifications WHERE user_id = $1 AND created_at > $2`
+
+	var count int
+	if err := r.db.QueryRow(query, userID, since).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetActive returns the most recent unused, unexpired code userID requested
+// for email, or nil if there is none.
+func (r *EmailVerificationRepository) GetActive(userID, email string) (*models.EmailVerification, error) {
+	verification := &models.EmailVerification{}
+	var usedAt sql.NullTime
+
+	query := `
+		SELECT id, user_id, email, code_hash, attempts, expires_at, used_at, created_at
+		FROM email_verifications
+		WHERE user_id = $1 AND email = $2 AND used_at IS NULL AND expires_at > $3
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	err := r.db.QueryRow(query, userID, email, time.Now()).Scan(
+		&verification.ID,
+		&verification.UserID,
+		&verification.Email,
+		&verification.CodeHash,
+		&verification.Attempts,
+		&verification.ExpiresAt,
+		&usedAt,
+		&verification.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		verification.UsedAt = &usedAt.Time
+	}
+
+	return verification, nil
+}
+
+func (r *EmailVerificationRepository) IncrementAttempts(id string) (int, error) {
+	query := `UPDATE email_verifications SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`
+
+	var attempts int
+	if err := r.db.QueryRow(query, id).Scan(&attempts); err != nil {
+		return 0, err
+	}
+	return attempts, nil
+}
+
+// MarkUsed consumes the code. It reports false if the code had already been
+// used.
+func (r *EmailVerificationRepository) MarkUsed(id string) (bool, error) {
+	query := `UPDATE email_verifications SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+
+	result, err := r.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// InvalidateByUserID consumes every outstanding code of userID.
+func (r *EmailVerificationRepository) InvalidateByUserID(userID string) error {
+	query := `UPDATE email_verifications SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL`
+	_, err := r.db.Exec(query, time.Now(), userID)
+	return err
+}
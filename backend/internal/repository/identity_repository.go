@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"backend/internal/fieldcrypt"
+	"backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// IdentityRepository stores the login methods linked to each account, one
+// row per method with the email address or phone number it signs in with.
+// Sign-in reads the users columns, which are changed in the same
+// transaction.
+type IdentityRepository struct {
+	db   dbtx
+	ring *fieldcrypt.KeyRing
+}
+
+func NewIdentityRepository(db *sql.DB, ring *fieldcrypt.KeyRing) *IdentityRepository {
+	return &IdentityRepository{db: db, ring: ring}
+}
+
+// WithTx returns a copy of the repository that works within tx.
+func (r *IdentityRepository) WithTx(tx *sql.Tx) *IdentityRepository {
+	return &IdentityRepository{db: tx, ring: r.ring}
+}
+
+// List returns the login methods linked to userID, oldest first.
+func (r *IdentityRepository) List(userID string) ([]models.Identity, error) {
+	query := `
+		SELECT method, identifier
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at, method
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.Identity{}
+	for rows.Next() {
+		var identity models.Identity
+		if err := rows.Scan(&identity.Method, &identity.Identifier); err != nil {
+			return nil, err
+		}
+		if identity.Identifier, err = r.ring.Decrypt(identityField(identity.Method), identity.Identifier); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+// Link links method to userID with identifier, empty for a password,
+// replacing the identifier it was linked with before. It fails with
+// ErrEmailAlreadyExists or ErrPhoneAlreadyExists if another account has
+// the email address or phone number.
+func (r *IdentityRepository) Link(userID, method, identifier string) error {
+	field := identityField(method)
+	encrypted, err := r.ring.Encrypt(field, identifier)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO user_identities (user_id, method, identifier, identifier_index, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, method) DO UPDATE
+		SET identifier = EXCLUDED.identifier, identifier_index = EXCLUDED.identifier_index
+	`
+
+	_, err = r.db.Exec(query, userID, method, encrypted, r.index(field, identifier), time.Now())
+	if err != nil && isDuplicate(err, "user_identities_method_identifier_index_key") {
+		if method == models.IdentityPhone {
+			return ErrPhoneAlreadyExists
+		}
+		return ErrEmailAlreadyExists
+	}
+	return err
+}
+
+// Unlink removes methods from userID. It fails with ErrLastIdentity unless
+// the account keeps a method to sign in with on its own, which a password
+// isn't.
+func (r *IdentityRepository) Unlink(userID string, methods ...string) error {
+	query := `
+		DELETE FROM user_identities
+		WHERE user_id = $1 AND method = ANY($2)
+			AND EXISTS (
+				SELECT 1 FROM user_identities
+				WHERE user_id = $1 AND method <> ALL($2) AND method <> $3
+			)
+	`
+
+	result, err := r.db.Exec(query, userID, pq.Array(methods), models.IdentityPassword)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrLastIdentity
+	}
+
+	return nil
+}
+
+// ReencryptBatch re-encrypts up to limit identifiers that aren't encrypted
+// with the active key yet and fills in missing blind indexes, like
+// UserRepository.ReencryptBatch.
+func (r *IdentityRepository) ReencryptBatch(limit int) (int, error) {
+	if !r.ring.Enabled() {
+		return 0, nil
+	}
+
+	query := `
+		SELECT user_id, method, identifier
+		FROM user_identities
+		WHERE identifier <> '' AND (identifier NOT LIKE $1 OR identifier_index IS NULL)
+		ORDER BY user_id, method
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, r.ring.ActivePrefix()+"%", limit)
+	if err != nil {
+		return 0, err
+	}
+
+	type stored struct {
+		userID     string
+		method     string
+		identifier string
+	}
+	var batch []stored
+	for rows.Next() {
+		var row stored
+		if err := rows.Scan(&row.userID, &row.method, &row.identifier); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, row := range batch {
+		field := identityField(row.method)
+		plaintext, err := r.ring.Decrypt(field, row.identifier)
+		if err != nil {
+			return 0, err
+		}
+		encrypted, err := r.ring.Encrypt(field, plaintext)
+		if err != nil {
+			return 0, err
+		}
+
+		query := `
+			UPDATE user_identities
+			SET identifier = $1, identifier_index = $2
+			WHERE user_id = $3 AND method = $4 AND identifier = $5
+		`
+		_, err = r.db.Exec(query, encrypted, r.index(field, plaintext), row.userID, row.method, row.identifier)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(batch), nil
+}
+
+// index returns the blind index of value of field, or NULL when there is
+// none.
+func (r *IdentityRepository) index(field, value string) sql.NullString {
+	index := r.ring.Index(field, value)
+	return sql.NullString{String: index, Valid: index != ""}
+}
+
+// identityField returns the field the identifiers of method are encrypted
+// and indexed as. Email addresses and phone numbers are bound to the users
+// columns they mirror, so both copies read alike.
+func identityField(method string) string {
+	switch method {
+	case models.IdentityEmail:
+		return fieldEmail
+	case models.IdentityPhone:
+		return fieldPhone
+	default:
+		return "user_identities." + method
+	}
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+)
+
+var ErrInvitationNotFound = errors.New("invitation code not found")
+
+type InvitationCodeRepository struct {
+	db *sql.DB
+}
+
+func NewInvitationCodeRepository(db *sql.DB) *InvitationCodeRepository {
+	return &InvitationCodeRepository{db: db}
+}
+
+func (r *InvitationCodeRepository) Create(invitation *models.InvitationCode) error {
+	query := `
+		INSERT INTO invitation_codes (id, code_hash, email, max_uses, uses, created_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(query,
+		invitation.ID,
+		invitation.CodeHash,
+		invitation.Email,
+		invitation.MaxUses,
+		invitation.Uses,
+		invitation.CreatedBy,
+		invitation.ExpiresAt,
+		invitation.CreatedAt,
+	)
+	return err
+}
+
+// Redeem uses up one registration of the code with the given hash and
+// returns its ID, or "" if the code doesn't exist, has expired, is spent or
+// is limited to an address other than email.
+func (r *InvitationCodeRepository) Redeem(codeHash, email string) (string, error) {
+	query := `
+		UPDATE invitation_codes
+		SET uses = uses + 1
+		WHERE code_hash = $1
+		  AND uses < max_uses
+		  AND (expires_at IS NULL OR expires_at > $2)
+		  AND (email = '' OR LOWER(email) = LOWER($3))
+		RETURNING id
+	`
+
+	var id string
+	err := r.db.QueryRow(query, codeHash, time.Now(), email).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Release gives back a use taken by Redeem when the registration it was
+// for didn't complete.
+func (r *InvitationCodeRepository) Release(id string) error {
+	query := `UPDATE invitation_codes SET uses = uses - 1 WHERE id = $1 AND uses > 0`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+func (r *InvitationCodeRepository) List() ([]*models.InvitationCode, error) {
+	query := `
+		SELECT id, code_hash, email, max_uses, uses, created_by, expires_at, created_at
+		FROM invitation_codes
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []*models.InvitationCode
+	for rows.Next() {
+		invitation := &models.InvitationCode{}
+		var expiresAt sql.NullTime
+		if err := rows.Scan(
+			&invitation.ID,
+			&invitation.CodeHash,
+			&invitation.Email,
+			&invitation.MaxUses,
+			&invitation.Uses,
+			&invitation.CreatedBy,
+			&expiresAt,
+			&invitation.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			invitation.ExpiresAt = &expiresAt.Time
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+func (r *InvitationCodeRepository) Delete(id string) error {
+	query := `DELETE FROM invitation_codes WHERE id = $1`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+
+	return nil
+}
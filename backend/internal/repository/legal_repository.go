@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"database/sql"
+
+	"backend/internal/models"
+)
+
+type LegalRepository struct {
+	db dbtx
+}
+
+func NewLegalRepository(db *sql.DB) *LegalRepository {
+	return &LegalRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that works within tx.
+func (r *LegalRepository) WithTx(tx *sql.Tx) *LegalRepository {
+	return &LegalRepository{db: tx}
+}
+
+const legalDocumentColumns = `id, kind, version, locale, title, body, mandatory, published_by, published_at`
+
+// NextVersion returns the version number the next document of kind gets.
+func (r *LegalRepository) NextVersion(kind string) (int, error) {
+	query := `SELECT COALESCE(MAX(version), 0) + 1 FROM legal_documents WHERE kind = $1`
+
+	var version int
+	if err := r.db.QueryRow(query, kind).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (r *LegalRepository) CreateDocument(doc *models.LegalDocument) error {
+	query := `
+		INSERT INTO legal_documents (` + legalDocumentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.Exec(query,
+		doc.ID,
+		doc.Kind,
+		doc.Version,
+		doc.Locale,
+		doc.Title,
+		doc.Body,
+		doc.Mandatory,
+		doc.PublishedBy,
+		doc.PublishedAt,
+	)
+	return err
+}
+
+// Current returns every translation of the latest version of kind, or none
+// if nothing was published yet.
+func (r *LegalRepository) Current(kind string) ([]*models.LegalDocument, error) {
+	query := `
+		SELECT ` + legalDocumentColumns + `
+		FROM legal_documents
+		WHERE kind = $1 AND version = (SELECT MAX(version) FROM legal_documents WHERE kind = $1)
+		ORDER BY locale
+	`
+
+	rows, err := r.db.Query(query, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []*models.LegalDocument
+	for rows.Next() {
+		doc, err := scanLegalDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, rows.Err()
+}
+
+// CurrentVersions returns the latest version of each kind of document.
+// Kinds nothing was published for are missing.
+func (r *LegalRepository) CurrentVersions() (map[string]int, error) {
+	return r.versions(`SELECT kind, MAX(version) FROM legal_documents GROUP BY kind`)
+}
+
+// RequiredVersions returns the latest mandatory version of each kind of
+// document, the oldest version a user may have accepted to go on using
+// the API.
+func (r *LegalRepository) RequiredVersions() (map[string]int, error) {
+	return r.versions(`SELECT kind, MAX(version) FROM legal_documents WHERE mandatory GROUP BY kind`)
+}
+
+func (r *LegalRepository) CreateConsent(consent *models.Consent) error {
+	query := `
+		INSERT INTO consents (id, user_id, kind, version, locale, ip, user_agent, accepted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(query,
+		consent.ID,
+		consent.UserID,
+		consent.Kind,
+		consent.Version,
+		consent.Locale,
+		consent.IP,
+		consent.UserAgent,
+		consent.AcceptedAt,
+	)
+	return err
+}
+
+// AcceptedVersions returns the latest version of each kind of document
+// userID accepted.
+func (r *LegalRepository) AcceptedVersions(userID string) (map[string]int, error) {
+	return r.versions(`SELECT kind, MAX(version) FROM consents WHERE user_id = $1 GROUP BY kind`, userID)
+}
+
+// ListConsents returns every consent userID gave, newest first.
+func (r *LegalRepository) ListConsents(userID string) ([]*models.Consent, error) {
+	query := `
+		SELECT id, user_id, kind, version, locale, ip, user_agent, accepted_at
+		FROM consents
+		WHERE user_id = $1
+		ORDER BY accepted_at DESC, kind
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []*models.Consent{}
+	for rows.Next() {
+		consent := &models.Consent{}
+		err := rows.Scan(
+			&consent.ID,
+			&consent.UserID,
+			&consent.Kind,
+			&consent.Version,
+			&consent.Locale,
+			&consent.IP,
+			&consent.UserAgent,
+			&consent.AcceptedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		consents = append(consents, consent)
+	}
+
+	return consents, rows.Err()
+}
+
+// versions runs a query returning (kind, version) rows into a map.
+func (r *LegalRepository) versions(query string, args ...any) (map[string]int, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[string]int{}
+	for rows.Next() {
+		var kind string
+		var version int
+		if err := rows.Scan(&kind, &version); err != nil {
+			return nil, err
+		}
+		versions[kind] = version
+	}
+
+	return versions, rows.Err()
+}
+
+func scanLegalDocument(row rowScanner) (*models.LegalDocument, error) {
+	doc := &models.LegalDocument{}
+
+	err := row.Scan(
+		&doc.ID,
+		&doc.Kind,
+		&doc.Version,
+		&doc.Locale,
+		&doc.Title,
+		&doc.Body,
+		&doc.Mandatory,
+		&doc.PublishedBy,
+		&doc.PublishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
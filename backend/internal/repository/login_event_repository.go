@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"backend/internal/models"
+)
+
+type LoginEventRepository struct {
+	db *sql.DB
+}
+
+func NewLoginEventRepository(db *sql.DB) *LoginEventRepository {
+	return &LoginEventRepository{db: db}
+}
+
+func (r *LoginEventRepository) Create(event *models.LoginEvent) error {
+	query := `
+		INSERT INTO login_events (id, user_id, method, outcome, ip, network, user_agent, country, city, asn, new_device, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	_, err := r.db.Exec(query,
+		event.ID,
+		event.UserID,
+		event.Method,
+		event.Outcome,
+		event.IP,
+		event.Network,
+		event.UserAgent,
+		event.Country,
+		event.City,
+		event.ASN,
+		event.NewDevice,
+		event.CreatedAt,
+	)
+	return err
+}
+
+// Seen reports whether userID has any successful logins on record, and
+// whether any of them came from userAgent or from network.
+func (r *LoginEventRepository) Seen(userID, userAgent, network string) (bool, bool, bool, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE user_agent = $2),
+			COUNT(*) FILTER (WHERE network = $3)
+		FROM login_events
+		WHERE user_id = $1 AND outcome = 'success'
+	`
+
+	var total, userAgents, networks int
+	if err := r.db.QueryRow(query, userID, userAgent, network).Scan(&total, &userAgents, &networks); err != nil {
+		return false, false, false, err
+	}
+
+	return total > 0, userAgents > 0, networks > 0, nil
+}
+
+// CountByIPSince returns how many events with method came from ip after
+// since.
+func (r *LoginEventRepository) CountByIPSince(method, ip string, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM login_events WHERE method = $1 AND ip = $2 AND created_at > $3`
+
+	var count int
+	if err := r.db.QueryRow(query, method, ip, since).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountByNetworkSince returns how many events with method came from network
+// after since.
+func (r *LoginEventRepository) CountByNetworkSince(method, network string, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM login_events WHERE method = $1 AND network = $2 AND created_at > $3`
+
+	var count int
+	if err := r.db.QueryRow(query, method, network, since).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListByUserID returns a page of userID's events, newest first, and the
+// total number of events.
+func (r *LoginEventRepository) ListByUserID(userID string, limit, offset int) ([]*models.LoginEvent, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM login_events WHERE user_id = $1`
+	if err := r.db.QueryRow(countQuery, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, user_id, method, outcome, ip, network, user_agent, country, city, asn, new_device, created_at
+		FROM login_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := make([]*models.LoginEvent, 0)
+	for rows.Next() {
+		event := &models.LoginEvent{}
+		if err := rows.Scan(
+			&event.ID,
+			&event.UserID,
+			&event.Method,
+			&event.Outcome,
+			&event.IP,
+			&event.Network,
+			&event.UserAgent,
+			&event.Country,
+			&event.City,
+			&event.ASN,
+			&event.NewDevice,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"backend/internal/models"
+)
+
+type MagicLinkRepository struct {
+	db *sql.DB
+}
+
+func NewMagicLinkRepository(db *sql.DB) *MagicLinkRepository {
+	return &MagicLinkRepository{db: db}
+}
+
+func (r *MagicLinkRepository) Create(link *models.MagicLink) error {
+	query := `
+		INSERT INTO magic_links (id, user_id, email, token_hash, code_hash, device_id, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.Exec(query,
+		link.ID,
+		link.UserID,
+		link.Email,
+		link.TokenHash,
+		link.CodeHash,
+		link.DeviceID,
+		link.Attempts,
+		link.ExpiresAt,
+		link.CreatedAt,
+	)
+	return err
+}
+
+// CountSince returns how many links were issued for email after since.
+func (r *MagicLinkRepository) CountSince(email string, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM magic_links WHERE email = $1 AND created_at > $2`
+
+	var count int
+	if err := r.db.QueryRow(query, email, since).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetActiveByTokenHash returns the unused, unexpired link with the given
+// token hash, or nil if there is none.
+func (r *MagicLinkRepository) GetActiveByTokenHash(tokenHash string) (*models.MagicLink, error) {
+	query := `
+		SELECT id, user_id, email, token_hash, code_hash, device_id, attempts, expires_at, used_at, created_at
+		FROM magic_links
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+	`
+	return r.scanOne(r.db.QueryRow(query, tokenHash, time.Now()))
+}
+
+// GetActiveByEmail returns the most recent unused, unexpired link for email,
+// or nil if there is none.
+func (r *MagicLinkRepository) GetActiveByEmail(email string) (*models.MagicLink, error) {
+	query := `
+		SELECT id, user_id, email, token_hash, code_hash, device_id, attempts, expires_at, used_at, created_at
+		FROM magic_links
+		WHERE email = $1 AND used_at IS NULL AND expires_at > $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	return r.scanOne(r.db.QueryRow(query, email, time.Now()))
+}
+
+func (r *MagicLinkRepository) IncrementAttempts(id string) (int, error) {
+	query := `UPDATE magic_links SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`
+
+	var attempts int
+	if err := r.db.QueryRow(query, id).Scan(&attempts); err != nil {
+		return 0, err
+	}
+	return attempts, nil
+}
+
+// MarkUsed consumes the link. It reports false if the link had already been
+// used, so two concurrent redemptions cannot both succeed.
+func (r *MagicLinkRepository) MarkUsed(id string) (bool, error) {
+	query := `UPDATE magic_links SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+
+	result, err := r.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// InvalidateByEmail consumes every outstanding link for email.
+func (r *MagicLinkRepository) InvalidateByEmail(email string) error {
+	query := `UPDATE magic_links SET used_at = $1 WHERE email = $2 AND used_at IS NULL`
+	_, err := r.db.Exec(query, time.Now(), email)
+	return err
+}
+
+func (r *MagicLinkRepository) scanOne(row *sql.Row) (*models.MagicLink, error) {
+	link := &models.MagicLink{}
+	var usedAt sql.NullTime
+
+	err := row.Scan(
+		&link.ID,
+		&link.UserID,
+		&link.Email,
+		&link.TokenHash,
+		&link.CodeHash,
+		&link.DeviceID,
+		&link.Attempts,
+		&link.ExpiresAt,
+		&usedAt,
+		&link.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		link.UsedAt = &usedAt.Time
+	}
+
+	return link, nil
+}
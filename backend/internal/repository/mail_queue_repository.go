@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/internal/fieldcrypt"
+	"backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// fieldMailMessage is the field queued messages are encrypted as.
+const fieldMailMessage = "mail_queue.message"
+
+// mailMessage is what the message column holds.
+type mailMessage struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// ErrMailUnreadable is returned, wrapped, with an email whose message can't
+// be decrypted or decoded; only its ID and delivery state are set.
+var ErrMailUnreadable = errors.New("queued email can't be read")
+
+type MailQueueRepository struct {
+	db   *sql.DB
+	ring *fieldcrypt.KeyRing
+}
+
+// NewMailQueueRepository creates the repository; ring encrypts the queued
+// messages.
+func NewMailQueueRepository(db *sql.DB, ring *fieldcrypt.KeyRing) *MailQueueRepository {
+	return &MailQueueRepository{db: db, ring: ring}
+}
+
+const mailQueueColumns = `id, message, status, attempts, next_attempt_at, last_error, created_at, sent_at`
+
+func (r *MailQueueRepository) Enqueue(mail *models.QueuedMail) error {
+	message, err := json.Marshal(mailMessage{
+		To:      mail.To,
+		Subject: mail.Subject,
+		Text:    mail.Text,
+		HTML:    mail.HTML,
+	})
+	if err != nil {
+		return err
+	}
+	encrypted, err := r.ring.Encrypt(fieldMailMessage, string(message))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO mail_queue (id, message, status, attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err = r.db.Exec(query,
+		mail.ID,
+		encrypted,
+		mail.Status,
+		mail.Attempts,
+		mail.NextAttemptAt,
+		mail.CreatedAt,
+	)
+	return err
+}
+
+// ClaimDue marks the pending email whose next attempt is due the longest as
+// sending, claimed at now, counts the attempt and returns it, or nil if none
+// is due. Several workers can claim concurrently. An email that can't be
+// read is returned along with an ErrMailUnreadable error.
+func (r *MailQueueRepository) ClaimDue(now time.Time) (*models.QueuedMail, error) {
+	query := `
+		UPDATE mail_queue SET status = $1, attempts = attempts + 1, claimed_at = $3
+		WHERE id = (
+			SELECT id FROM mail_queue
+			WHERE status = $2 AND next_attempt_at <= $3
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + mailQueueColumns
+
+	mail, err := r.scanMail(r.db.QueryRow(query, models.MailSending, models.MailPending, now))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return mail, err
+}
+
+// RequeueSending puts emails claimed before claimedBefore that are still
+// sending back in the queue, as the worker that claimed them has stopped.
+// The server may have accepted some of them already, so they can arrive
+// twice. It returns how many it requeued.
+func (r *MailQueueRepository) RequeueSending(claimedBefore time.Time) (int64, error) {
+	query := `
+		UPDATE mail_queue SET status = $1
+		WHERE status = $2 AND (claimed_at IS NULL OR claimed_at < $3)
+	`
+
+	result, err := r.db.Exec(query, models.MailPending, models.MailSending, claimedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *MailQueueRepository) MarkSent(id string) error {
+	query := `UPDATE mail_queue SET status = $1, last_error = '', sent_at = $2 WHERE id = $3`
+	_, err := r.db.Exec(query, models.MailSent, time.Now(), id)
+	return err
+}
+
+// Reschedule puts email id back in the queue after a failed attempt, to be
+// tried again at next.
+func (r *MailQueueRepository) Reschedule(id string, next time.Time, lastError string) error {
+	query := `UPDATE mail_queue SET status = $1, next_attempt_at = $2, last_error = $3 WHERE id = $4`
+	_, err := r.db.Exec(query, models.MailPending, next, lastError, id)
+	return err
+}
+
+// MarkDead gives up on email id.
+func (r *MailQueueRepository) MarkDead(id, lastError string) error {
+	query := `UPDATE mail_queue SET status = $1, last_error = $2 WHERE id = $3`
+	_, err := r.db.Exec(query, models.MailDead, lastError, id)
+	return err
+}
+
+// ListDead returns up to limit dead emails, newest first.
+func (r *MailQueueRepository) ListDead(limit int) ([]*models.QueuedMail, error) {
+	query := `
+		SELECT ` + mailQueueColumns + `
+		FROM mail_queue
+		WHERE status = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, models.MailDead, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mails := []*models.QueuedMail{}
+	for rows.Next() {
+		// An unreadable email is listed too, so it can be found by its ID
+		mail, err := r.scanMail(rows)
+		if err != nil && !errors.Is(err, ErrMailUnreadable) {
+			return nil, err
+		}
+		mails = append(mails, mail)
+	}
+
+	return mails, rows.Err()
+}
+
+// RequeueDead puts the dead emails with ids, or all of them if ids is
+// empty, back in the queue with fresh attempts, and returns how many it
+// requeued.
+func (r *MailQueueRepository) RequeueDead(ids []string) (int64, error) {
+	query := `
+		UPDATE mail_queue SET status = $1, attempts = 0, next_attempt_at = $2
+		WHERE status = $3 AND (cardinality($4::varchar[]) = 0 OR id = ANY($4))
+	`
+
+	result, err := r.db.Exec(query, models.MailPending, time.Now(), models.MailDead, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ReencryptBatch re-encrypts up to limit queued emails that aren't
+// encrypted with the active key yet, including ones stored before
+// encryption was turned on. Sent emails are left as they are, as they are
+// never read again. Emails under keys no longer in the ring can't be read
+// and are skipped. It returns how many emails it went through; fewer than
+// limit means it caught up. Emails changed in the meantime are skipped.
+func (r *MailQueueRepository) ReencryptBatch(limit int) (int, error) {
+	if !r.ring.Enabled() {
+		return 0, nil
+	}
+
+	query := `
+		SELECT id, message
+		FROM mail_queue
+		WHERE status <> $1 AND message NOT LIKE $2
+			AND (message NOT LIKE $3 OR message LIKE ANY($4))
+		ORDER BY id
+		LIMIT $5
+	`
+
+	var prefixes []string
+	for _, prefix := range r.ring.Prefixes() {
+		prefixes = append(prefixes, prefix+"%")
+	}
+	rows, err := r.db.Query(query,
+		models.MailSent,
+		r.ring.ActivePrefix()+"%",
+		fieldcrypt.Prefix+"%",
+		pq.Array(prefixes),
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	type stored struct {
+		id      string
+		message string
+	}
+	var batch []stored
+	for rows.Next() {
+		var row stored
+		if err := rows.Scan(&row.id, &row.message); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, row := range batch {
+		plaintext, err := r.ring.Decrypt(fieldMailMessage, row.message)
+		if err != nil {
+			return 0, err
+		}
+		encrypted, err := r.ring.Encrypt(fieldMailMessage, plaintext)
+		if err != nil {
+			return 0, err
+		}
+
+		query := `UPDATE mail_queue SET message = $1 WHERE id = $2 AND message = $3`
+		if _, err := r.db.Exec(query, encrypted, row.id, row.message); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(batch), nil
+}
+
+func (r *MailQueueRepository) scanMail(row rowScanner) (*models.QueuedMail, error) {
+	mail := &models.QueuedMail{}
+	var encrypted string
+	var sentAt sql.NullTime
+
+	err := row.Scan(
+		&mail.ID,
+		&encrypted,
+		&mail.Status,
+		&mail.Attempts,
+		&mail.NextAttemptAt,
+		&mail.LastError,
+		&mail.CreatedAt,
+		&sentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if sentAt.Valid {
+		mail.SentAt = &sentAt.Time
+	}
+
+	plaintext, err := r.ring.Decrypt(fieldMailMessage, encrypted)
+	if err != nil {
+		return mail, fmt.Errorf("%w: %v", ErrMailUnreadable, err)
+	}
+	var message mailMessage
+	if err := json.Unmarshal([]byte(plaintext), &message); err != nil {
+		return mail, fmt.Errorf("%w: %v", ErrMailUnreadable, err)
+	}
+	mail.To = message.To
+	mail.Subject = message.Subject
+	mail.Text = message.Text
+	mail.HTML = message.HTML
+
+	return mail, nil
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+)
+
+var ErrOrgInvitationNotFound = errors.New("organization invitation not found")
+
+// orgInvitationColumns selects an invitation together with the name of its
+// organization; queries join organizations as o.
+const orgInvitationColumns = `i.id, i.org_id, o.name, i.email, i.role, i.token_hash, i.invited_by,
+	i.expires_at, i.accepted_at, i.declined_at, i.created_at`
+
+// pendingInvitation restricts a query to invitations that can still be
+// answered; $1 is the current time.
+const pendingInvitation = `i.accepted_at IS NULL AND i.declined_at IS NULL AND i.expires_at > $1`
+
+type OrgInvitationRepository struct {
+	db *sql.DB
+}
+
+func NewOrgInvitationRepository(db *sql.DB) *OrgInvitationRepository {
+	return &OrgInvitationRepository{db: db}
+}
+
+func (r *OrgInvitationRepository) Create(invitation *models.OrgInvitation) error {
+	query := `
+		INSERT INTO organization_invitations (id, org_id, email, role, token_hash, invited_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(query,
+		invitation.ID,
+		invitation.OrgID,
+		invitation.Email,
+		invitation.Role,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+		invitation.CreatedAt,
+	)
+	return err
+}
+
+// GetPendingByTokenHash returns the pending invitation with the given token
+// hash, or nil if there is none.
+func (r *OrgInvitationRepository) GetPendingByTokenHash(tokenHash string) (*models.OrgInvitation, error) {
+	query := `
+		SELECT ` + orgInvitationColumns + `
+		FROM organization_invitations i
+		JOIN organizations o ON o.id = i.org_id
+		WHERE i.token_hash = $2 AND ` + pendingInvitation
+
+	return scanOrgInvitation(r.db.QueryRow(query, time.Now(), tokenHash))
+}
+
+// GetPendingByID returns the pending invitation with the given ID, or nil
+// if there is none.
+func (r *OrgInvitationRepository) GetPendingByID(id string) (*models.OrgInvitation, error) {
+	query := `
+		SELECT ` + orgInvitationColumns + `
+		FROM organization_invitations i
+		JOIN organizations o ON o.id = i.org_id
+		WHERE i.id = $2 AND ` + pendingInvitation
+
+	return scanOrgInvitation(r.db.QueryRow(query, time.Now(), id))
+}
+
+// ListPendingByEmail returns the invitations waiting for an answer from
+// email, newest first.
+func (r *OrgInvitationRepository) ListPendingByEmail(email string) ([]*models.OrgInvitation, error) {
+	query := `
+		SELECT ` + orgInvitationColumns + `
+		FROM organization_invitations i
+		JOIN organizations o ON o.id = i.org_id
+		WHERE LOWER(i.email) = LOWER($2) AND ` + pendingInvitation + `
+		ORDER BY i.created_at DESC
+	`
+
+	return r.list(query, time.Now(), email)
+}
+
+// ListPendingByOrgID returns the invitations of orgID that haven't been
+// answered yet, newest first.
+func (r *OrgInvitationRepository) ListPendingByOrgID(orgID string) ([]*models.OrgInvitation, error) {
+	query := `
+		SELECT ` + orgInvitationColumns + `
+		FROM organization_invitations i
+		JOIN organizations o ON o.id = i.org_id
+		WHERE i.org_id = $2 AND ` + pendingInvitation + `
+		ORDER BY i.created_at DESC
+	`
+
+	return r.list(query, time.Now(), orgID)
+}
+
+// MarkAccepted answers a pending invitation. It returns false if the
+// invitation was answered, revoked or expired in the meantime.
+func (r *OrgInvitationRepository) MarkAccepted(id string) (bool, error) {
+	return r.answer(`accepted_at`, id)
+}
+
+// MarkDeclined is MarkAccepted for declining.
+func (r *OrgInvitationRepository) MarkDeclined(id string) (bool, error) {
+	return r.answer(`declined_at`, id)
+}
+
+func (r *OrgInvitationRepository) answer(column, id string) (bool, error) {
+	query := `
+		UPDATE organization_invitations i
+		SET ` + column + ` = $1
+		WHERE i.id = $2 AND ` + pendingInvitation
+
+	result, err := r.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// DeletePending revokes the pending invitations of orgID for email, so a
+// new invitation replaces them.
+func (r *OrgInvitationRepository) DeletePending(orgID, email string) error {
+	query := `
+		DELETE FROM organization_invitations
+		WHERE org_id = $1 AND LOWER(email) = LOWER($2) AND accepted_at IS NULL AND declined_at IS NULL
+	`
+	_, err := r.db.Exec(query, orgID, email)
+	return err
+}
+
+// Delete revokes invitation id of orgID if it is still pending.
+func (r *OrgInvitationRepository) Delete(orgID, id string) error {
+	query := `
+		DELETE FROM organization_invitations
+		WHERE id = $1 AND org_id = $2 AND accepted_at IS NULL AND declined_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, id, orgID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrOrgInvitationNotFound
+	}
+
+	return nil
+}
+
+func (r *OrgInvitationRepository) list(query string, args ...any) ([]*models.OrgInvitation, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := make([]*models.OrgInvitation, 0)
+	for rows.Next() {
+		invitation, err := scanOrgInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+func scanOrgInvitation(row rowScanner) (*models.OrgInvitation, error) {
+	invitation := &models.OrgInvitation{}
+	var acceptedAt, declinedAt sql.NullTime
+
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.OrgID,
+		&invitation.OrgName,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.TokenHash,
+		&invitation.InvitedBy,
+		&invitation.ExpiresAt,
+		&acceptedAt,
+		&declinedAt,
+		&invitation.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if acceptedAt.Valid {
+		invitation.AcceptedAt = &acceptedAt.Time
+	}
+	if declinedAt.Valid {
+		invitation.DeclinedAt = &declinedAt.Time
+	}
+
+	return invitation, nil
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"backend/internal/fieldcrypt"
+	"backend/internal/models"
+)
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrMembershipNotFound   = errors.New("membership not found")
+	ErrAlreadyMember        = errors.New("user is already a member")
+)
+
+type OrganizationRepository struct {
+	db   *sql.DB
+	ring *fieldcrypt.KeyRing
+}
+
+// NewOrganizationRepository creates the repository; ring decrypts the
+// names and email addresses of members.
+func NewOrganizationRepository(db *sql.DB, ring *fieldcrypt.KeyRing) *OrganizationRepository {
+	return &OrganizationRepository{db: db, ring: ring}
+}
+
+// Create stores org and makes ownerID its owner.
+func (r *OrganizationRepository) Create(org *models.Organization, ownerID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO organizations (id, name, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.Exec(query, org.ID, org.Name, org.CreatedBy, org.CreatedAt, org.UpdatedAt); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO organization_members (org_id, user_id, role, last_active_at, created_at)
+		VALUES ($1, $2, $3, $4, $4)
+	`
+	if _, err := tx.Exec(query, org.ID, ownerID, models.OrgRoleOwner, org.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *OrganizationRepository) GetByID(id string) (*models.Organization, error) {
+	org := &models.Organization{}
+
+	query := `SELECT id, name, created_by, created_at, updated_at FROM organizations WHERE id = $1`
+
+	err := r.db.QueryRow(query, id).Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// ListByUserID returns the organizations userID belongs to with their role
+// in each, most recently used first.
+func (r *OrganizationRepository) ListByUserID(userID string) ([]*models.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.created_by, o.created_at, o.updated_at, m.role
+		FROM organizations o
+		JOIN organization_members m ON m.org_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY m.last_active_at DESC NULLS LAST, m.created_at
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := make([]*models.Organization, 0)
+	for rows.Next() {
+		org := &models.Organization{}
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt, &org.Role); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+
+	return orgs, rows.Err()
+}
+
+// GetMembership returns userID's membership of orgID, or nil if there is
+// none.
+func (r *OrganizationRepository) GetMembership(orgID, userID string) (*models.Membership, error) {
+	membership := &models.Membership{}
+
+	query := `
+		SELECT org_id, user_id, role, created_at
+		FROM organization_members
+		WHERE org_id = $1 AND user_id = $2
+	`
+
+	err := r.db.QueryRow(query, orgID, userID).Scan(
+		&membership.OrgID,
+		&membership.UserID,
+		&membership.Role,
+		&membership.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+// DefaultOrgID returns the organization userID used most recently, or ""
+// if the user doesn't belong to any.
+func (r *OrganizationRepository) DefaultOrgID(userID string) (string, error) {
+	query := `
+		SELECT org_id
+		FROM organization_members
+		WHERE user_id = $1
+		ORDER BY last_active_at DESC NULLS LAST, created_at
+		LIMIT 1
+	`
+
+	var orgID string
+	err := r.db.QueryRow(query, userID).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return orgID, err
+}
+
+// Touch records that userID switched to orgID, making it their default.
+func (r *OrganizationRepository) Touch(orgID, userID string) error {
+	query := `UPDATE organization_members SET last_active_at = $1 WHERE org_id = $2 AND user_id = $3`
+	_, err := r.db.Exec(query, time.Now(), orgID, userID)
+	return err
+}
+
+func (r *OrganizationRepository) AddMember(orgID, userID, role string) error {
+	query := `
+		INSERT INTO organization_members (org_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := r.db.Exec(query, orgID, userID, role, time.Now())
+	if err != nil {
+		if err.Error() == "pq: duplicate key value violates unique constraint \"organization_members_pkey\"" {
+			return ErrAlreadyMember
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ListMembers returns the members of orgID with their names and email
+// addresses, owners first.
+func (r *OrganizationRepository) ListMembers(orgID string) ([]*models.Membership, error) {
+	query := `
+		SELECT m.org_id, m.user_id, m.role, u.name, u.email, m.created_at
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1
+		ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, m.created_at
+	`
+
+	rows, err := r.db.Query(query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]*models.Membership, 0)
+	for rows.Next() {
+		member := &models.Membership{}
+		var email sql.NullString
+		if err := rows.Scan(
+			&member.OrgID,
+			&member.UserID,
+			&member.Role,
+			&member.Name,
+			&email,
+			&member.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if member.Name, err = r.ring.Decrypt(fieldName, member.Name); err != nil {
+			return nil, err
+		}
+		if member.Email, err = r.ring.Decrypt(fieldEmail, email.String); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func (r *OrganizationRepository) UpdateMemberRole(orgID, userID, role string) error {
+	query := `UPDATE organization_members SET role = $1 WHERE org_id = $2 AND user_id = $3`
+
+	result, err := r.db.Exec(query, role, orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrMembershipNotFound
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepository) RemoveMember(orgID, userID string) error {
+	query := `DELETE FROM organization_members WHERE org_id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(query, orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrMembershipNotFound
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepository) CountOwners(orgID string) (int, error) {
+	query := `SELECT COUNT(*) FROM organization_members WHERE org_id = $1 AND role = $2`
+
+	var count int
+	err := r.db.QueryRow(query, orgID, models.OrgRoleOwner).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"backend/internal/models"
+)
+
+type PhoneOTPRepository struct {
+	db *sql.DB
+}
+
+func NewPhoneOTPRepository(db *sql.DB) *PhoneOTPRepository {
+	return &PhoneOTPRepository{db: db}
+}
+
+func (r *PhoneOTPRepository) Create(otp *models.PhoneOTP) error {
+	query := `
+		INSERT INTO phone_otps (id, phone, code_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.Exec(query, otp.ID, otp.Phone, otp.CodeHash, otp.Attempts, otp.ExpiresAt, otp.CreatedAt)
+	return err
+}
+
+// CountSince returns how many codes were sent to phone after since.
+func (r *PhoneOTPRepository) CountSince(phone string, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM phone_otps WHERE phone = $1 AND created_at > $2`
+
+	var count int
+	if err := r.db.QueryRow(query, phone, since).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// LastSentAt returns when the most recent code was sent to phone, or the
+// zero time if none was.
+func (r *PhoneOTPRepository) LastSentAt(phone string) (time.Time, error) {
+	query := `SELECT MAX(created_at) FROM phone_otps WHERE phone = $1`
+
+	var sentAt sql.NullTime
+	if err := r.db.QueryRow(query, phone).Scan(&sentAt); err != nil {
+		return time.Time{}, err
+	}
+	return sentAt.Time, nil
+}
+
+// GetActive returns the most recent unused, unexpired code for phone, or nil
+// if there is none.
+func (r *PhoneOTPRepository) GetActive(phone string) (*models.PhoneOTP, error) {
+	otp := &models.PhoneOTP{}
+	var usedAt sql.NullTime
+
+	query := `
+		SELECT id, phone, code_hash, attempts, expires_at, used_at, created_at
+		FROM phone_otps
+		WHERE phone = $1 AND used_at IS NULL AND expires_at > $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	err := r.db.QueryRow(query, phone, time.Now()).Scan(
+		&otp.ID,
+		&otp.Phone,
+		&otp.CodeHash,
+		&otp.Attempts,
+		&otp.ExpiresAt,
+		&usedAt,
+		&otp.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		otp.UsedAt = &usedAt.Time
+	}
+
+	return otp, nil
+}
+
+func (r *PhoneOTPRepository) IncrementAttempts(id string) (int, error) {
+	query := `UPDATE phone_otps SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`
+
+	var attempts int
+	if err := r.db.QueryRow(query, id).Scan(&attempts); err != nil {
+		return 0, err
+	}
+	return attempts, nil
+}
+
+// MarkUsed consumes the code. It reports false if the code had already been
+// used.
+func (r *PhoneOTPRepository) MarkUsed(id string) (bool, error) {
+	query := `UPDATE phone_otps SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+
+	result, err := r.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// InvalidateByPhone consumes every outstanding code for phone.
+func (r *PhoneOTPRepository) InvalidateByPhone(phone string) error {
+	query := `UPDATE phone_otps SET used_at = $1 WHERE phone = $2 AND used_at IS NULL`
+	_, err := r.db.Exec(query, time.Now(), phone)
+	return err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+
+	"backend/internal/models"
+)
+
+type PreferencesRepository struct {
+	db dbtx
+}
+
+func NewPreferencesRepository(db *sql.DB) *PreferencesRepository {
+	return &PreferencesRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that works within tx.
+func (r *PreferencesRepository) WithTx(tx *sql.Tx) *PreferencesRepository {
+	return &PreferencesRepository{db: tx}
+}
+
+const preferencesColumns = `user_id, locale, theme, time_zone, notify_email, notify_push, notify_marketing, updated_at`
+
+// Get returns userID's preferences, or the defaults if the user never
+// saved any.
+func (r *PreferencesRepository) Get(userID string) (*models.Preferences, error) {
+	query := `SELECT ` + preferencesColumns + ` FROM user_preferences WHERE user_id = $1`
+
+	prefs := &models.Preferences{}
+	err := r.db.QueryRow(query, userID).Scan(
+		&prefs.UserID,
+		&prefs.Locale,
+		&prefs.Theme,
+		&prefs.TimeZone,
+		&prefs.Notifications.Email,
+		&prefs.Notifications.Push,
+		&prefs.Notifications.Marketing,
+		&prefs.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return models.DefaultPreferences(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+// Init stores prefs unless the user already has preferences, so initial
+// values such as the locale of the device a user signs up on never
+// overwrite what they chose.
+func (r *PreferencesRepository) Init(prefs *models.Preferences) error {
+	return r.save(prefs, `DO NOTHING`)
+}
+
+// Save stores prefs, replacing what the user had.
+func (r *PreferencesRepository) Save(prefs *models.Preferences) error {
+	return r.save(prefs, `DO UPDATE SET
+		locale = EXCLUDED.locale,
+		theme = EXCLUDED.theme,
+		time_zone = EXCLUDED.time_zone,
+		notify_email = EXCLUDED.notify_email,
+		notify_push = EXCLUDED.notify_push,
+		notify_marketing = EXCLUDED.notify_marketing,
+		updated_at = EXCLUDED.updated_at`)
+}
+
+func (r *PreferencesRepository) save(prefs *models.Preferences, onConflict string) error {
+	query := `
+		INSERT INTO user_preferences (` + preferencesColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id) ` + onConflict
+
+	_, err := r.db.Exec(query,
+		prefs.UserID,
+		prefs.Locale,
+		prefs.Theme,
+		prefs.TimeZone,
+		prefs.Notifications.Email,
+		prefs.Notifications.Push,
+		prefs.Notifications.Marketing,
+		prefs.UpdatedAt,
+	)
+	return err
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"backend/internal/models"
+)
+
+type RegistrationChallengeRepository struct {
+	db *sql.DB
+}
+
+func NewRegistrationChallengeRepository(db *sql.DB) *RegistrationChallengeRepository {
+	return &RegistrationChallengeRepository{db: db}
+}
+
+func (r *RegistrationChallengeRepository) Create(challenge *models.RegistrationChallenge) error {
+	query := `
+		INSERT INTO registration_challenges (id, difficulty, ip, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Exec(query, challenge.ID, challenge.Difficulty, challenge.IP, challenge.ExpiresAt, challenge.CreatedAt)
+	return err
+}
+
+// Consume marks the challenge used and returns it, or nil if it doesn't
+// exist, has expired or was already used. Each challenge can be consumed
+// only once.
+func (r *RegistrationChallengeRepository) Consume(id string) (*models.RegistrationChallenge, error) {
+	challenge := &models.RegistrationChallenge{}
+	var usedAt sql.NullTime
+
+	query := `
+		UPDATE registration_challenges
+		SET used_at = $1
+		WHERE id = $2 AND used_at IS NULL AND expires_at > $1
+		RETURNING id, difficulty, ip, expires_at, used_at, created_at
+	`
+
+	err := r.db.QueryRow(query, time.Now(), id).Scan(
+		&challenge.ID,
+		&challenge.Difficulty,
+		&challenge.IP,
+		&challenge.ExpiresAt,
+		&usedAt,
+		&challenge.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		challenge.UsedAt = &usedAt.Time
+	}
+
+	return challenge, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+)
+
+var ErrUnknownRetentionTable = errors.New("unknown retention table")
+
+// retentionTables lists the tables rows expire from and which rows are old
+// enough to go, given the cutoff in $1. Rows that can still be used, e.g.
+// a refresh token that hasn't expired, never match.
+var retentionTables = map[string]string{
+	"refresh_tokens":           `expires_at < $1`,
+	"trusted_devices":          `expires_at < $1`,
+	"magic_links":              `expires_at < $1`,
+	"phone_otps":               `expires_at < $1`,
+	"email_verifications":      `expires_at < $1`,
+	"registration_challenges":  `expires_at < $1`,
+	"organization_invitations": `expires_at < $1`,
+	"login_events":             `created_at < $1`,
+	"data_exports":             `status = '` + models.DataExportExpired + `' AND expires_at < $1`,
+	"mail_queue":               `status IN ('` + models.MailSent + `', '` + models.MailDead + `') AND created_at < $1`,
+}
+
+// auditPurgeable matches the audit events before cutoff in $1 that can go
+// without cutting a hole into the hash chain: only the oldest events, and
+// never the last one, which the next event links to.
+const auditPurgeable = `
+	occurred_at < $1
+	AND seq < (SELECT MAX(seq) FROM audit_events)
+	AND seq < COALESCE((SELECT MIN(seq) FROM audit_events WHERE occurred_at >= $1), 9223372036854775807)
+`
+
+type RetentionRepository struct {
+	db *sql.DB
+}
+
+func NewRetentionRepository(db *sql.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// Delete deletes up to limit rows of table that are older than cutoff and
+// returns how many it deleted.
+func (r *RetentionRepository) Delete(table string, cutoff time.Time, limit int) (int64, error) {
+	condition, ok := retentionTables[table]
+	if !ok {
+		return 0, ErrUnknownRetentionTable
+	}
+
+	query := `
+		DELETE FROM ` + table + `
+		WHERE id IN (SELECT id FROM ` + table + ` WHERE ` + condition + ` LIMIT $2)
+	`
+
+	result, err := r.db.Exec(query, cutoff, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Count returns how many rows of table Delete would delete, without a
+// limit.
+func (r *RetentionRepository) Count(table string, cutoff time.Time) (int64, error) {
+	condition, ok := retentionTables[table]
+	if !ok {
+		return 0, ErrUnknownRetentionTable
+	}
+
+	var count int64
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE `+condition, cutoff).Scan(&count)
+	return count, err
+}
+
+// DeleteAuditEvents deletes up to limit of the oldest audit events before
+// cutoff within tx. The table refuses deletes unless the transaction says
+// they come from here. It returns how many events it deleted and the
+// sequence number and hash of the last of them, which the remaining chain
+// links to.
+func (r *RetentionRepository) DeleteAuditEvents(tx *sql.Tx, cutoff time.Time, limit int) (int64, int64, string, error) {
+	if _, err := tx.Exec(`SET LOCAL audit.retention = 'on'`); err != nil {
+		return 0, 0, "", err
+	}
+
+	query := `
+		DELETE FROM audit_events
+		WHERE seq IN (SELECT seq FROM audit_events WHERE ` + auditPurgeable + ` ORDER BY seq LIMIT $2)
+		RETURNING seq, hash
+	`
+
+	rows, err := tx.Query(query, cutoff, limit)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	defer rows.Close()
+
+	var count, lastSeq int64
+	var lastHash string
+	for rows.Next() {
+		var seq int64
+		var hash string
+		if err := rows.Scan(&seq, &hash); err != nil {
+			return 0, 0, "", err
+		}
+		count++
+		if seq > lastSeq {
+			lastSeq, lastHash = seq, hash
+		}
+	}
+
+	return count, lastSeq, lastHash, rows.Err()
+}
+
+// CountAuditEvents returns how many events DeleteAuditEvents would delete,
+// without a limit.
+func (r *RetentionRepository) CountAuditEvents(cutoff time.Time) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM audit_events WHERE `+auditPurgeable, cutoff).Scan(&count)
+	return count, err
+}
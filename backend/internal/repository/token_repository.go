@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 
 	"backend/internal/models"
@@ -10,28 +11,48 @@ import (
 )
 
 type TokenRepository struct {
-	db *sql.DB
+	db dbtx
 }
 
 func NewTokenRepository(db *sql.DB) *TokenRepository {
 	return &TokenRepository{db: db}
 }
 
-func (r *TokenRepository) Create(userID, token string, expiresAt time.Time) (*models.RefreshToken, error) {
+// WithTx returns a copy of the repository that works within tx.
+func (r *TokenRepository) WithTx(tx *sql.Tx) *TokenRepository {
+	return &TokenRepository{db: tx}
+}
+
+// Create stores a refresh token. authTime and amr describe the login the
+// token descends from and, like the active organization orgID, are carried
+// over on every rotation.
+func (r *TokenRepository) Create(userID, token string, expiresAt, authTime time.Time, amr []string, orgID string) (*models.RefreshToken, error) {
 	refreshToken := &models.RefreshToken{
 		ID:        uuid.New().String(),
 		UserID:    userID,
 		Token:     token,
+		AuthTime:  authTime,
+		AMR:       amr,
+		OrgID:     orgID,
 		ExpiresAt: expiresAt,
 		CreatedAt: time.Now(),
 	}
 
 	query := `
-		INSERT INTO refresh_tokens (id, user_id, token, expires_at, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO refresh_tokens (id, user_id, token, auth_time, amr, org_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 
-	_, err := r.db.Exec(query, refreshToken.ID, refreshToken.UserID, refreshToken.Token, refreshToken.ExpiresAt, refreshToken.CreatedAt)
+	_, err := r.db.Exec(query,
+		refreshToken.ID,
+		refreshToken.UserID,
+		refreshToken.Token,
+		refreshToken.AuthTime,
+		strings.Join(refreshToken.AMR, ","),
+		refreshToken.OrgID,
+		refreshToken.ExpiresAt,
+		refreshToken.CreatedAt,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -41,9 +62,11 @@ func (r *TokenRepository) Create(userID, token string, expiresAt time.Time) (*mo
 
 func (r *TokenRepository) GetByToken(token string) (*models.RefreshToken, error) {
 	refreshToken := &models.RefreshToken{}
+	var amr string
 
+	// Tokens created before auth_time was tracked fall back to created_at
 	query := `
-		SELECT id, user_id, token, expires_at, created_at
+		SELECT id, user_id, token, COALESCE(auth_time, created_at), amr, org_id, expires_at, created_at
 		FROM refresh_tokens
 		WHERE token = $1 AND expires_at > $2
 	`
@@ -52,6 +75,9 @@ func (r *TokenRepository) GetByToken(token string) (*models.RefreshToken, error)
 		&refreshToken.ID,
 		&refreshToken.UserID,
 		&refreshToken.Token,
+		&refreshToken.AuthTime,
+		&amr,
+		&refreshToken.OrgID,
 		&refreshToken.ExpiresAt,
 		&refreshToken.CreatedAt,
 	)
@@ -63,6 +89,10 @@ func (r *TokenRepository) GetByToken(token string) (*models.RefreshToken, error)
 		return nil, err
 	}
 
+	if amr != "" {
+		refreshToken.AMR = strings.Split(amr, ",")
+	}
+
 	return refreshToken, nil
 }
 
@@ -77,9 +107,3 @@ func (r *TokenRepository) DeleteByUserID(userID string) error {
 	_, err := r.db.Exec(query, userID)
 	return err
 }
-
-func (r *TokenRepository) CleanupExpired() error {
-	query := `DELETE FROM refresh_tokens WHERE expires_at < $1`
-	_, err := r.db.Exec(query, time.Now())
-	return err
-}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"backend/internal/models"
+)
+
+var ErrTranslationBundleNotFound = errors.New("translation bundle not found")
+
+type TranslationRepository struct {
+	db dbtx
+}
+
+func NewTranslationRepository(db *sql.DB) *TranslationRepository {
+	return &TranslationRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that works within tx.
+func (r *TranslationRepository) WithTx(tx *sql.Tx) *TranslationRepository {
+	return &TranslationRepository{db: tx}
+}
+
+const translationBundleColumns = `id, locale, version, status, messages, uploaded_by, uploaded_at, reviewed_by, reviewed_at, review_note`
+
+// NextVersion returns the version number the next bundle of locale gets.
+func (r *TranslationRepository) NextVersion(locale string) (int, error) {
+	query := `SELECT COALESCE(MAX(version), 0) + 1 FROM translation_bundles WHERE locale = $1`
+
+	var version int
+	if err := r.db.QueryRow(query, locale).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (r *TranslationRepository) Create(bundle *models.TranslationBundle) error {
+	messages, err := json.Marshal(bundle.Messages)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO translation_bundles (id, locale, version, status, messages, uploaded_by, uploaded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = r.db.Exec(query,
+		bundle.ID,
+		bundle.Locale,
+		bundle.Version,
+		bundle.Status,
+		messages,
+		bundle.UploadedBy,
+		bundle.UploadedAt,
+	)
+	return err
+}
+
+// Get returns version of the bundle of locale.
+func (r *TranslationRepository) Get(locale string, version int) (*models.TranslationBundle, error) {
+	query := `SELECT ` + translationBundleColumns + ` FROM translation_bundles WHERE locale = $1 AND version = $2`
+
+	bundle, err := scanTranslationBundle(r.db.QueryRow(query, locale, version))
+	if err == sql.ErrNoRows {
+		return nil, ErrTranslationBundleNotFound
+	}
+	return bundle, err
+}
+
+// LatestPublished returns the newest published bundle of locale.
+func (r *TranslationRepository) LatestPublished(locale string) (*models.TranslationBundle, error) {
+	query := `
+		SELECT ` + translationBundleColumns + `
+		FROM translation_bundles
+		WHERE locale = $1 AND status = $2
+		ORDER BY version DESC
+		LIMIT 1
+	`
+
+	bundle, err := scanTranslationBundle(r.db.QueryRow(query, locale, models.TranslationPublished))
+	if err == sql.ErrNoRows {
+		return nil, ErrTranslationBundleNotFound
+	}
+	return bundle, err
+}
+
+// List returns the bundles of locale without their messages, newest first.
+func (r *TranslationRepository) List(locale string) ([]*models.TranslationBundle, error) {
+	query := `
+		SELECT ` + translationBundleColumns + `
+		FROM translation_bundles
+		WHERE locale = $1
+		ORDER BY version DESC
+	`
+
+	rows, err := r.db.Query(query, locale)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bundles := []*models.TranslationBundle{}
+	for rows.Next() {
+		bundle, err := scanTranslationBundle(rows)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Messages = nil
+		bundles = append(bundles, bundle)
+	}
+
+	return bundles, rows.Err()
+}
+
+// Review records the outcome of reviewing bundle, which must still be
+// pending; otherwise it returns ErrTranslationBundleNotFound.
+func (r *TranslationRepository) Review(bundle *models.TranslationBundle) error {
+	query := `
+		UPDATE translation_bundles
+		SET status = $1, reviewed_by = $2, reviewed_at = $3, review_note = $4
+		WHERE id = $5 AND status = $6
+	`
+
+	result, err := r.db.Exec(query,
+		bundle.Status,
+		bundle.ReviewedBy,
+		bundle.ReviewedAt,
+		bundle.ReviewNote,
+		bundle.ID,
+		models.TranslationPending,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrTranslationBundleNotFound
+	}
+	return nil
+}
+
+func scanTranslationBundle(row rowScanner) (*models.TranslationBundle, error) {
+	bundle := &models.TranslationBundle{}
+	var messages []byte
+	var reviewedAt sql.NullTime
+
+	err := row.Scan(
+		&bundle.ID,
+		&bundle.Locale,
+		&bundle.Version,
+		&bundle.Status,
+		&messages,
+		&bundle.UploadedBy,
+		&bundle.UploadedAt,
+		&bundle.ReviewedBy,
+		&reviewedAt,
+		&bundle.ReviewNote,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(messages, &bundle.Messages); err != nil {
+		return nil, err
+	}
+	if reviewedAt.Valid {
+		bundle.ReviewedAt = &reviewedAt.Time
+	}
+
+	return bundle, nil
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+)
+
+var ErrDeviceNotFound = errors.New("device not found")
+
+type TrustedDeviceRepository struct {
+	db dbtx
+}
+
+func NewTrustedDeviceRepository(db *sql.DB) *TrustedDeviceRepository {
+	return &TrustedDeviceRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that works within tx.
+func (r *TrustedDeviceRepository) WithTx(tx *sql.Tx) *TrustedDeviceRepository {
+	return &TrustedDeviceRepository{db: tx}
+}
+
+func (r *TrustedDeviceRepository) Create(device *models.TrustedDevice) error {
+	query := `
+		INSERT INTO trusted_devices (id, user_id, token_hash, name, user_agent, expires_at, last_used_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(query,
+		device.ID,
+		device.UserID,
+		device.TokenHash,
+		device.Name,
+		device.UserAgent,
+		device.ExpiresAt,
+		device.LastUsedAt,
+		device.CreatedAt,
+	)
+	return err
+}
+
+// GetActive returns the unexpired device of userID with the given token
+// hash, or nil if there is none.
+func (r *TrustedDeviceRepository) GetActive(userID, tokenHash string) (*models.TrustedDevice, error) {
+	device := &models.TrustedDevice{}
+
+	query := `
+		SELECT id, user_id, token_hash, name, user_agent, expires_at, last_used_at, created_at
+		FROM trusted_devices
+		WHERE user_id = $1 AND token_hash = $2 AND expires_at > $3
+	`
+
+	err := r.db.QueryRow(query, userID, tokenHash, time.Now()).Scan(
+		&device.ID,
+		&device.UserID,
+		&device.TokenHash,
+		&device.Name,
+		&device.UserAgent,
+		&device.ExpiresAt,
+		&device.LastUsedAt,
+		&device.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+func (r *TrustedDeviceRepository) Touch(id string) error {
+	query := `UPDATE trusted_devices SET last_used_at = $1 WHERE id = $2`
+	_, err := r.db.Exec(query, time.Now(), id)
+	return err
+}
+
+func (r *TrustedDeviceRepository) ListByUserID(userID string) ([]*models.TrustedDevice, error) {
+	query := `
+		SELECT id, user_id, token_hash, name, user_agent, expires_at, last_used_at, created_at
+		FROM trusted_devices
+		WHERE user_id = $1 AND expires_at > $2
+		ORDER BY last_used_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]*models.TrustedDevice, 0)
+	for rows.Next() {
+		device := &models.TrustedDevice{}
+		if err := rows.Scan(
+			&device.ID,
+			&device.UserID,
+			&device.TokenHash,
+			&device.Name,
+			&device.UserAgent,
+			&device.ExpiresAt,
+			&device.LastUsedAt,
+			&device.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+func (r *TrustedDeviceRepository) Delete(userID, id string) error {
+	query := `DELETE FROM trusted_devices WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDeviceNotFound
+	}
+
+	return nil
+}
+
+func (r *TrustedDeviceRepository) DeleteByUserID(userID string) error {
+	query := `DELETE FROM trusted_devices WHERE user_id = $1`
+	_, err := r.db.Exec(query, userID)
+	return err
+}
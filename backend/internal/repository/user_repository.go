@@ -3,8 +3,10 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"slices"
 	"time"
 
+	"backend/internal/fieldcrypt"
 	"backend/internal/models"
 
 	"github.com/google/uuid"
@@ -13,35 +15,93 @@ import (
 var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrEmailAlreadyExists = errors.New("email already exists")
+	ErrPhoneAlreadyExists = errors.New("phone already exists")
+	ErrLastIdentity       = errors.New("cannot remove the last login method")
 )
 
+const userColumns = `id, email, phone, password_hash, name, role, risk_score, created_at, updated_at, email_verified_at, deleted_at`
+
+// Encrypted columns of users, also the names their values are bound to.
+// Email addresses and phone numbers have a blind index next to them, in
+// email_index and phone_index, to look them up by.
+const (
+	fieldEmail = "users.email"
+	fieldPhone = "users.phone"
+	fieldName  = "users.name"
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// dbtx is what repositories need from a *sql.DB, and which a *sql.Tx
+// offers too, so a repository can take part in a transaction.
+type dbtx interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// UserRepository stores users. Email addresses, phone numbers and names
+// are encrypted with ring on the way in and decrypted on the way out.
 type UserRepository struct {
-	db *sql.DB
+	db   dbtx
+	ring *fieldcrypt.KeyRing
+}
+
+func NewUserRepository(db *sql.DB, ring *fieldcrypt.KeyRing) *UserRepository {
+	return &UserRepository{db: db, ring: ring}
 }
 
-func NewUserRepository(db *sql.DB) *UserRepository {
-	return &UserRepository{db: db}
+// WithTx returns a copy of the repository that works within tx.
+func (r *UserRepository) WithTx(tx *sql.Tx) *UserRepository {
+	return &UserRepository{db: tx, ring: r.ring}
 }
 
-func (r *UserRepository) Create(email, passwordHash, name string) (*models.User, error) {
+// Create stores a user that signs in with email and password. riskScore is
+// the registration risk assessed by the caller.
+func (r *UserRepository) Create(email, passwordHash, name string, riskScore int) (*models.User, error) {
 	user := &models.User{
 		ID:           uuid.New().String(),
 		Email:        email,
 		PasswordHash: passwordHash,
 		Name:         name,
+		Role:         models.RoleUser,
+		RiskScore:    riskScore,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
+	if err := r.checkUnindexed(fieldEmail, user.Email, ErrEmailAlreadyExists); err != nil {
+		return nil, err
+	}
+	encryptedEmail, err := r.ring.Encrypt(fieldEmail, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	encryptedName, err := r.ring.Encrypt(fieldName, user.Name)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		INSERT INTO users (id, email, password_hash, name, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO users (id, email, email_index, password_hash, name, risk_score, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 
-	_, err := r.db.Exec(query, user.ID, user.Email, user.PasswordHash, user.Name, user.CreatedAt, user.UpdatedAt)
+	_, err = r.db.Exec(query,
+		user.ID,
+		encryptedEmail,
+		r.index(fieldEmail, user.Email),
+		user.PasswordHash,
+		encryptedName,
+		user.RiskScore,
+		user.CreatedAt,
+		user.UpdatedAt,
+	)
 	if err != nil {
 		// Check for unique constraint violation
-		if err.Error() == "pq: duplicate key value violates unique constraint \"users_email_key\"" {
+		if isDuplicate(err, "users_email_key", "users_email_index_key") {
 			return nil, ErrEmailAlreadyExists
 		}
 		return nil, err
@@ -50,27 +110,73 @@ func (r *UserRepository) Create(email, passwordHash, name string) (*models.User,
 	return user, nil
 }
 
-func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
-	user := &models.User{}
+// CreateWithPhone creates a user that signs in with an SMS code and has
+// neither an email address nor a password. riskScore is the registration
+// risk assessed by the caller.
+func (r *UserRepository) CreateWithPhone(phone, name string, riskScore int) (*models.User, error) {
+	user := &models.User{
+		ID:        uuid.New().String(),
+		Phone:     phone,
+		Name:      name,
+		Role:      models.RoleUser,
+		RiskScore: riskScore,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := r.checkUnindexed(fieldPhone, user.Phone, ErrPhoneAlreadyExists); err != nil {
+		return nil, err
+	}
+	encryptedPhone, err := r.ring.Encrypt(fieldPhone, user.Phone)
+	if err != nil {
+		return nil, err
+	}
+	encryptedName, err := r.ring.Encrypt(fieldName, user.Name)
+	if err != nil {
+		return nil, err
+	}
 
 	query := `
-		SELECT id, email, password_hash, name, created_at, updated_at
-		FROM users
-		WHERE email = $1
+		INSERT INTO users (id, phone, phone_index, password_hash, name, risk_score, created_at, updated_at)
+		VALUES ($1, $2, $3, '', $4, $5, $6, $7)
 	`
 
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.Name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
+	_, err = r.db.Exec(query,
+		user.ID,
+		encryptedPhone,
+		r.index(fieldPhone, user.Phone),
+		encryptedName,
+		user.RiskScore,
+		user.CreatedAt,
+		user.UpdatedAt,
 	)
+	if err != nil {
+		if isDuplicate(err, "users_phone_key", "users_phone_index_key") {
+			return nil, ErrPhoneAlreadyExists
+		}
+		return nil, err
+	}
 
-	if err == sql.ErrNoRows {
-		return nil, ErrUserNotFound
+	return user, nil
+}
+
+// CreateGuest creates an anonymous guest account. riskScore is the
+// registration risk assessed by the caller.
+func (r *UserRepository) CreateGuest(riskScore int) (*models.User, error) {
+	user := &models.User{
+		ID:        uuid.New().String(),
+		Role:      models.RoleGuest,
+		RiskScore: riskScore,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
 	}
+
+	query := `
+		INSERT INTO users (id, password_hash, name, role, risk_score, created_at, updated_at)
+		VALUES ($1, '', '', $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Exec(query, user.ID, user.Role, user.RiskScore, user.CreatedAt, user.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -78,37 +184,173 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
-func (r *UserRepository) GetByID(id string) (*models.User, error) {
-	user := &models.User{}
+// UpgradeGuest turns guest account id into a full account that signs in
+// with email and password. It fails with ErrUserNotFound if id isn't a
+// guest (anymore).
+func (r *UserRepository) UpgradeGuest(id, email, passwordHash, name string) error {
+	if err := r.checkUnindexed(fieldEmail, email, ErrEmailAlreadyExists); err != nil {
+		return err
+	}
+	encryptedEmail, err := r.ring.Encrypt(fieldEmail, email)
+	if err != nil {
+		return err
+	}
+	encryptedName, err := r.ring.Encrypt(fieldName, name)
+	if err != nil {
+		return err
+	}
 
 	query := `
-		SELECT id, email, password_hash, name, created_at, updated_at
-		FROM users
-		WHERE id = $1
+		UPDATE users
+		SET email = $1, email_index = $2, password_hash = $3, name = $4, role = $5, updated_at = $6
+		WHERE id = $7 AND role = $8
 	`
 
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.Name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
+	result, err := r.db.Exec(query,
+		encryptedEmail,
+		r.index(fieldEmail, email),
+		passwordHash,
+		encryptedName,
+		models.RoleUser,
+		time.Now(),
+		id,
+		models.RoleGuest,
 	)
+	if err != nil {
+		if isDuplicate(err, "users_email_key", "users_email_index_key") {
+			return ErrEmailAlreadyExists
+		}
+		return err
+	}
 
-	if err == sql.ErrNoRows {
-		return nil, ErrUserNotFound
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
 	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpgradeGuestWithPhone is UpgradeGuest for an account that signs in with
+// an SMS code.
+func (r *UserRepository) UpgradeGuestWithPhone(id, phone, name string) error {
+	if err := r.checkUnindexed(fieldPhone, phone, ErrPhoneAlreadyExists); err != nil {
+		return err
+	}
+	encryptedPhone, err := r.ring.Encrypt(fieldPhone, phone)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	encryptedName, err := r.ring.Encrypt(fieldName, name)
+	if err != nil {
+		return err
 	}
 
-	return user, nil
+	query := `
+		UPDATE users
+		SET phone = $1, phone_index = $2, name = $3, role = $4, updated_at = $5
+		WHERE id = $6 AND role = $7
+	`
+
+	result, err := r.db.Exec(query,
+		encryptedPhone,
+		r.index(fieldPhone, phone),
+		encryptedName,
+		models.RoleUser,
+		time.Now(),
+		id,
+		models.RoleGuest,
+	)
+	if err != nil {
+		if isDuplicate(err, "users_phone_key", "users_phone_index_key") {
+			return ErrPhoneAlreadyExists
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// inactiveGuests matches guest accounts created before the cutoff in $2
+// that haven't signed in or refreshed a token since.
+const inactiveGuests = `
+	u.role = $1 AND u.created_at < $2
+	AND NOT EXISTS (
+		SELECT 1 FROM login_events e
+		WHERE e.user_id = u.id AND e.created_at >= $2
+	)
+`
+
+// DeleteInactiveGuests removes up to limit guest accounts created before
+// cutoff that haven't signed in or refreshed a token since, and returns the
+// IDs of the removed accounts. Their data goes with them through ON DELETE
+// CASCADE.
+func (r *UserRepository) DeleteInactiveGuests(cutoff time.Time, limit int) ([]string, error) {
+	query := `
+		DELETE FROM users
+		WHERE id IN (SELECT u.id FROM users u WHERE ` + inactiveGuests + ` LIMIT $3)
+		RETURNING id
+	`
+
+	return r.deleteReturningIDs(query, models.RoleGuest, cutoff, limit)
+}
+
+// CountInactiveGuests returns how many accounts DeleteInactiveGuests would
+// remove, without a limit.
+func (r *UserRepository) CountInactiveGuests(cutoff time.Time) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users u WHERE `+inactiveGuests, models.RoleGuest, cutoff).Scan(&count)
+	return count, err
+}
+
+// GetByEmail finds a user by email address through its blind index.
+// Addresses stored before encryption was turned on have no index until
+// ReencryptBatch gets to them and are matched ignoring case; rows that only
+// differ in case predate canonical addresses, so an exact match wins over
+// the others, then the oldest account.
+func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE email_index = $1 OR (email_index IS NULL AND LOWER(email) = LOWER($2))
+		ORDER BY email_index IS NOT NULL DESC, email = $2 DESC, created_at
+		LIMIT 1
+	`
+	return r.scanUser(r.db.QueryRow(query, r.index(fieldEmail, email), email))
+}
+
+// GetByPhone finds a user by phone number, like GetByEmail.
+func (r *UserRepository) GetByPhone(phone string) (*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE phone_index = $1 OR (phone_index IS NULL AND phone = $2)
+		LIMIT 1
+	`
+	return r.scanUser(r.db.QueryRow(query, r.index(fieldPhone, phone), phone))
+}
+
+func (r *UserRepository) GetByID(id string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	return r.scanUser(r.db.QueryRow(query, id))
 }
 
 func (r *UserRepository) List() ([]*models.User, error) {
 	query := `
-		SELECT id, email, name, created_at, updated_at
+		SELECT id, email, phone, name, role, created_at, updated_at, deleted_at
 		FROM users
 		ORDER BY created_at DESC
 	`
@@ -122,15 +364,26 @@ func (r *UserRepository) List() ([]*models.User, error) {
 	users := make([]*models.User, 0)
 	for rows.Next() {
 		user := &models.User{}
+		var email, phone sql.NullString
+		var deletedAt sql.NullTime
 		if err := rows.Scan(
 			&user.ID,
-			&user.Email,
+			&email,
+			&phone,
 			&user.Name,
+			&user.Role,
 			&user.CreatedAt,
 			&user.UpdatedAt,
+			&deletedAt,
 		); err != nil {
 			return nil, err
 		}
+		if err := r.decryptUser(user, email.String, phone.String, user.Name); err != nil {
+			return nil, err
+		}
+		if deletedAt.Valid {
+			user.DeletedAt = &deletedAt.Time
+		}
 		users = append(users, user)
 	}
 
@@ -142,13 +395,168 @@ func (r *UserRepository) List() ([]*models.User, error) {
 }
 
 func (r *UserRepository) Update(id, name string) error {
+	encryptedName, err := r.ring.Encrypt(fieldName, name)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE users
 		SET name = $1, updated_at = $2
 		WHERE id = $3
 	`
 
-	result, err := r.db.Exec(query, name, time.Now(), id)
+	result, err := r.db.Exec(query, encryptedName, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) UpdatePassword(id, passwordHash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	result, err := r.db.Exec(query, passwordHash, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateEmail sets the email address of id. verified tells whether the user
+// has proven they receive mail at it.
+func (r *UserRepository) UpdateEmail(id, email string, verified bool) error {
+	if err := r.checkUnindexed(fieldEmail, email, ErrEmailAlreadyExists); err != nil {
+		return err
+	}
+	encryptedEmail, err := r.ring.Encrypt(fieldEmail, email)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var verifiedAt *time.Time
+	if verified {
+		verifiedAt = &now
+	}
+
+	query := `
+		UPDATE users
+		SET email = $1, email_index = $2, email_verified_at = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	result, err := r.db.Exec(query, encryptedEmail, r.index(fieldEmail, email), verifiedAt, now, id)
+	if err != nil {
+		if isDuplicate(err, "users_email_key", "users_email_index_key") {
+			return ErrEmailAlreadyExists
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) UpdatePhone(id, phone string) error {
+	if err := r.checkUnindexed(fieldPhone, phone, ErrPhoneAlreadyExists); err != nil {
+		return err
+	}
+	encryptedPhone, err := r.ring.Encrypt(fieldPhone, phone)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE users
+		SET phone = $1, phone_index = $2, updated_at = $3
+		WHERE id = $4
+	`
+
+	result, err := r.db.Exec(query, encryptedPhone, r.index(fieldPhone, phone), time.Now(), id)
+	if err != nil {
+		if isDuplicate(err, "users_phone_key", "users_phone_index_key") {
+			return ErrPhoneAlreadyExists
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// MarkEmailVerified records that the user id has proven they receive mail
+// at their current address.
+func (r *UserRepository) MarkEmailVerified(id string) error {
+	query := `UPDATE users SET email_verified_at = $1 WHERE id = $2 AND email_verified_at IS NULL`
+	_, err := r.db.Exec(query, time.Now(), id)
+	return err
+}
+
+// UnlinkEmail removes the email address of id together with its password,
+// which can't be used without one. Whether the account keeps a way to sign
+// in is up to IdentityRepository.Unlink.
+func (r *UserRepository) UnlinkEmail(id string) error {
+	query := `
+		UPDATE users
+		SET email = NULL, email_index = NULL, email_verified_at = NULL, password_hash = '', updated_at = $1
+		WHERE id = $2
+	`
+	return r.unlink(query, id)
+}
+
+// UnlinkPhone removes the phone number of id, like UnlinkEmail.
+func (r *UserRepository) UnlinkPhone(id string) error {
+	query := `
+		UPDATE users
+		SET phone = NULL, phone_index = NULL, updated_at = $1
+		WHERE id = $2
+	`
+	return r.unlink(query, id)
+}
+
+func (r *UserRepository) unlink(query, id string) error {
+	result, err := r.db.Exec(query, time.Now(), id)
 	if err != nil {
 		return err
 	}
@@ -185,6 +593,178 @@ func (r *UserRepository) Delete(id string) error {
 	return nil
 }
 
+// MarkDeleted schedules id for deletion. The row stays until
+// PurgeDeleted removes it, so Restore can bring the account back.
+func (r *UserRepository) MarkDeleted(id string) error {
+	query := `
+		UPDATE users
+		SET deleted_at = $1, updated_at = $1
+		WHERE id = $2 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Restore cancels the scheduled deletion of id.
+func (r *UserRepository) Restore(id string) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, updated_at = $1
+		WHERE id = $2 AND deleted_at IS NOT NULL
+	`
+
+	result, err := r.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// PurgeDeleted permanently deletes the accounts scheduled for deletion
+// before cutoff and returns their IDs.
+func (r *UserRepository) PurgeDeleted(cutoff time.Time) ([]string, error) {
+	query := `DELETE FROM users WHERE deleted_at < $1 RETURNING id`
+
+	return r.deleteReturningIDs(query, cutoff)
+}
+
+func (r *UserRepository) deleteReturningIDs(query string, args ...any) ([]string, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// ReencryptBatch re-encrypts up to limit users whose email address, phone
+// number or name isn't encrypted with the active key yet, including values
+// stored before encryption was turned on, and fills in missing blind
+// indexes. It returns how many users it went through; fewer than limit
+// means it caught up. Rows changed in the meantime are skipped, as the
+// change already encrypted them.
+func (r *UserRepository) ReencryptBatch(limit int) (int, error) {
+	if !r.ring.Enabled() {
+		return 0, nil
+	}
+
+	query := `
+		SELECT id, email, phone, name
+		FROM users
+		WHERE (email IS NOT NULL AND (email NOT LIKE $1 OR email_index IS NULL))
+			OR (phone IS NOT NULL AND (phone NOT LIKE $1 OR phone_index IS NULL))
+			OR (name <> '' AND name NOT LIKE $1)
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, r.ring.ActivePrefix()+"%", limit)
+	if err != nil {
+		return 0, err
+	}
+
+	type stored struct {
+		id    string
+		email sql.NullString
+		phone sql.NullString
+		name  string
+	}
+	var batch []stored
+	for rows.Next() {
+		var row stored
+		if err := rows.Scan(&row.id, &row.email, &row.phone, &row.name); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, row := range batch {
+		user := &models.User{ID: row.id}
+		if err := r.decryptUser(user, row.email.String, row.phone.String, row.name); err != nil {
+			return 0, err
+		}
+
+		email, phone := sql.NullString{}, sql.NullString{}
+		if row.email.Valid {
+			if email.String, err = r.ring.Encrypt(fieldEmail, user.Email); err != nil {
+				return 0, err
+			}
+			email.Valid = true
+		}
+		if row.phone.Valid {
+			if phone.String, err = r.ring.Encrypt(fieldPhone, user.Phone); err != nil {
+				return 0, err
+			}
+			phone.Valid = true
+		}
+		name, err := r.ring.Encrypt(fieldName, user.Name)
+		if err != nil {
+			return 0, err
+		}
+
+		query := `
+			UPDATE users
+			SET email = $1, email_index = $2, phone = $3, phone_index = $4, name = $5
+			WHERE id = $6 AND email IS NOT DISTINCT FROM $7 AND phone IS NOT DISTINCT FROM $8 AND name = $9
+		`
+		_, err = r.db.Exec(query,
+			email,
+			r.index(fieldEmail, user.Email),
+			phone,
+			r.index(fieldPhone, user.Phone),
+			name,
+			row.id,
+			row.email,
+			row.phone,
+			row.name,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(batch), nil
+}
+
 func (r *UserRepository) GetUsageStats(userID string) (map[string]int, error) {
 	query := `
 		SELECT feature, count
@@ -210,3 +790,96 @@ func (r *UserRepository) GetUsageStats(userID string) (map[string]int, error) {
 
 	return usage, nil
 }
+
+func (r *UserRepository) scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	var email, phone sql.NullString
+	var emailVerifiedAt, deletedAt sql.NullTime
+
+	err := row.Scan(
+		&user.ID,
+		&email,
+		&phone,
+		&user.PasswordHash,
+		&user.Name,
+		&user.Role,
+		&user.RiskScore,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&emailVerifiedAt,
+		&deletedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.decryptUser(user, email.String, phone.String, user.Name); err != nil {
+		return nil, err
+	}
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+
+	return user, nil
+}
+
+// decryptUser sets the email address, phone number and name of user from
+// their stored values.
+func (r *UserRepository) decryptUser(user *models.User, email, phone, name string) error {
+	var err error
+	if user.Email, err = r.ring.Decrypt(fieldEmail, email); err != nil {
+		return err
+	}
+	if user.Phone, err = r.ring.Decrypt(fieldPhone, phone); err != nil {
+		return err
+	}
+	if user.Name, err = r.ring.Decrypt(fieldName, name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkUnindexed fails with dup if a user stored before encryption was
+// turned on, whose blind index ReencryptBatch hasn't filled in yet, has
+// value for field. Unique constraints only cover the indexes once
+// encryption is on.
+func (r *UserRepository) checkUnindexed(field, value string, dup error) error {
+	if !r.ring.Enabled() {
+		return nil
+	}
+
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE email_index IS NULL AND LOWER(email) = LOWER($1))`
+	if field == fieldPhone {
+		query = `SELECT EXISTS (SELECT 1 FROM users WHERE phone_index IS NULL AND phone = $1)`
+	}
+
+	var exists bool
+	if err := r.db.QueryRow(query, value).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return dup
+	}
+	return nil
+}
+
+// index returns the blind index of value of field, or NULL when there is
+// none.
+func (r *UserRepository) index(field, value string) sql.NullString {
+	index := r.ring.Index(field, value)
+	return sql.NullString{String: index, Valid: index != ""}
+}
+
+// isDuplicate reports whether err violates one of the unique constraints.
+func isDuplicate(err error, constraints ...string) bool {
+	return slices.ContainsFunc(constraints, func(constraint string) bool {
+		return err.Error() == "pq: duplicate key value violates unique constraint \""+constraint+"\""
+	})
+}
@@ -9,11 +9,19 @@ import (
 
 func New(
 	authMW *middleware.AuthMiddleware,
+	consentGate *middleware.ConsentGate,
 	rateLimiter *middleware.RateLimiter,
+	accessLogger *middleware.AccessLogger,
 	serviceProxy *proxy.ServiceProxy,
 ) http.Handler {
 	mux := http.NewServeMux()
 
+	// consented requires a JWT whose user accepted the required versions of
+	// the legal documents
+	consented := func(next http.Handler) http.Handler {
+		return authMW.RequireAuth(consentGate.Require(next))
+	}
+
 	// Health check
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -22,23 +30,91 @@ func New(
 	})
 
 	// Public routes — proxied to auth-service
+	mux.Handle("POST /api/v1/auth/register/challenge", serviceProxy.AuthProxy())
 	mux.Handle("POST /api/v1/auth/register", serviceProxy.AuthProxy())
 	mux.Handle("POST /api/v1/auth/login", serviceProxy.AuthProxy())
 	mux.Handle("POST /api/v1/auth/refresh", serviceProxy.AuthProxy())
 	mux.Handle("POST /api/v1/auth/forgot-password", serviceProxy.AuthProxy())
+	mux.Handle("POST /api/v1/auth/magic-link", serviceProxy.AuthProxy())
+	mux.Handle("POST /api/v1/auth/magic-link/verify", serviceProxy.AuthProxy())
+	mux.Handle("POST /api/v1/auth/phone/otp", serviceProxy.AuthProxy())
+	mux.Handle("POST /api/v1/auth/phone/verify", serviceProxy.AuthProxy())
+	mux.Handle("POST /api/v1/auth/guest", serviceProxy.AuthProxy())
+	mux.Handle("GET /api/v1/exports/{id}/download", serviceProxy.AuthProxy())
+	mux.Handle("GET /api/v1/legal/documents", serviceProxy.AuthProxy())
+	mux.Handle("GET /api/v1/legal/versions", serviceProxy.AuthProxy())
+	mux.Handle("GET /api/v1/translations/{locale}", serviceProxy.AuthProxy())
 
-	// Protected routes — require JWT, then proxy to auth-service
+	// Protected routes that stay open until the user accepts the legal
+	// documents, so they can read and accept them in their language, export
+	// their data or delete their account instead
+	mux.Handle("POST /api/v1/user/consents", authMW.RequireAuth(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/user/consents", authMW.RequireAuth(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/user/preferences", authMW.RequireAuth(serviceProxy.AuthProxy()))
+	mux.Handle("PATCH /api/v1/user/preferences", authMW.RequireAuth(serviceProxy.AuthProxy()))
 	mux.Handle("GET /api/v1/user/profile", authMW.RequireAuth(serviceProxy.AuthProxy()))
-	mux.Handle("PUT /api/v1/user/profile", authMW.RequireAuth(serviceProxy.AuthProxy()))
-	mux.Handle("GET /api/v1/user/usage", authMW.RequireAuth(serviceProxy.AuthProxy()))
-	mux.Handle("GET /api/v1/users", authMW.RequireAuth(serviceProxy.AuthProxy()))
-	mux.Handle("GET /api/v1/users/{id}", authMW.RequireAuth(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/user/exports", authMW.RequireAuth(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/user/exports", authMW.RequireAuth(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/user/exports/{id}", authMW.RequireAuth(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/auth/reauthenticate", authMW.RequireAuth(serviceProxy.AuthProxy()))
 	mux.Handle("DELETE /api/v1/auth/account", authMW.RequireAuth(serviceProxy.AuthProxy()))
 
+	// Protected routes — require JWT and consent, then proxy to auth-service
+	mux.Handle("PUT /api/v1/user/profile", consented(serviceProxy.AuthProxy()))
+	mux.Handle("PUT /api/v1/user/password", consented(serviceProxy.AuthProxy()))
+	mux.Handle("PUT /api/v1/user/email", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/user/upgrade", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/user/upgrade/phone", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/user/identities", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/user/identities/email", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/user/identities/phone", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/user/identities/password", consented(serviceProxy.AuthProxy()))
+	mux.Handle("DELETE /api/v1/user/identities/{method}", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/user/usage", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/user/login-history", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/user/devices", consented(serviceProxy.AuthProxy()))
+	mux.Handle("DELETE /api/v1/user/devices", consented(serviceProxy.AuthProxy()))
+	mux.Handle("DELETE /api/v1/user/devices/{id}", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/user/invitations", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/user/invitations/accept", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/user/invitations/decline", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/users", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/users/{id}", consented(serviceProxy.AuthProxy()))
+
+	// Organization routes — require JWT and consent; the auth-service checks org roles
+	mux.Handle("POST /api/v1/orgs", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/orgs", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/orgs/{id}/switch", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/orgs/{id}/members", consented(serviceProxy.AuthProxy()))
+	mux.Handle("PUT /api/v1/orgs/{id}/members/{userId}", consented(serviceProxy.AuthProxy()))
+	mux.Handle("DELETE /api/v1/orgs/{id}/members/{userId}", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/orgs/{id}/invitations", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/orgs/{id}/invitations", consented(serviceProxy.AuthProxy()))
+	mux.Handle("DELETE /api/v1/orgs/{id}/invitations/{invitationId}", consented(serviceProxy.AuthProxy()))
+
+	// Admin routes — require JWT and consent; the auth-service checks the admin role
+	mux.Handle("POST /api/v1/admin/legal-documents", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/admin/translations/{locale}", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/admin/translations/{locale}", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/admin/translations/{locale}/{version}", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/admin/translations/{locale}/{version}/publish", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/admin/translations/{locale}/{version}/reject", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/admin/emails", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/admin/emails/{name}/preview", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/admin/users/{id}/login-history", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/admin/users/{id}/restore", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/admin/audit-events", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/admin/audit-events/verify", consented(serviceProxy.AuthProxy()))
+	mux.Handle("POST /api/v1/admin/invitations", consented(serviceProxy.AuthProxy()))
+	mux.Handle("GET /api/v1/admin/invitations", consented(serviceProxy.AuthProxy()))
+	mux.Handle("DELETE /api/v1/admin/invitations/{id}", consented(serviceProxy.AuthProxy()))
+
 	// Apply global middleware
 	var handler http.Handler = mux
 	handler = rateLimiter.Limit(handler)
 	handler = middleware.CORS(handler)
+	handler = accessLogger.Log(handler)
+	handler = middleware.RequestID(handler)
 
 	return handler
 }
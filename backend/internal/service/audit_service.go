@@ -0,0 +1,222 @@
+package service
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"backend/internal/audit"
+	"backend/internal/geoip"
+	"backend/internal/models"
+	"backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// auditVerifyBatch is how many events Verify reads at a time.
+const auditVerifyBatch = 1000
+
+// AuditService keeps the security audit log. Events are written in the
+// same transaction as the change they describe, so there is an event for
+// every change that was committed and none for changes that weren't. Each
+// event's hash covers the one before it, which makes tampering with the
+// table evident. Committed events are also handed to a sink.
+type AuditService struct {
+	db        *sql.DB
+	auditRepo *repository.AuditRepository
+	geo       *geoip.Resolver
+	sink      audit.Sink
+}
+
+func NewAuditService(db *sql.DB, auditRepo *repository.AuditRepository, geo *geoip.Resolver, sink audit.Sink) *AuditService {
+	return &AuditService{
+		db:        db,
+		auditRepo: auditRepo,
+		geo:       geo,
+		sink:      sink,
+	}
+}
+
+// Track runs change and appends event to the audit log in one transaction.
+// change may fill in event, e.g. with the ID of a user it creates. With a
+// nil change only the event is recorded.
+func (s *AuditService) Track(event *models.AuditEvent, change func(tx *sql.Tx) error) error {
+	return s.TrackAll(func(tx *sql.Tx) ([]*models.AuditEvent, error) {
+		if change != nil {
+			if err := change(tx); err != nil {
+				return nil, err
+			}
+		}
+		return []*models.AuditEvent{event}, nil
+	})
+}
+
+// TrackAll is Track for changes that cause any number of events; change
+// returns them.
+func (s *AuditService) TrackAll(change func(tx *sql.Tx) ([]*models.AuditEvent, error)) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	events, err := change(tx)
+	if err != nil {
+		return err
+	}
+
+	if len(events) > 0 {
+		seq, prevHash, err := s.auditRepo.Tail(tx)
+		if err != nil {
+			return err
+		}
+
+		// The column has microsecond precision; the hash must match what
+		// is read back
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		for _, event := range events {
+			seq++
+			event.Seq = seq
+			event.Time = now
+			s.locate(event)
+			event.PrevHash = prevHash
+			event.Hash = auditHash(event)
+			prevHash = event.Hash
+
+			if err := s.auditRepo.Append(tx, event); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if err := s.sink.Write(event); err != nil {
+			log.Printf("Error writing audit event %d to the sink: %v", event.Seq, err)
+		}
+	}
+
+	return nil
+}
+
+// Record appends event on its own, for events without a change to go with,
+// such as failed logins. Failures are logged rather than returned so they
+// don't fail the request.
+func (s *AuditService) Record(event *models.AuditEvent) {
+	if err := s.Track(event, nil); err != nil {
+		log.Printf("Error recording audit event %s for user %s: %v", event.Action, event.UserID, err)
+	}
+}
+
+// Events returns a page of the events matching filter, newest first, and
+// the total number of matching events.
+func (s *AuditService) Events(filter repository.AuditFilter, limit, offset int) ([]*models.AuditEvent, int, error) {
+	return s.auditRepo.List(filter, limit, offset)
+}
+
+// Verify walks the hash chain from the start, or from the oldest event
+// the retention job kept. It returns how many events check out and the
+// first event that doesn't, or nil if the chain is intact.
+func (s *AuditService) Verify() (int64, *models.AuditEvent, error) {
+	var checked, seq int64
+	prevHash := ""
+	first := true
+
+	for {
+		events, err := s.auditRepo.ListAfter(seq, auditVerifyBatch)
+		if err != nil {
+			return checked, nil, err
+		}
+		if len(events) == 0 {
+			return checked, nil, nil
+		}
+
+		// Events before the oldest one were purged; the purge recorded
+		// the hash of the last of them
+		if first && events[0].Seq > 1 {
+			seq = events[0].Seq - 1
+			if prevHash, err = s.auditRepo.PurgedThrough(seq); err != nil {
+				return checked, nil, err
+			}
+		}
+		first = false
+
+		for _, event := range events {
+			if event.Seq != seq+1 || event.PrevHash != prevHash || event.Hash != auditHash(event) {
+				return checked, event, nil
+			}
+			seq = event.Seq
+			prevHash = event.Hash
+			checked++
+		}
+	}
+}
+
+// newAuditEvent starts an event about a change actorID made to userID's
+// account from client.
+func newAuditEvent(action, actorID, userID string, client ClientInfo) *models.AuditEvent {
+	return &models.AuditEvent{
+		ID:        uuid.New().String(),
+		Action:    action,
+		ActorID:   actorID,
+		UserID:    userID,
+		IP:        truncate(client.IP, 45),
+		UserAgent: truncate(client.UserAgent, 500),
+	}
+}
+
+// locate fills in where event's IP address is, like login events, so the
+// location is covered by the hash too.
+func (s *AuditService) locate(event *models.AuditEvent) {
+	if event.IP == "" {
+		return
+	}
+	loc := s.geo.Lookup(event.IP)
+	event.Country = loc.Country
+	event.City = truncate(loc.City, 100)
+	event.ASN = loc.ASN
+}
+
+// auditHash returns the hash of event, which covers every field but the
+// hash itself. The location is left out when empty, so events recorded
+// before it was still verify.
+func auditHash(event *models.AuditEvent) string {
+	data, _ := json.Marshal(struct {
+		Seq       int64             `json:"seq"`
+		ID        string            `json:"id"`
+		Time      string            `json:"time"`
+		Action    string            `json:"action"`
+		ActorID   string            `json:"actor_id"`
+		UserID    string            `json:"user_id"`
+		IP        string            `json:"ip"`
+		Country   string            `json:"country,omitempty"`
+		City      string            `json:"city,omitempty"`
+		ASN       uint              `json:"asn,omitempty"`
+		UserAgent string            `json:"user_agent"`
+		Details   map[string]string `json:"details,omitempty"`
+		PrevHash  string            `json:"prev_hash"`
+	}{
+		Seq:       event.Seq,
+		ID:        event.ID,
+		Time:      event.Time.UTC().Format(time.RFC3339Nano),
+		Action:    event.Action,
+		ActorID:   event.ActorID,
+		UserID:    event.UserID,
+		IP:        event.IP,
+		Country:   event.Country,
+		City:      event.City,
+		ASN:       event.ASN,
+		UserAgent: event.UserAgent,
+		Details:   event.Details,
+		PrevHash:  event.PrevHash,
+	})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
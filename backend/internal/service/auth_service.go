@@ -1,11 +1,14 @@
 package service
 
 import (
+	"database/sql"
 	"errors"
+	"log"
 	"time"
 
 	"backend/internal/models"
 	"backend/internal/repository"
+	"backend/internal/textnorm"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -15,86 +18,274 @@ import (
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrInvalidToken       = errors.New("invalid token")
+	ErrReauthRequired     = errors.New("recent authentication required")
+	ErrGuestNotAllowed    = errors.New("guest accounts must be upgraded first")
+
+	ErrAccountPendingDeletion = errors.New("account is scheduled for deletion")
+	ErrAccountNotDeleted      = errors.New("account is not scheduled for deletion")
+)
+
+// Authentication method references (RFC 8176) recorded in the amr claim.
+const (
+	amrPassword = "pwd"
+	amrEmail    = "email"
+	amrSMS      = "sms"
+	amrOTP      = "otp"
 )
 
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	tokenRepo *repository.TokenRepository
-	jwtSecret string
-	jwtExpiry time.Duration
+	userRepo         *repository.UserRepository
+	identityRepo     *repository.IdentityRepository
+	tokenRepo        *repository.TokenRepository
+	deviceRepo       *repository.TrustedDeviceRepository
+	orgRepo          *repository.OrganizationRepository
+	legalRepo        *repository.LegalRepository
+	prefsRepo        *repository.PreferencesRepository
+	history          *LoginHistoryService
+	guard            *RegistrationGuard
+	policy           *RegistrationPolicy
+	audit            *AuditService
+	jwtSecret        string
+	jwtExpiry        time.Duration
+	stepUpMaxAge     time.Duration
+	trustedDeviceTTL time.Duration
+	deletionGrace    time.Duration
 }
 
 func NewAuthService(
 	userRepo *repository.UserRepository,
+	identityRepo *repository.IdentityRepository,
 	tokenRepo *repository.TokenRepository,
+	deviceRepo *repository.TrustedDeviceRepository,
+	orgRepo *repository.OrganizationRepository,
+	legalRepo *repository.LegalRepository,
+	prefsRepo *repository.PreferencesRepository,
+	history *LoginHistoryService,
+	guard *RegistrationGuard,
+	policy *RegistrationPolicy,
+	audit *AuditService,
 	jwtSecret string,
 	jwtExpiry time.Duration,
+	stepUpMaxAge time.Duration,
+	trustedDeviceTTL time.Duration,
+	deletionGrace time.Duration,
 ) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		tokenRepo: tokenRepo,
-		jwtSecret: jwtSecret,
-		jwtExpiry: jwtExpiry,
+		userRepo:         userRepo,
+		identityRepo:     identityRepo,
+		tokenRepo:        tokenRepo,
+		deviceRepo:       deviceRepo,
+		orgRepo:          orgRepo,
+		legalRepo:        legalRepo,
+		prefsRepo:        prefsRepo,
+		history:          history,
+		guard:            guard,
+		policy:           policy,
+		audit:            audit,
+		jwtSecret:        jwtSecret,
+		jwtExpiry:        jwtExpiry,
+		stepUpMaxAge:     stepUpMaxAge,
+		trustedDeviceTTL: trustedDeviceTTL,
+		deletionGrace:    deletionGrace,
 	}
 }
 
-func (s *AuthService) Register(email, password, name string) (*models.User, string, string, error) {
-	// Hash password
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// ClientInfo describes the client a login request comes from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+
+	// Locale is the client's preferred language tag, e.g. "fa-IR". It
+	// becomes the user's preferred locale if they have none yet.
+	Locale string
+
+	// DeviceToken is the trusted-device token the client kept from an
+	// earlier login, if any.
+	DeviceToken string
+
+	// RememberDevice asks for a trusted-device token to be issued after a
+	// successful login. DeviceName labels it in the device list.
+	RememberDevice bool
+	DeviceName     string
+
+	// CancelDeletion lets a login to an account scheduled for deletion go
+	// through and cancel the deletion. Without it such logins fail with
+	// ErrAccountPendingDeletion.
+	CancelDeletion bool
+}
+
+type RegisterInput struct {
+	Email    string
+	Password string
+	Name     string
+
+	// Solution to a challenge from RegistrationGuard.IssueChallenge
+	Challenge string
+	Nonce     string
+
+	// Required when registration is invite-only
+	InvitationCode string
+
+	// Versions of the legal documents the user accepted, by kind. Each
+	// must be the current version.
+	Consents map[string]int
+}
+
+type LoginResult struct {
+	User         *models.User
+	AccessToken  string
+	RefreshToken string
+
+	// TrustedDevice reports whether the login came from a device the user
+	// trusts, so checks that add friction for unknown devices can skip it.
+	TrustedDevice bool
+
+	// DeviceToken is set when a new trusted-device token was issued.
+	DeviceToken string
+}
+
+func (s *AuthService) Register(input RegisterInput, client ClientInfo) (*models.User, string, string, error) {
+	email, err := textnorm.Email(input.Email)
 	if err != nil {
 		return nil, "", "", err
 	}
 
-	// Create user
-	user, err := s.userRepo.Create(email, string(passwordHash), name)
+	name, err := textnorm.Name(input.Name)
 	if err != nil {
 		return nil, "", "", err
 	}
 
-	// Generate tokens
-	accessToken, err := s.generateAccessToken(user.ID)
+	// Check the email domain and consents before spending the challenge
+	if err := s.policy.CheckEmail(email); err != nil {
+		return nil, "", "", err
+	}
+	if _, err := s.consentedDocuments(s.legalRepo, input.Consents, client.Locale); err != nil {
+		return nil, "", "", err
+	}
+
+	// Enforce abuse controls and assess risk
+	riskScore, err := s.guard.Check(client, input.Challenge, input.Nonce)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	// Hash password
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	invitationID, err := s.policy.redeemInvitation(input.InvitationCode, email)
 	if err != nil {
 		return nil, "", "", err
 	}
 
-	refreshToken, err := s.generateRefreshToken(user.ID)
+	// Create user and generate tokens
+	var user *models.User
+	var accessToken, refreshToken string
+	event := newAuditEvent(models.AuditUserRegistered, "", "", client)
+	event.Details = map[string]string{"method": models.LoginMethodPassword}
+	err = s.audit.TrackAll(func(tx *sql.Tx) ([]*models.AuditEvent, error) {
+		var err error
+		user, err = s.userRepo.WithTx(tx).Create(email, string(passwordHash), name, riskScore)
+		if err != nil {
+			return nil, err
+		}
+		if err := linkEmailAndPassword(s.identityRepo.WithTx(tx), user.ID, email); err != nil {
+			return nil, err
+		}
+		event.ActorID, event.UserID = user.ID, user.ID
+
+		consentEvents, err := s.recordConsents(tx, user.ID, input.Consents, client)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.initPreferences(tx, user.ID, client); err != nil {
+			return nil, err
+		}
+
+		accessToken, refreshToken, err = s.issueTokens(tx, user, time.Now(), []string{amrPassword}, "")
+		if err != nil {
+			return nil, err
+		}
+		return append([]*models.AuditEvent{event}, consentEvents...), nil
+	})
 	if err != nil {
+		s.policy.releaseInvitation(invitationID)
 		return nil, "", "", err
 	}
 
+	s.history.Record(user, models.LoginMethodRegister, models.LoginOutcomeSuccess, client, false)
+
 	return user, accessToken, refreshToken, nil
 }
 
-func (s *AuthService) Login(email, password string) (*models.User, string, string, error) {
+func (s *AuthService) IssueRegistrationChallenge(client ClientInfo) (*models.RegistrationChallenge, error) {
+	return s.guard.IssueChallenge(client)
+}
+
+func (s *AuthService) Login(email, password string, client ClientInfo) (*LoginResult, error) {
+	email, err := textnorm.Email(email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
 		if err == repository.ErrUserNotFound {
-			return nil, "", "", ErrInvalidCredentials
+			return nil, ErrInvalidCredentials
 		}
-		return nil, "", "", err
+		return nil, err
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return nil, "", "", ErrInvalidCredentials
+		s.history.Record(user, models.LoginMethodPassword, models.LoginOutcomeInvalidCredentials, client, false)
+		s.audit.Record(auditLoginFailed(user, models.LoginMethodPassword, models.LoginOutcomeInvalidCredentials, client))
+		return nil, ErrInvalidCredentials
 	}
 
-	// Generate tokens
-	accessToken, err := s.generateAccessToken(user.ID)
+	if err := s.checkPendingDeletion(user, models.LoginMethodPassword, client); err != nil {
+		return nil, err
+	}
+
+	result := &LoginResult{User: user}
+
+	// Recognize a device the user already trusts
+	result.TrustedDevice, err = s.checkTrustedDevice(user.ID, client.DeviceToken)
 	if err != nil {
-		return nil, "", "", err
+		return nil, err
 	}
 
-	refreshToken, err := s.generateRefreshToken(user.ID)
+	if client.RememberDevice && !result.TrustedDevice {
+		result.DeviceToken, err = s.trustDevice(user.ID, client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Generate tokens
+	err = s.audit.Track(auditLogin(user, models.LoginMethodPassword, client), func(tx *sql.Tx) error {
+		if err := s.initPreferences(tx, user.ID, client); err != nil {
+			return err
+		}
+
+		var err error
+		result.AccessToken, result.RefreshToken, err = s.issueTokens(tx, user, time.Now(), []string{amrPassword}, "")
+		return err
+	})
 	if err != nil {
-		return nil, "", "", err
+		return nil, err
 	}
 
-	return user, accessToken, refreshToken, nil
+	s.history.Record(user, models.LoginMethodPassword, models.LoginOutcomeSuccess, client, result.TrustedDevice)
+
+	return result, nil
 }
 
-func (s *AuthService) RefreshToken(refreshToken string) (string, string, error) {
+func (s *AuthService) RefreshToken(refreshToken string, client ClientInfo) (string, string, error) {
 	// Verify refresh token exists in database
 	token, err := s.tokenRepo.GetByToken(refreshToken)
 	if err != nil {
@@ -105,22 +296,37 @@ func (s *AuthService) RefreshToken(refreshToken string) (string, string, error)
 		return "", "", ErrInvalidToken
 	}
 
-	// Delete old refresh token
-	if err := s.tokenRepo.DeleteByToken(refreshToken); err != nil {
-		return "", "", err
-	}
-
-	// Generate new tokens
-	accessToken, err := s.generateAccessToken(token.UserID)
+	user, err := s.userRepo.GetByID(token.UserID)
 	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return "", "", ErrInvalidToken
+		}
 		return "", "", err
 	}
+	if user.DeletedAt != nil {
+		return "", "", ErrInvalidToken
+	}
+
+	var accessToken, newRefreshToken string
+	event := newAuditEvent(models.AuditTokenRefreshed, user.ID, user.ID, client)
+	err = s.audit.Track(event, func(tx *sql.Tx) error {
+		// Delete old refresh token
+		if err := s.tokenRepo.WithTx(tx).DeleteByToken(refreshToken); err != nil {
+			return err
+		}
 
-	newRefreshToken, err := s.generateRefreshToken(token.UserID)
+		// Generate new tokens, keeping the time and methods of the original
+		// login and the active organization
+		var err error
+		accessToken, newRefreshToken, err = s.issueTokens(tx, user, token.AuthTime, token.AMR, token.OrgID)
+		return err
+	})
 	if err != nil {
 		return "", "", err
 	}
 
+	s.history.Record(user, models.LoginMethodRefresh, models.LoginOutcomeSuccess, client, false)
+
 	return accessToken, newRefreshToken, nil
 }
 
@@ -128,47 +334,437 @@ func (s *AuthService) GetUserByID(userID string) (*models.User, error) {
 	return s.userRepo.GetByID(userID)
 }
 
+// Identities returns the login methods linked to userID.
+func (s *AuthService) Identities(userID string) ([]models.Identity, error) {
+	return s.identityRepo.List(userID)
+}
+
+// CheckActive fails with ErrAccountPendingDeletion if userID is scheduled
+// for deletion and with repository.ErrUserNotFound if it is gone. Access
+// tokens stay valid until they expire, so requests made with them are
+// checked against the account.
+func (s *AuthService) CheckActive(userID string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt != nil {
+		return ErrAccountPendingDeletion
+	}
+	return nil
+}
+
+func (s *AuthService) IsAdmin(userID string) (bool, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return user.Role == models.RoleAdmin, nil
+}
+
 func (s *AuthService) ListUsers() ([]*models.User, error) {
 	return s.userRepo.List()
 }
 
-func (s *AuthService) UpdateProfile(userID, name string) error {
-	return s.userRepo.Update(userID, name)
+func (s *AuthService) UpdateProfile(userID, name string, client ClientInfo) error {
+	name, err := textnorm.Name(name)
+	if err != nil {
+		return err
+	}
+
+	event := newAuditEvent(models.AuditProfileUpdated, userID, userID, client)
+	event.Details = map[string]string{"fields": "name"}
+	return s.audit.Track(event, func(tx *sql.Tx) error {
+		return s.userRepo.WithTx(tx).Update(userID, name)
+	})
+}
+
+// Reauthenticate checks the user's password and returns a short-lived access
+// token whose auth_time is now, for use with operations that require recent
+// authentication. orgID is the caller's active organization.
+func (s *AuthService) Reauthenticate(userID, password, orgID string) (string, time.Duration, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return "", 0, ErrInvalidCredentials
+		}
+		return "", 0, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return "", 0, ErrInvalidCredentials
+	}
+
+	orgID, err = s.activeOrgID(user.ID, orgID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	consents, err := s.legalRepo.AcceptedVersions(user.ID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	prefs, err := s.prefsRepo.Get(user.ID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	accessToken, err := s.generateAccessToken(user, time.Now(), []string{amrPassword}, orgID, consents, prefs, s.stepUpMaxAge)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return accessToken, s.stepUpMaxAge, nil
+}
+
+// requireRecentAuth fails with ErrReauthRequired unless the access token
+// presented for this request was obtained by a login within stepUpMaxAge.
+func (s *AuthService) requireRecentAuth(authTime time.Time) error {
+	if authTime.IsZero() || time.Since(authTime) > s.stepUpMaxAge {
+		return ErrReauthRequired
+	}
+	return nil
+}
+
+// ChangePassword sets a new password and signs out every other session. It
+// returns a fresh token pair for the caller, in its active organization
+// orgID.
+func (s *AuthService) ChangePassword(userID, newPassword string, authTime time.Time, orgID string, client ClientInfo) (string, string, error) {
+	if err := s.requireRecentAuth(authTime); err != nil {
+		return "", "", err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.Role == models.RoleGuest {
+		return "", "", ErrGuestNotAllowed
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+
+	var accessToken, refreshToken string
+	event := newAuditEvent(models.AuditPasswordChanged, userID, userID, client)
+	err = s.audit.Track(event, func(tx *sql.Tx) error {
+		if err := s.userRepo.WithTx(tx).UpdatePassword(userID, string(passwordHash)); err != nil {
+			return err
+		}
+		// Without an email address the password can't be signed in with
+		if user.Email != "" {
+			if err := s.identityRepo.WithTx(tx).Link(userID, models.IdentityPassword, ""); err != nil {
+				return err
+			}
+		}
+
+		if err := s.tokenRepo.WithTx(tx).DeleteByUserID(userID); err != nil {
+			return err
+		}
+
+		// A new password means every device has to earn trust again
+		if err := s.deviceRepo.WithTx(tx).DeleteByUserID(userID); err != nil {
+			return err
+		}
+
+		var err error
+		accessToken, refreshToken, err = s.issueTokens(tx, user, time.Now(), []string{amrPassword}, orgID)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+func (s *AuthService) ChangeEmail(userID, email string, authTime time.Time, client ClientInfo) error {
+	if err := s.requireRecentAuth(authTime); err != nil {
+		return err
+	}
+
+	email, err := textnorm.Email(email)
+	if err != nil {
+		return err
+	}
+
+	if err := s.policy.CheckEmail(email); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Role == models.RoleGuest {
+		return ErrGuestNotAllowed
+	}
+
+	event := newAuditEvent(models.AuditEmailChanged, userID, userID, client)
+	return s.audit.Track(event, func(tx *sql.Tx) error {
+		if err := s.userRepo.WithTx(tx).UpdateEmail(userID, email, false); err != nil {
+			return err
+		}
+		return s.identityRepo.WithTx(tx).Link(userID, models.IdentityEmail, email)
+	})
+}
+
+// DeleteAccount signs out all sessions and schedules the account for
+// deletion after the grace period, during which logging in with
+// ClientInfo.CancelDeletion or an administrator can restore it. It returns
+// when the account will be purged, or the zero time if it was deleted right
+// away: guests, who have no credentials to log in with again, and every
+// account when the grace period is zero. Guests also skip the recent
+// authentication check.
+func (s *AuthService) DeleteAccount(userID string, authTime time.Time, client ClientInfo) (time.Time, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if user.Role != models.RoleGuest {
+		if err := s.requireRecentAuth(authTime); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	immediate := user.Role == models.RoleGuest || s.deletionGrace == 0
+
+	action := models.AuditDeletionScheduled
+	if immediate {
+		action = models.AuditUserDeleted
+	}
+
+	err = s.audit.Track(newAuditEvent(action, userID, userID, client), func(tx *sql.Tx) error {
+		// Delete all refresh tokens
+		if err := s.tokenRepo.WithTx(tx).DeleteByUserID(userID); err != nil {
+			return err
+		}
+
+		if immediate {
+			return s.userRepo.WithTx(tx).Delete(userID)
+		}
+		return s.userRepo.WithTx(tx).MarkDeleted(userID)
+	})
+	if err != nil || immediate {
+		return time.Time{}, err
+	}
+
+	return time.Now().Add(s.deletionGrace), nil
 }
 
-func (s *AuthService) DeleteAccount(userID string) error {
-	// Delete all refresh tokens
-	if err := s.tokenRepo.DeleteByUserID(userID); err != nil {
+// RestoreAccount cancels the scheduled deletion of userID on behalf of
+// administrator adminID.
+func (s *AuthService) RestoreAccount(adminID, userID string, client ClientInfo) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
 		return err
 	}
 
-	// Delete user
-	return s.userRepo.Delete(userID)
+	if user.DeletedAt == nil {
+		return ErrAccountNotDeleted
+	}
+
+	return s.audit.Track(newAuditEvent(models.AuditUserRestored, adminID, userID, client), func(tx *sql.Tx) error {
+		return s.userRepo.WithTx(tx).Restore(userID)
+	})
+}
+
+// RunPurge permanently deletes accounts whose grace period has run out,
+// checking every interval. It never returns, so run it in its own
+// goroutine.
+func (s *AuthService) RunPurge(interval time.Duration) {
+	for {
+		var purged []string
+		err := s.audit.TrackAll(func(tx *sql.Tx) ([]*models.AuditEvent, error) {
+			var err error
+			purged, err = s.userRepo.WithTx(tx).PurgeDeleted(time.Now().Add(-s.deletionGrace))
+			return systemDeletionEvents(purged, "deletion_grace_period_over"), err
+		})
+		if err != nil {
+			log.Printf("Purge of deleted accounts failed: %v", err)
+		} else if len(purged) > 0 {
+			log.Printf("🗑️  Purged %d deleted account(s)", len(purged))
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// checkPendingDeletion stops a login to user, who has already proven their
+// identity with method, while the account is scheduled for deletion, unless
+// the client asked to cancel the deletion.
+func (s *AuthService) checkPendingDeletion(user *models.User, method string, client ClientInfo) error {
+	if user.DeletedAt == nil {
+		return nil
+	}
+
+	if !client.CancelDeletion {
+		s.history.Record(user, method, models.LoginOutcomePendingDeletion, client, false)
+		s.audit.Record(auditLoginFailed(user, method, models.LoginOutcomePendingDeletion, client))
+		return ErrAccountPendingDeletion
+	}
+
+	event := newAuditEvent(models.AuditUserRestored, user.ID, user.ID, client)
+	event.Details = map[string]string{"method": method}
+	err := s.audit.Track(event, func(tx *sql.Tx) error {
+		return s.userRepo.WithTx(tx).Restore(user.ID)
+	})
+	if err != nil {
+		return err
+	}
+	user.DeletedAt = nil
+
+	return nil
 }
 
 func (s *AuthService) GetUsageStats(userID string) (map[string]int, error) {
 	return s.userRepo.GetUsageStats(userID)
 }
 
-func (s *AuthService) generateAccessToken(userID string) (string, error) {
+// issueTokens creates a new access/refresh token pair for user, storing the
+// refresh token within tx. authTime and amr describe how and when the user
+// last actually authenticated. orgID selects the active organization; when
+// empty or when the user is no longer a member, the organization the user
+// used last is picked.
+func (s *AuthService) issueTokens(tx *sql.Tx, user *models.User, authTime time.Time, amr []string, orgID string) (string, string, error) {
+	orgID, err := s.activeOrgID(user.ID, orgID)
+	if err != nil {
+		return "", "", err
+	}
+
+	consents, err := s.legalRepo.WithTx(tx).AcceptedVersions(user.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	prefs, err := s.prefsRepo.WithTx(tx).Get(user.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, err := s.generateAccessToken(user, authTime, amr, orgID, consents, prefs, s.jwtExpiry)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := s.generateRefreshToken(tx, user.ID, authTime, amr, orgID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// reissueTokens replaces refreshToken, the one user holds, with a new
+// token pair within tx, so the old pair can't be refreshed next to the new
+// one. It fails with ErrInvalidToken if the token isn't user's.
+func (s *AuthService) reissueTokens(tx *sql.Tx, user *models.User, refreshToken string, authTime time.Time, amr []string, orgID string) (string, string, error) {
+	tokens := s.tokenRepo.WithTx(tx)
+	token, err := tokens.GetByToken(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	if token == nil || token.UserID != user.ID {
+		return "", "", ErrInvalidToken
+	}
+
+	if err := tokens.DeleteByToken(refreshToken); err != nil {
+		return "", "", err
+	}
+
+	return s.issueTokens(tx, user, authTime, amr, orgID)
+}
+
+// activeOrgID returns orgID if userID still belongs to it, and otherwise
+// the organization the user used most recently, or "" if there is none.
+func (s *AuthService) activeOrgID(userID, orgID string) (string, error) {
+	if orgID != "" {
+		membership, err := s.orgRepo.GetMembership(orgID, userID)
+		if err != nil {
+			return "", err
+		}
+		if membership != nil {
+			return orgID, nil
+		}
+	}
+
+	return s.orgRepo.DefaultOrgID(userID)
+}
+
+// generateAccessToken signs an access token for user. Guest accounts get a
+// "guest" claim so services can hold back features until they upgrade. The
+// "consents" claim carries the latest version of each legal document the
+// user accepted, which the gateway compares with the required versions.
+// "locale" and "tz" are the user's preferred locale and time zone, for
+// services to localize what they send.
+func (s *AuthService) generateAccessToken(user *models.User, authTime time.Time, amr []string, orgID string, consents map[string]int, prefs *models.Preferences, expiry time.Duration) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(s.jwtExpiry).Unix(),
-		"iat":     time.Now().Unix(),
+		"user_id":   user.ID,
+		"auth_time": authTime.Unix(),
+		"amr":       amr,
+		"locale":    prefs.Locale,
+		"tz":        prefs.TimeZone,
+		"exp":       time.Now().Add(expiry).Unix(),
+		"iat":       time.Now().Unix(),
+	}
+	if orgID != "" {
+		claims["org_id"] = orgID
+	}
+	if user.Role == models.RoleGuest {
+		claims["guest"] = true
+	}
+	if len(consents) > 0 {
+		claims["consents"] = consents
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(s.jwtSecret))
 }
 
-func (s *AuthService) generateRefreshToken(userID string) (string, error) {
+func (s *AuthService) generateRefreshToken(tx *sql.Tx, userID string, authTime time.Time, amr []string, orgID string) (string, error) {
 	tokenString := uuid.New().String()
 	expiresAt := time.Now().Add(30 * 24 * time.Hour) // 30 days
 
-	_, err := s.tokenRepo.Create(userID, tokenString, expiresAt)
+	_, err := s.tokenRepo.WithTx(tx).Create(userID, tokenString, expiresAt, authTime, amr, orgID)
 	if err != nil {
 		return "", err
 	}
 
 	return tokenString, nil
 }
+
+// auditLogin starts the event of a successful login by user with method,
+// one of the models.LoginMethod constants.
+func auditLogin(user *models.User, method string, client ClientInfo) *models.AuditEvent {
+	event := newAuditEvent(models.AuditLoginSucceeded, user.ID, user.ID, client)
+	event.Details = map[string]string{"method": method}
+	return event
+}
+
+// auditLoginFailed returns the event of a login to user that failed with
+// reason, one of the models.LoginOutcome constants.
+func auditLoginFailed(user *models.User, method, reason string, client ClientInfo) *models.AuditEvent {
+	event := newAuditEvent(models.AuditLoginFailed, user.ID, user.ID, client)
+	event.Details = map[string]string{"method": method, "reason": reason}
+	return event
+}
+
+// systemDeletionEvents returns the events of background jobs deleting the
+// accounts userIDs for reason.
+func systemDeletionEvents(userIDs []string, reason string) []*models.AuditEvent {
+	events := make([]*models.AuditEvent, 0, len(userIDs))
+	for _, userID := range userIDs {
+		event := newAuditEvent(models.AuditUserDeleted, models.AuditActorSystem, userID, ClientInfo{})
+		event.Details = map[string]string{"reason": reason}
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"backend/internal/i18n"
+	"backend/internal/models"
+	"backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownLegalDocument = errors.New("unknown legal document")
+	ErrConsentOutdated      = errors.New("a newer version of the legal document was published")
+	ErrNothingToAccept      = errors.New("no legal documents to accept")
+)
+
+// consentedDocuments checks consents, the versions of the legal documents a
+// user accepts by kind, and returns the accepted translations, picked for
+// locale. Only the current version of a document can be accepted.
+func (s *AuthService) consentedDocuments(legalRepo *repository.LegalRepository, consents map[string]int, locale string) ([]*models.LegalDocument, error) {
+	for kind := range consents {
+		if !slices.Contains(models.LegalKinds, kind) {
+			return nil, ErrUnknownLegalDocument
+		}
+	}
+
+	var docs []*models.LegalDocument
+	for _, kind := range models.LegalKinds {
+		version, ok := consents[kind]
+		if !ok {
+			continue
+		}
+
+		translations, err := legalRepo.Current(kind)
+		if err != nil {
+			return nil, err
+		}
+		if len(translations) == 0 {
+			return nil, ErrUnknownLegalDocument
+		}
+		if version != translations[0].Version {
+			return nil, ErrConsentOutdated
+		}
+		docs = append(docs, pickTranslation(translations, locale))
+	}
+
+	return docs, nil
+}
+
+// recordConsents stores within tx that userID accepted consents from client
+// and returns the events to audit them with.
+func (s *AuthService) recordConsents(tx *sql.Tx, userID string, consents map[string]int, client ClientInfo) ([]*models.AuditEvent, error) {
+	legalRepo := s.legalRepo.WithTx(tx)
+
+	docs, err := s.consentedDocuments(legalRepo, consents, client.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	events := make([]*models.AuditEvent, 0, len(docs))
+	for _, doc := range docs {
+		consent := &models.Consent{
+			ID:         uuid.New().String(),
+			UserID:     userID,
+			Kind:       doc.Kind,
+			Version:    doc.Version,
+			Locale:     doc.Locale,
+			IP:         truncate(client.IP, 45),
+			UserAgent:  truncate(client.UserAgent, 500),
+			AcceptedAt: now,
+		}
+		if err := legalRepo.CreateConsent(consent); err != nil {
+			return nil, err
+		}
+
+		event := newAuditEvent(models.AuditConsentAccepted, userID, userID, client)
+		event.Details = map[string]string{
+			"kind":    doc.Kind,
+			"version": strconv.Itoa(doc.Version),
+			"locale":  doc.Locale,
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// pickTranslation returns the translation in translations that best
+// matches locale, falling back to English and then to any translation.
+func pickTranslation(translations []*models.LegalDocument, locale string) *models.LegalDocument {
+	var fallback *models.LegalDocument
+	for _, doc := range translations {
+		if doc.Locale == i18n.Match(locale) {
+			return doc
+		}
+		if doc.Locale == i18n.Default {
+			fallback = doc
+		}
+	}
+
+	if fallback == nil {
+		fallback = translations[0]
+	}
+	return fallback
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"time"
+
+	"backend/internal/datefmt"
+	"backend/internal/i18n"
+	"backend/internal/mailer"
+	"backend/internal/mailtmpl"
+	"backend/internal/repository"
+)
+
+// Emailer sends the emails of internal/mailtmpl in the language and time
+// zone of their recipient.
+type Emailer struct {
+	mailer    mailer.Mailer
+	templates *mailtmpl.Templates
+	prefsRepo *repository.PreferencesRepository
+}
+
+func NewEmailer(mailer mailer.Mailer, templates *mailtmpl.Templates, prefsRepo *repository.PreferencesRepository) *Emailer {
+	return &Emailer{
+		mailer:    mailer,
+		templates: templates,
+		prefsRepo: prefsRepo,
+	}
+}
+
+// Recipient returns the language userID reads email in, their preferred
+// locale, and a formatter for times in the email, in their time zone.
+func (e *Emailer) Recipient(userID string) (string, *datefmt.Formatter, error) {
+	prefs, err := e.prefsRepo.Get(userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	locale := i18n.Match(prefs.Locale)
+	location, err := time.LoadLocation(prefs.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	return locale, datefmt.New(locale, "", location), nil
+}
+
+// Send renders email name in locale with data, the email's data type, and
+// sends it to to.
+func (e *Emailer) Send(to, name, locale string, data interface{}) error {
+	email, err := e.templates.Render(name, locale, data)
+	if err != nil {
+		return err
+	}
+
+	return e.mailer.Send(&mailer.Message{
+		To:      to,
+		Subject: email.Subject,
+		Text:    email.Text,
+		HTML:    email.HTML,
+	})
+}
+
+// Templates returns the names of the emails and the languages each is
+// translated into.
+func (e *Emailer) Templates() map[string][]string {
+	templates := make(map[string][]string)
+	for _, name := range e.templates.Names() {
+		templates[name] = e.templates.Locales(name)
+	}
+	return templates
+}
+
+// Preview renders email name in locale with sample data, for admins to
+// check translations.
+func (e *Emailer) Preview(name, locale string) (*mailtmpl.Email, error) {
+	if i18n.Supported(locale) != locale {
+		return nil, ErrUnsupportedLocale
+	}
+	return e.templates.Preview(name, locale)
+}
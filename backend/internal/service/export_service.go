@@ -0,0 +1,375 @@
+package service
+
+import (
+	"archive/zip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/i18n"
+	"backend/internal/mailtmpl"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/internal/sms"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrExportRateLimited = errors.New("a data export was requested recently")
+	ErrExportNotReady    = errors.New("data export is not ready")
+	ErrInvalidExportLink = errors.New("invalid or expired download link")
+)
+
+// ExportOptions configures data exports.
+type ExportOptions struct {
+	// Directory the archives are written to
+	Dir string
+
+	// Public URL the download links start with; the export ID and
+	// "/download" are appended
+	DownloadURL string
+
+	// How long an archive is kept once built
+	TTL time.Duration
+
+	// How long a signed download link works
+	LinkTTL time.Duration
+
+	// Minimum time between two exports of one user
+	Cooldown time.Duration
+}
+
+// ExportService gives users a copy of their personal data, as the GDPR and
+// KVKK require. Requests are queued and built in the background into a ZIP
+// archive of JSON documents; the user is notified when it is ready and
+// downloads it through a short-lived signed link. Archives are deleted
+// when they expire.
+type ExportService struct {
+	exportRepo *repository.DataExportRepository
+	userRepo   *repository.UserRepository
+	emails     *Emailer
+	sms        sms.SMSSender
+	signingKey []byte
+	options    ExportOptions
+
+	// Wakes the worker when an export is queued
+	queued chan struct{}
+}
+
+func NewExportService(
+	exportRepo *repository.DataExportRepository,
+	userRepo *repository.UserRepository,
+	emails *Emailer,
+	smsSender sms.SMSSender,
+	signingKey string,
+	options ExportOptions,
+) *ExportService {
+	// Links are signed with a key derived from the secret, so a signature
+	// made for something else under the same secret never passes as a link
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte("export-link"))
+
+	return &ExportService{
+		exportRepo: exportRepo,
+		userRepo:   userRepo,
+		emails:     emails,
+		sms:        smsSender,
+		signingKey: mac.Sum(nil),
+		options:    options,
+		queued:     make(chan struct{}, 1),
+	}
+}
+
+// Request queues an export of userID's data. One export may be requested
+// per cooldown period.
+func (s *ExportService) Request(userID string) (*models.DataExport, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+
+	count, err := s.exportRepo.CountSince(userID, time.Now().Add(-s.options.Cooldown))
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrExportRateLimited
+	}
+
+	export := &models.DataExport{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    models.DataExportPending,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.exportRepo.Create(export); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.queued <- struct{}{}:
+	default:
+	}
+
+	return export, nil
+}
+
+func (s *ExportService) List(userID string) ([]*models.DataExport, error) {
+	return s.exportRepo.ListByUserID(userID)
+}
+
+// Get returns export id of userID.
+func (s *ExportService) Get(userID, id string) (*models.DataExport, error) {
+	export, err := s.exportRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Other users' exports don't exist as far as the caller is concerned
+	if export.UserID != userID {
+		return nil, repository.ErrDataExportNotFound
+	}
+
+	return export, nil
+}
+
+// DownloadLink returns a signed link to the archive of a ready export and
+// when the link stops working.
+func (s *ExportService) DownloadLink(export *models.DataExport) (string, time.Time, error) {
+	if export.Status != models.DataExportReady {
+		return "", time.Time{}, ErrExportNotReady
+	}
+
+	expires := time.Now().Add(s.options.LinkTTL)
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(expires) {
+		expires = *export.ExpiresAt
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", s.sign(export.ID, expires.Unix()))
+
+	link := strings.TrimSuffix(s.options.DownloadURL, "/") + "/" + export.ID + "/download?" + query.Encode()
+	return link, expires, nil
+}
+
+// Open checks a download link and opens the archive it points to. The
+// caller closes the file.
+func (s *ExportService) Open(id, expires, signature string) (*models.DataExport, *os.File, error) {
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresUnix {
+		return nil, nil, ErrInvalidExportLink
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(id, expiresUnix))) {
+		return nil, nil, ErrInvalidExportLink
+	}
+
+	export, err := s.exportRepo.GetByID(id)
+	if err != nil {
+		if err == repository.ErrDataExportNotFound {
+			return nil, nil, ErrInvalidExportLink
+		}
+		return nil, nil, err
+	}
+	if export.Status != models.DataExportReady {
+		return nil, nil, ErrInvalidExportLink
+	}
+
+	file, err := os.Open(export.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return export, file, nil
+}
+
+// Run builds queued exports as they come in, and checks for exports queued
+// by other instances every interval. It never returns, so run it in its own
+// goroutine.
+func (s *ExportService) Run(interval time.Duration) {
+	if err := os.MkdirAll(s.options.Dir, 0o700); err != nil {
+		log.Printf("Data export directory %s is not usable: %v", s.options.Dir, err)
+	}
+
+	if err := s.exportRepo.RequeueProcessing(); err != nil {
+		log.Printf("Requeueing data exports failed: %v", err)
+	}
+
+	for {
+		for {
+			export, err := s.exportRepo.ClaimPending()
+			if err != nil {
+				log.Printf("Claiming a data export failed: %v", err)
+				break
+			}
+			if export == nil {
+				break
+			}
+			s.process(export)
+		}
+
+		select {
+		case <-s.queued:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RunCleanup deletes the archives of expired exports, checking every
+// interval. It never returns, so run it in its own goroutine.
+func (s *ExportService) RunCleanup(interval time.Duration) {
+	for {
+		s.cleanup()
+		time.Sleep(interval)
+	}
+}
+
+func (s *ExportService) cleanup() {
+	exports, err := s.exportRepo.ListExpired(time.Now())
+	if err != nil {
+		log.Printf("Data export cleanup failed: %v", err)
+		return
+	}
+
+	for _, export := range exports {
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("Deleting data export %s failed: %v", export.ID, err)
+			continue
+		}
+		if err := s.exportRepo.MarkExpired(export.ID); err != nil {
+			log.Printf("Expiring data export %s failed: %v", export.ID, err)
+		}
+	}
+
+	// Exports of deleted accounts disappear with them, their archives
+	// don't
+	entries, err := os.ReadDir(s.options.Dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".zip")
+		if !ok {
+			continue
+		}
+		if _, err := s.exportRepo.GetByID(id); err == repository.ErrDataExportNotFound {
+			os.Remove(filepath.Join(s.options.Dir, entry.Name()))
+		}
+	}
+}
+
+func (s *ExportService) process(export *models.DataExport) {
+	path, size, err := s.build(export)
+	if err != nil {
+		log.Printf("Building data export %s failed: %v", export.ID, err)
+		if err := s.exportRepo.MarkFailed(export.ID); err != nil {
+			log.Printf("Marking data export %s failed: %v", export.ID, err)
+		}
+		return
+	}
+
+	expiresAt := time.Now().Add(s.options.TTL)
+	if err := s.exportRepo.MarkReady(export.ID, path, size, expiresAt); err != nil {
+		log.Printf("Marking data export %s ready failed: %v", export.ID, err)
+		os.Remove(path)
+		return
+	}
+
+	s.notify(export.UserID, expiresAt)
+}
+
+// build writes the archive of export and returns its path and size. The
+// archive is written under a temporary name so a half-written file is
+// never served.
+func (s *ExportService) build(export *models.DataExport) (string, int64, error) {
+	sets, err := s.exportRepo.PersonalData(export.UserID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	path := filepath.Join(s.options.Dir, export.ID+".zip")
+	tmp, err := os.CreateTemp(s.options.Dir, export.ID+"-*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	archive := zip.NewWriter(tmp)
+	for _, set := range sets {
+		w, err := archive.Create(set.Name + ".json")
+		if err != nil {
+			tmp.Close()
+			return "", 0, err
+		}
+		if _, err := w.Write(set.JSON); err != nil {
+			tmp.Close()
+			return "", 0, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+
+	return path, info.Size(), nil
+}
+
+// notify tells the user their export can be downloaded, by email or else
+// by SMS. Guests have neither and check the app instead.
+func (s *ExportService) notify(userID string, expiresAt time.Time) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		log.Printf("Loading user %s for a data export notification failed: %v", userID, err)
+		return
+	}
+
+	locale, dates, err := s.emails.Recipient(userID)
+	if err != nil {
+		log.Printf("Loading the preferences of user %s for a data export notification failed: %v", userID, err)
+		return
+	}
+
+	switch {
+	case user.Email != "":
+		err = s.emails.Send(user.Email, mailtmpl.ExportReady, locale, mailtmpl.ExportReadyData{
+			Name:    user.Name,
+			Expires: dates.Format(expiresAt),
+		})
+	case user.Phone != "":
+		err = s.sms.Send(user.Phone, i18n.Message(locale, "export_ready_sms", dates.Format(expiresAt)))
+	}
+	if err != nil {
+		log.Printf("Sending the data export notification to user %s failed: %v", userID, err)
+	}
+}
+
+// sign returns the signature of a download link for export id that works
+// until expires.
+func (s *ExportService) sign(id string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(id + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/internal/textnorm"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrGuestAccessDisabled = errors.New("guest access is disabled")
+	ErrNotGuest            = errors.New("account is not a guest account")
+)
+
+// GuestService lets people try the app before signing up. A guest account
+// has no credentials; upgrading it attaches an email address and password
+// or a phone number while keeping the user ID, and with it everything the
+// guest created. Guest accounts that go unused are deleted by the retention
+// job.
+type GuestService struct {
+	auth     *AuthService
+	phone    *PhoneOTPService
+	userRepo *repository.UserRepository
+	enabled  bool
+}
+
+func NewGuestService(
+	auth *AuthService,
+	phone *PhoneOTPService,
+	userRepo *repository.UserRepository,
+	enabled bool,
+) *GuestService {
+	return &GuestService{
+		auth:     auth,
+		phone:    phone,
+		userRepo: userRepo,
+		enabled:  enabled,
+	}
+}
+
+// UpgradeInput holds the credentials that turn a guest into a full account.
+type UpgradeInput struct {
+	Email    string
+	Password string
+
+	// Optional if the guest already set a name with UpdateProfile
+	Name string
+
+	// Required when registration is invite-only
+	InvitationCode string
+}
+
+// SignIn creates a guest account and logs it in. Like a registration it has
+// to pass the proof-of-work challenge and velocity limits.
+func (s *GuestService) SignIn(challenge, nonce string, client ClientInfo) (*models.User, string, string, error) {
+	if !s.enabled {
+		return nil, "", "", ErrGuestAccessDisabled
+	}
+
+	riskScore, err := s.auth.guard.Check(client, challenge, nonce)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var user *models.User
+	var accessToken, refreshToken string
+	event := newAuditEvent(models.AuditGuestCreated, "", "", client)
+	err = s.auth.audit.Track(event, func(tx *sql.Tx) error {
+		var err error
+		user, err = s.userRepo.WithTx(tx).CreateGuest(riskScore)
+		if err != nil {
+			return err
+		}
+		event.ActorID, event.UserID = user.ID, user.ID
+
+		// A guest hasn't authenticated with anything, so amr stays empty
+		accessToken, refreshToken, err = s.auth.issueTokens(tx, user, time.Now(), []string{}, "")
+		return err
+	})
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	s.auth.history.Record(user, models.LoginMethodRegister, models.LoginOutcomeSuccess, client, false)
+
+	return user, accessToken, refreshToken, nil
+}
+
+// Upgrade gives guest userID an email address and password. The email
+// domain policy and invitation requirement apply as for Register.
+func (s *GuestService) Upgrade(userID string, input UpgradeInput, client ClientInfo) (*models.User, string, string, error) {
+	user, err := s.guest(userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	email, err := textnorm.Email(input.Email)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	name, err := upgradeName(user, input.Name)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if err := s.auth.policy.CheckEmail(email); err != nil {
+		return nil, "", "", err
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	invitationID, err := s.auth.policy.redeemInvitation(input.InvitationCode, email)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	upgraded := *user
+	upgraded.Email = email
+	upgraded.Name = name
+	upgraded.Role = models.RoleUser
+
+	var accessToken, refreshToken string
+	event := newAuditEvent(models.AuditGuestUpgraded, user.ID, user.ID, client)
+	event.Details = map[string]string{"method": models.IdentityPassword}
+	err = s.auth.audit.Track(event, func(tx *sql.Tx) error {
+		if err := s.userRepo.WithTx(tx).UpgradeGuest(user.ID, email, string(passwordHash), name); err != nil {
+			return err
+		}
+		if err := linkEmailAndPassword(s.auth.identityRepo.WithTx(tx), user.ID, email); err != nil {
+			return err
+		}
+
+		var err error
+		accessToken, refreshToken, err = s.auth.issueTokens(tx, &upgraded, time.Now(), []string{amrPassword}, "")
+		return err
+	})
+	if err != nil {
+		s.auth.policy.releaseInvitation(invitationID)
+		return nil, "", "", err
+	}
+
+	return &upgraded, accessToken, refreshToken, nil
+}
+
+// UpgradeWithPhone gives guest userID a phone number to sign in with, once
+// code proves the guest received an SMS sent to it with
+// PhoneOTPService.SendCode.
+func (s *GuestService) UpgradeWithPhone(userID, phone, code, name, invitationCode string, client ClientInfo) (*models.User, string, string, error) {
+	user, err := s.guest(userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	phone, err = NormalizePhone(phone)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	otp, err := s.phone.checkCode(phone, code)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	// Leave the code usable so the client can retry with what's missing
+	existing, err := s.userRepo.GetByPhone(phone)
+	if err != nil && err != repository.ErrUserNotFound {
+		return nil, "", "", err
+	}
+	if existing != nil {
+		return nil, "", "", repository.ErrPhoneAlreadyExists
+	}
+
+	name, err = upgradeName(user, name)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	invitationID, err := s.auth.policy.redeemInvitation(invitationCode, "")
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	ok, err := s.phone.otpRepo.MarkUsed(otp.ID)
+	if err != nil {
+		s.auth.policy.releaseInvitation(invitationID)
+		return nil, "", "", err
+	}
+	if !ok {
+		s.auth.policy.releaseInvitation(invitationID)
+		return nil, "", "", ErrInvalidOTP
+	}
+
+	upgraded := *user
+	upgraded.Phone = phone
+	upgraded.Name = name
+	upgraded.Role = models.RoleUser
+
+	var accessToken, refreshToken string
+	event := newAuditEvent(models.AuditGuestUpgraded, user.ID, user.ID, client)
+	event.Details = map[string]string{"method": models.IdentityPhone}
+	err = s.auth.audit.Track(event, func(tx *sql.Tx) error {
+		if err := s.userRepo.WithTx(tx).UpgradeGuestWithPhone(user.ID, phone, name); err != nil {
+			return err
+		}
+		if err := s.auth.identityRepo.WithTx(tx).Link(user.ID, models.IdentityPhone, phone); err != nil {
+			return err
+		}
+
+		var err error
+		accessToken, refreshToken, err = s.auth.issueTokens(tx, &upgraded, time.Now(), []string{amrSMS, amrOTP}, "")
+		return err
+	})
+	if err != nil {
+		s.auth.policy.releaseInvitation(invitationID)
+		return nil, "", "", err
+	}
+
+	return &upgraded, accessToken, refreshToken, nil
+}
+
+func (s *GuestService) guest(userID string) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role != models.RoleGuest {
+		return nil, ErrNotGuest
+	}
+
+	return user, nil
+}
+
+// upgradeName returns the name an upgraded guest account gets: name if
+// given, otherwise the one the guest already set.
+func upgradeName(user *models.User, name string) (string, error) {
+	if strings.TrimSpace(name) == "" && user.Name != "" {
+		return user.Name, nil
+	}
+	return textnorm.Name(name)
+}
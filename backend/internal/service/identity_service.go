@@ -0,0 +1,398 @@
+package service
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"backend/internal/mailtmpl"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/internal/textnorm"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrIdentityAlreadyLinked   = errors.New("login method is already linked")
+	ErrIdentityNotLinked       = errors.New("login method is not linked")
+	ErrPasswordNeedsEmail      = errors.New("an email address is needed to sign in with a password")
+	ErrVerificationRateLimited = errors.New("too many verification codes requested")
+	ErrInvalidVerificationCode = errors.New("invalid or expired verification code")
+)
+
+const (
+	// At most emailVerificationMaxPerWindow codes may be requested by one
+	// user within emailVerificationWindow.
+	emailVerificationMaxPerWindow = 3
+	emailVerificationWindow       = 15 * time.Minute
+
+	// A code is burned after this many wrong guesses.
+	emailVerificationMaxAttempts = 5
+)
+
+// IdentityService links and unlinks the login methods of an account, stored
+// as rows of user_identities: an email address (magic link), a password
+// that goes with it, and a phone number (SMS code). Linking an email address or phone number needs a code
+// sent to it. Every change needs recent authentication, and the account
+// always keeps an email address or phone number to sign in with.
+type IdentityService struct {
+	auth             *AuthService
+	phone            *PhoneOTPService
+	userRepo         *repository.UserRepository
+	verificationRepo *repository.EmailVerificationRepository
+	linkRepo         *repository.MagicLinkRepository
+	emails           *Emailer
+	ttl              time.Duration
+}
+
+func NewIdentityService(
+	auth *AuthService,
+	phone *PhoneOTPService,
+	userRepo *repository.UserRepository,
+	verificationRepo *repository.EmailVerificationRepository,
+	linkRepo *repository.MagicLinkRepository,
+	emails *Emailer,
+	ttl time.Duration,
+) *IdentityService {
+	return &IdentityService{
+		auth:             auth,
+		phone:            phone,
+		userRepo:         userRepo,
+		verificationRepo: verificationRepo,
+		linkRepo:         linkRepo,
+		emails:           emails,
+		ttl:              ttl,
+	}
+}
+
+// List returns the login methods linked to userID.
+func (s *IdentityService) List(userID string) ([]models.Identity, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+
+	return s.auth.identityRepo.List(userID)
+}
+
+// RequestEmailLink emails a code to prove userID controls email, for
+// LinkEmail.
+func (s *IdentityService) RequestEmailLink(userID, email string, authTime time.Time) error {
+	user, err := s.linkableUser(userID, authTime)
+	if err != nil {
+		return err
+	}
+	if err := s.requireUnlinked(user.ID, models.IdentityEmail); err != nil {
+		return err
+	}
+
+	email, err = textnorm.Email(email)
+	if err != nil {
+		return err
+	}
+
+	if err := s.auth.policy.CheckEmail(email); err != nil {
+		return err
+	}
+
+	count, err := s.verificationRepo.CountSince(user.ID, time.Now().Add(-emailVerificationWindow))
+	if err != nil {
+		return err
+	}
+	if count >= emailVerificationMaxPerWindow {
+		return ErrVerificationRateLimited
+	}
+
+	// Only the newest code is valid
+	if err := s.verificationRepo.InvalidateByUserID(user.ID); err != nil {
+		return err
+	}
+
+	code, err := randomCode()
+	if err != nil {
+		return err
+	}
+
+	verification := &models.EmailVerification{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(s.ttl),
+		CreatedAt: time.Now(),
+	}
+	verification.CodeHash = hashCode(verification.ID, code)
+
+	if err := s.verificationRepo.Create(verification); err != nil {
+		return err
+	}
+
+	locale, _, err := s.emails.Recipient(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.emails.Send(email, mailtmpl.EmailVerification, locale, mailtmpl.EmailVerificationData{
+		Name:    user.Name,
+		Code:    code,
+		Minutes: int(s.ttl.Minutes()),
+	})
+}
+
+// LinkEmail adds email to userID's account once code from RequestEmailLink
+// checks out.
+func (s *IdentityService) LinkEmail(userID, email, code string, authTime time.Time, client ClientInfo) error {
+	user, err := s.linkableUser(userID, authTime)
+	if err != nil {
+		return err
+	}
+	if err := s.requireUnlinked(user.ID, models.IdentityEmail); err != nil {
+		return err
+	}
+
+	email, err = textnorm.Email(email)
+	if err != nil {
+		return ErrInvalidVerificationCode
+	}
+
+	verification, err := s.verificationRepo.GetActive(user.ID, email)
+	if err != nil {
+		return err
+	}
+	if verification == nil {
+		return ErrInvalidVerificationCode
+	}
+
+	code = strings.TrimSpace(normalizeDigits(code))
+	expected := []byte(verification.CodeHash)
+	actual := []byte(hashCode(verification.ID, code))
+	if subtle.ConstantTimeCompare(expected, actual) != 1 {
+		attempts, err := s.verificationRepo.IncrementAttempts(verification.ID)
+		if err != nil {
+			return err
+		}
+		if attempts >= emailVerificationMaxAttempts {
+			if err := s.verificationRepo.InvalidateByUserID(user.ID); err != nil {
+				return err
+			}
+		}
+		return ErrInvalidVerificationCode
+	}
+
+	// Leave the code usable, the address may be freed up
+	existing, err := s.userRepo.GetByEmail(email)
+	if err != nil && err != repository.ErrUserNotFound {
+		return err
+	}
+	if existing != nil {
+		return repository.ErrEmailAlreadyExists
+	}
+
+	ok, err := s.verificationRepo.MarkUsed(verification.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidVerificationCode
+	}
+
+	return s.track(user.ID, models.AuditIdentityLinked, models.IdentityEmail, client, func(tx *sql.Tx) error {
+		if err := s.userRepo.WithTx(tx).UpdateEmail(user.ID, email, true); err != nil {
+			return err
+		}
+		return s.auth.identityRepo.WithTx(tx).Link(user.ID, models.IdentityEmail, email)
+	})
+}
+
+// LinkPhone adds phone to userID's account. code proves control of the
+// number and is requested with PhoneOTPService.SendCode.
+func (s *IdentityService) LinkPhone(userID, phone, code string, authTime time.Time, client ClientInfo) error {
+	user, err := s.linkableUser(userID, authTime)
+	if err != nil {
+		return err
+	}
+	if err := s.requireUnlinked(user.ID, models.IdentityPhone); err != nil {
+		return err
+	}
+
+	phone, err = NormalizePhone(phone)
+	if err != nil {
+		return err
+	}
+
+	otp, err := s.phone.checkCode(phone, code)
+	if err != nil {
+		return err
+	}
+
+	// Leave the code usable, the number may be freed up
+	existing, err := s.userRepo.GetByPhone(phone)
+	if err != nil && err != repository.ErrUserNotFound {
+		return err
+	}
+	if existing != nil {
+		return repository.ErrPhoneAlreadyExists
+	}
+
+	ok, err := s.phone.otpRepo.MarkUsed(otp.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidOTP
+	}
+
+	return s.track(user.ID, models.AuditIdentityLinked, models.IdentityPhone, client, func(tx *sql.Tx) error {
+		if err := s.userRepo.WithTx(tx).UpdatePhone(user.ID, phone); err != nil {
+			return err
+		}
+		return s.auth.identityRepo.WithTx(tx).Link(user.ID, models.IdentityPhone, phone)
+	})
+}
+
+// LinkPassword lets userID sign in with password as well. The account needs
+// an email address to sign in with it; an existing password is changed with
+// AuthService.ChangePassword instead.
+func (s *IdentityService) LinkPassword(userID, password string, authTime time.Time, client ClientInfo) error {
+	user, err := s.linkableUser(userID, authTime)
+	if err != nil {
+		return err
+	}
+	linked, err := s.linkedMethods(user.ID)
+	if err != nil {
+		return err
+	}
+	if linked[models.IdentityPassword] {
+		return ErrIdentityAlreadyLinked
+	}
+	if !linked[models.IdentityEmail] {
+		return ErrPasswordNeedsEmail
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.track(user.ID, models.AuditIdentityLinked, models.IdentityPassword, client, func(tx *sql.Tx) error {
+		if err := s.userRepo.WithTx(tx).UpdatePassword(user.ID, string(passwordHash)); err != nil {
+			return err
+		}
+		return s.auth.identityRepo.WithTx(tx).Link(user.ID, models.IdentityPassword, "")
+	})
+}
+
+// Unlink removes a login method from userID's account. Removing the email
+// address removes the password with it. It fails with
+// repository.ErrLastIdentity if the account would be left without a method
+// to sign in with on its own, which a password isn't.
+func (s *IdentityService) Unlink(userID, method string, authTime time.Time, client ClientInfo) error {
+	user, err := s.linkableUser(userID, authTime)
+	if err != nil {
+		return err
+	}
+	linked, err := s.linkedMethods(user.ID)
+	if err != nil {
+		return err
+	}
+	if !linked[method] {
+		return ErrIdentityNotLinked
+	}
+
+	err = s.track(user.ID, models.AuditIdentityUnlinked, method, client, func(tx *sql.Tx) error {
+		users, identities := s.userRepo.WithTx(tx), s.auth.identityRepo.WithTx(tx)
+		switch method {
+		case models.IdentityEmail:
+			if err := identities.Unlink(user.ID, models.IdentityEmail, models.IdentityPassword); err != nil {
+				return err
+			}
+			return users.UnlinkEmail(user.ID)
+		case models.IdentityPhone:
+			if err := identities.Unlink(user.ID, models.IdentityPhone); err != nil {
+				return err
+			}
+			return users.UnlinkPhone(user.ID)
+		case models.IdentityPassword:
+			if err := identities.Unlink(user.ID, models.IdentityPassword); err != nil {
+				return err
+			}
+			return users.UpdatePassword(user.ID, "")
+		default:
+			return identities.Unlink(user.ID, method)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if method == models.IdentityEmail {
+		// Outstanding login links must not work for the old address
+		return s.linkRepo.InvalidateByEmail(user.Email)
+	}
+	return nil
+}
+
+// linkableUser loads userID for a change to its login methods, which needs
+// recent authentication. Guests add their first login method by upgrading.
+func (s *IdentityService) linkableUser(userID string, authTime time.Time) (*models.User, error) {
+	if err := s.auth.requireRecentAuth(authTime); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role == models.RoleGuest {
+		return nil, ErrGuestNotAllowed
+	}
+
+	return user, nil
+}
+
+// linkedMethods returns the login methods linked to userID.
+func (s *IdentityService) linkedMethods(userID string) (map[string]bool, error) {
+	identities, err := s.auth.identityRepo.List(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := make(map[string]bool, len(identities))
+	for _, identity := range identities {
+		linked[identity.Method] = true
+	}
+	return linked, nil
+}
+
+// requireUnlinked fails with ErrIdentityAlreadyLinked if method is linked
+// to userID.
+func (s *IdentityService) requireUnlinked(userID, method string) error {
+	linked, err := s.linkedMethods(userID)
+	if err != nil {
+		return err
+	}
+	if linked[method] {
+		return ErrIdentityAlreadyLinked
+	}
+	return nil
+}
+
+// track makes change to userID's login methods within a transaction, and
+// records it in the audit log as action.
+func (s *IdentityService) track(userID, action, method string, client ClientInfo, change func(tx *sql.Tx) error) error {
+	event := newAuditEvent(action, userID, userID, client)
+	event.Details = map[string]string{"method": method}
+	return s.auth.audit.Track(event, change)
+}
+
+// linkEmailAndPassword links the email address and password a new account
+// signs in with.
+func linkEmailAndPassword(identities *repository.IdentityRepository, userID, email string) error {
+	if err := identities.Link(userID, models.IdentityEmail, email); err != nil {
+		return err
+	}
+	return identities.Link(userID, models.IdentityPassword, "")
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"backend/internal/repository"
+)
+
+// KeyRotationService re-encrypts personal data and queued emails with the
+// active field encryption key, so older keys can be retired once it caught
+// up. It also encrypts data stored before encryption was turned on.
+type KeyRotationService struct {
+	userRepo      *repository.UserRepository
+	identityRepo  *repository.IdentityRepository
+	mailQueueRepo *repository.MailQueueRepository
+	batchSize     int
+}
+
+func NewKeyRotationService(
+	userRepo *repository.UserRepository,
+	identityRepo *repository.IdentityRepository,
+	mailQueueRepo *repository.MailQueueRepository,
+	batchSize int,
+) *KeyRotationService {
+	return &KeyRotationService{
+		userRepo:      userRepo,
+		identityRepo:  identityRepo,
+		mailQueueRepo: mailQueueRepo,
+		batchSize:     max(batchSize, 1),
+	}
+}
+
+// Run re-encrypts everything that isn't encrypted with the active key yet,
+// batch by batch, and checks again every interval. It never returns, so run
+// it in its own goroutine.
+func (s *KeyRotationService) Run(interval time.Duration) {
+	for {
+		if total := s.reencrypt("personal data", s.userRepo.ReencryptBatch); total > 0 {
+			log.Printf("🔐 Re-encrypted personal data of %d user(s)", total)
+		}
+		if total := s.reencrypt("login methods", s.identityRepo.ReencryptBatch); total > 0 {
+			log.Printf("🔐 Re-encrypted %d login method(s)", total)
+		}
+		if total := s.reencrypt("queued emails", s.mailQueueRepo.ReencryptBatch); total > 0 {
+			log.Printf("🔐 Re-encrypted %d queued email(s)", total)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// reencrypt runs batch until it catches up and returns how many rows it
+// went through.
+func (s *KeyRotationService) reencrypt(what string, batch func(limit int) (int, error)) int {
+	total := 0
+	for {
+		n, err := batch(s.batchSize)
+		if err != nil {
+			log.Printf("Re-encrypting %s failed: %v", what, err)
+			return total
+		}
+		total += n
+		if n < s.batchSize {
+			return total
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/i18n"
+	"backend/internal/models"
+	"backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnsupportedLocale       = errors.New("unsupported locale")
+	ErrLegalEnglishRequired    = errors.New("an English translation is required")
+	ErrLegalDocumentIncomplete = errors.New("every translation needs a title and a body")
+)
+
+// LegalTranslation is the text of a legal document in one language.
+type LegalTranslation struct {
+	Title string
+	Body  string
+}
+
+// LegalService publishes versions of the terms of service and the privacy
+// policy and records which of them users accepted. Accepting returns fresh
+// tokens whose "consents" claim the gateway checks against the required
+// versions.
+type LegalService struct {
+	auth      *AuthService
+	legalRepo *repository.LegalRepository
+	userRepo  *repository.UserRepository
+}
+
+func NewLegalService(auth *AuthService, legalRepo *repository.LegalRepository, userRepo *repository.UserRepository) *LegalService {
+	return &LegalService{
+		auth:      auth,
+		legalRepo: legalRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// Documents returns the current version of each kind of document in the
+// language closest to locale. Kinds nothing was published for are left out.
+func (s *LegalService) Documents(locale string) ([]*models.LegalDocument, error) {
+	docs := []*models.LegalDocument{}
+	for _, kind := range models.LegalKinds {
+		translations, err := s.legalRepo.Current(kind)
+		if err != nil {
+			return nil, err
+		}
+		if len(translations) > 0 {
+			docs = append(docs, pickTranslation(translations, locale))
+		}
+	}
+
+	return docs, nil
+}
+
+// Versions returns the current and the required version of each kind of
+// document. A user who accepted a version older than the required one must
+// accept the current one before going on.
+func (s *LegalService) Versions() (map[string]int, map[string]int, error) {
+	current, err := s.legalRepo.CurrentVersions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	required, err := s.legalRepo.RequiredVersions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return current, required, nil
+}
+
+// Publish stores translations, by locale, as the next version of kind and
+// returns that version. A mandatory version makes every user accept it
+// again.
+func (s *LegalService) Publish(adminID, kind string, mandatory bool, translations map[string]LegalTranslation, client ClientInfo) (int, error) {
+	if !slices.Contains(models.LegalKinds, kind) {
+		return 0, ErrUnknownLegalDocument
+	}
+	if _, ok := translations[i18n.Default]; !ok {
+		return 0, ErrLegalEnglishRequired
+	}
+	for locale, translation := range translations {
+		if i18n.Supported(locale) != locale {
+			return 0, ErrUnsupportedLocale
+		}
+		if strings.TrimSpace(translation.Title) == "" || strings.TrimSpace(translation.Body) == "" {
+			return 0, ErrLegalDocumentIncomplete
+		}
+	}
+
+	var version int
+	event := newAuditEvent(models.AuditLegalPublished, adminID, "", client)
+	err := s.auth.audit.Track(event, func(tx *sql.Tx) error {
+		legalRepo := s.legalRepo.WithTx(tx)
+
+		var err error
+		if version, err = legalRepo.NextVersion(kind); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for locale, translation := range translations {
+			err := legalRepo.CreateDocument(&models.LegalDocument{
+				ID:          uuid.New().String(),
+				Kind:        kind,
+				Version:     version,
+				Locale:      locale,
+				Title:       strings.TrimSpace(translation.Title),
+				Body:        translation.Body,
+				Mandatory:   mandatory,
+				PublishedBy: adminID,
+				PublishedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		event.Details = map[string]string{
+			"kind":      kind,
+			"version":   strconv.Itoa(version),
+			"mandatory": strconv.FormatBool(mandatory),
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// Accept records that userID accepted consents, the current versions of
+// legal documents by kind, and returns a new token pair that says so, which
+// replaces refreshToken. authTime, amr and orgID are carried over from the
+// caller's token.
+func (s *LegalService) Accept(userID string, consents map[string]int, refreshToken string, authTime time.Time, amr []string, orgID string, client ClientInfo) (string, string, error) {
+	if len(consents) == 0 {
+		return "", "", ErrNothingToAccept
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	var accessToken, newRefreshToken string
+	err = s.auth.audit.TrackAll(func(tx *sql.Tx) ([]*models.AuditEvent, error) {
+		events, err := s.auth.recordConsents(tx, userID, consents, client)
+		if err != nil {
+			return nil, err
+		}
+
+		accessToken, newRefreshToken, err = s.auth.reissueTokens(tx, user, refreshToken, authTime, amr, orgID)
+		if err != nil {
+			return nil, err
+		}
+		return events, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, newRefreshToken, nil
+}
+
+// Consents returns every consent userID gave, newest first.
+func (s *LegalService) Consents(userID string) ([]*models.Consent, error) {
+	return s.legalRepo.ListConsents(userID)
+}
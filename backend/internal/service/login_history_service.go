@@ -0,0 +1,124 @@
+package service
+
+import (
+	"log"
+	"net"
+	"time"
+
+	"backend/internal/geoip"
+	"backend/internal/mailtmpl"
+	"backend/internal/models"
+	"backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type LoginHistoryService struct {
+	eventRepo *repository.LoginEventRepository
+	geo       *geoip.Resolver
+	emails    *Emailer
+}
+
+func NewLoginHistoryService(eventRepo *repository.LoginEventRepository, geo *geoip.Resolver, emails *Emailer) *LoginHistoryService {
+	return &LoginHistoryService{
+		eventRepo: eventRepo,
+		geo:       geo,
+		emails:    emails,
+	}
+}
+
+// Record stores a login or refresh of user. A successful login from a user
+// agent or network the account hasn't used before triggers a "new sign-in"
+// email unless the device is trusted. Failures are logged rather than
+// returned so history problems never block a login.
+func (s *LoginHistoryService) Record(user *models.User, method, outcome string, client ClientInfo, trustedDevice bool) {
+	event := &models.LoginEvent{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Method:    method,
+		Outcome:   outcome,
+		IP:        truncate(client.IP, 45),
+		Network:   networkOf(client.IP),
+		UserAgent: truncate(client.UserAgent, 500),
+		CreatedAt: time.Now(),
+	}
+
+	loc := s.geo.Lookup(client.IP)
+	event.Country = loc.Country
+	event.City = truncate(loc.City, 100)
+	event.ASN = loc.ASN
+
+	alert := false
+	if outcome == models.LoginOutcomeSuccess && method != models.LoginMethodRefresh && method != models.LoginMethodRegister {
+		hasHistory, userAgentSeen, networkSeen, err := s.eventRepo.Seen(user.ID, event.UserAgent, event.Network)
+		if err != nil {
+			log.Printf("Error checking login history for user %s: %v", user.ID, err)
+		} else {
+			event.NewDevice = hasHistory && (!userAgentSeen || !networkSeen)
+			alert = event.NewDevice && !trustedDevice
+		}
+	}
+
+	if err := s.eventRepo.Create(event); err != nil {
+		log.Printf("Error recording login event for user %s: %v", user.ID, err)
+		return
+	}
+
+	if alert {
+		if err := s.sendNewSignInAlert(user, event); err != nil {
+			log.Printf("Error sending new sign-in alert to user %s: %v", user.ID, err)
+		}
+	}
+}
+
+func (s *LoginHistoryService) History(userID string, limit, offset int) ([]*models.LoginEvent, int, error) {
+	return s.eventRepo.ListByUserID(userID, limit, offset)
+}
+
+func (s *LoginHistoryService) sendNewSignInAlert(user *models.User, event *models.LoginEvent) error {
+	// Phone-only accounts have nowhere to send email
+	if user.Email == "" {
+		return nil
+	}
+
+	locale, dates, err := s.emails.Recipient(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.emails.Send(user.Email, mailtmpl.NewSignIn, locale, mailtmpl.NewSignInData{
+		Name:     user.Name,
+		Time:     dates.Format(event.CreatedAt),
+		IP:       event.IP,
+		Location: locationString(event),
+		Device:   event.UserAgent,
+	})
+}
+
+func locationString(event *models.LoginEvent) string {
+	switch {
+	case event.City != "" && event.Country != "":
+		return event.City + ", " + event.Country
+	case event.Country != "":
+		return event.Country
+	}
+	return "-"
+}
+
+// networkOf returns the /24 (IPv4) or /48 (IPv6) network containing ip, so
+// that address changes within one provider's block aren't treated as new.
+func networkOf(ip string) string {
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend/internal/mailtmpl"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/internal/textnorm"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidMagicLink = errors.New("invalid or expired login link")
+
+const (
+	// At most magicLinkMaxPerWindow links may be requested for one address
+	// within magicLinkWindow.
+	magicLinkMaxPerWindow = 3
+	magicLinkWindow       = 15 * time.Minute
+
+	// A link is burned after this many wrong codes to stop brute forcing
+	// the 6-digit space.
+	magicLinkMaxAttempts = 5
+)
+
+type MagicLinkService struct {
+	auth     *AuthService
+	userRepo *repository.UserRepository
+	linkRepo *repository.MagicLinkRepository
+	emails   *Emailer
+	linkURL  string
+	ttl      time.Duration
+}
+
+func NewMagicLinkService(
+	auth *AuthService,
+	userRepo *repository.UserRepository,
+	linkRepo *repository.MagicLinkRepository,
+	emails *Emailer,
+	linkURL string,
+	ttl time.Duration,
+) *MagicLinkService {
+	return &MagicLinkService{
+		auth:     auth,
+		userRepo: userRepo,
+		linkRepo: linkRepo,
+		emails:   emails,
+		linkURL:  linkURL,
+		ttl:      ttl,
+	}
+}
+
+// Request emails a login link and code to email. Unknown addresses, and
+// known ones that have had too many links recently, are silently ignored:
+// answering either differently would tell callers which addresses have
+// accounts.
+func (s *MagicLinkService) Request(email, deviceID string) error {
+	email, err := textnorm.Email(email)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+
+	count, err := s.linkRepo.CountSince(user.Email, time.Now().Add(-magicLinkWindow))
+	if err != nil {
+		return err
+	}
+	if count >= magicLinkMaxPerWindow {
+		return nil
+	}
+
+	// Only the newest link for an address is valid
+	if err := s.linkRepo.InvalidateByEmail(user.Email); err != nil {
+		return err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	code, err := randomCode()
+	if err != nil {
+		return err
+	}
+
+	link := &models.MagicLink{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Email:     user.Email,
+		DeviceID:  deviceID,
+		ExpiresAt: time.Now().Add(s.ttl),
+		CreatedAt: time.Now(),
+	}
+	link.TokenHash = hashToken(token)
+	link.CodeHash = hashCode(link.ID, code)
+
+	if err := s.linkRepo.Create(link); err != nil {
+		return err
+	}
+
+	locale, _, err := s.emails.Recipient(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.emails.Send(user.Email, mailtmpl.MagicLink, locale, mailtmpl.MagicLinkData{
+		Name:    user.Name,
+		URL:     s.linkURL + "?token=" + url.QueryEscape(token),
+		Code:    code,
+		Minutes: int(s.ttl.Minutes()),
+	})
+}
+
+// VerifyToken redeems the link token sent by email.
+func (s *MagicLinkService) VerifyToken(token, deviceID string, client ClientInfo) (*models.User, string, string, error) {
+	link, err := s.linkRepo.GetActiveByTokenHash(hashToken(token))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if link == nil || link.DeviceID != deviceID {
+		return nil, "", "", ErrInvalidMagicLink
+	}
+
+	return s.redeem(link, []string{amrEmail}, client)
+}
+
+// VerifyCode redeems the 6-digit code sent by email.
+func (s *MagicLinkService) VerifyCode(email, code, deviceID string, client ClientInfo) (*models.User, string, string, error) {
+	email, err := textnorm.Email(email)
+	if err != nil {
+		return nil, "", "", ErrInvalidMagicLink
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, "", "", ErrInvalidMagicLink
+		}
+		return nil, "", "", err
+	}
+
+	link, err := s.linkRepo.GetActiveByEmail(user.Email)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if link == nil {
+		return nil, "", "", ErrInvalidMagicLink
+	}
+
+	expected := []byte(link.CodeHash)
+	actual := []byte(hashCode(link.ID, code))
+	if link.DeviceID != deviceID || subtle.ConstantTimeCompare(expected, actual) != 1 {
+		attempts, err := s.linkRepo.IncrementAttempts(link.ID)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if attempts >= magicLinkMaxAttempts {
+			if err := s.linkRepo.InvalidateByEmail(user.Email); err != nil {
+				return nil, "", "", err
+			}
+		}
+		return nil, "", "", ErrInvalidMagicLink
+	}
+
+	return s.redeem(link, []string{amrEmail, amrOTP}, client)
+}
+
+func (s *MagicLinkService) redeem(link *models.MagicLink, amr []string, client ClientInfo) (*models.User, string, string, error) {
+	user, err := s.userRepo.GetByID(link.UserID)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, "", "", ErrInvalidMagicLink
+		}
+		return nil, "", "", err
+	}
+
+	// Leave the link usable so the client can retry and cancel the deletion
+	if err := s.auth.checkPendingDeletion(user, models.LoginMethodMagicLink, client); err != nil {
+		return nil, "", "", err
+	}
+
+	// Consuming the row invalidates the link and the code together
+	ok, err := s.linkRepo.MarkUsed(link.ID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if !ok {
+		return nil, "", "", ErrInvalidMagicLink
+	}
+
+	var accessToken, refreshToken string
+	err = s.auth.audit.Track(auditLogin(user, models.LoginMethodMagicLink, client), func(tx *sql.Tx) error {
+		// The link reached the user, so the address is theirs, unless it
+		// was changed after the link was sent
+		if user.EmailVerifiedAt == nil && strings.EqualFold(link.Email, user.Email) {
+			if err := s.userRepo.WithTx(tx).MarkEmailVerified(user.ID); err != nil {
+				return err
+			}
+			verifiedAt := time.Now()
+			user.EmailVerifiedAt = &verifiedAt
+		}
+
+		var err error
+		accessToken, refreshToken, err = s.auth.issueTokens(tx, user, time.Now(), amr, "")
+		return err
+	})
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	s.auth.history.Record(user, models.LoginMethodMagicLink, models.LoginOutcomeSuccess, client, false)
+
+	return user, accessToken, refreshToken, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func randomCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashCode salts the code with the ID of the row it belongs to so equal
+// codes issued for different challenges never share a hash.
+func hashCode(id, code string) string {
+	return hashToken(id + ":" + code)
+}
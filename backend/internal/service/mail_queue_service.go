@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"backend/internal/mailer"
+	"backend/internal/models"
+	"backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// MailQueueOptions configures outbound mail delivery.
+type MailQueueOptions struct {
+	// Attempts after which an email that keeps failing is given up on
+	MaxAttempts int
+
+	// Wait before the second attempt; it doubles after every further
+	// failure
+	RetryBase time.Duration
+
+	// Longest wait between two attempts
+	RetryMax time.Duration
+
+	// How long an email may be sending before it is taken to have been
+	// abandoned by a stopped worker and queued again. It must be well
+	// above the time a delivery can take.
+	ClaimTimeout time.Duration
+}
+
+// MailQueueService is the mailer the services send through. Emails are
+// stored in a persistent queue and delivered in the background by the
+// transport, so a slow or unavailable mail server doesn't hold up requests
+// and emails survive restarts. Failed sends are retried with exponential
+// backoff; emails that fail permanently or run out of attempts are dead
+// letters, kept for an administrator to retry.
+type MailQueueService struct {
+	queueRepo mailQueue
+	transport mailer.Mailer
+	options   MailQueueOptions
+
+	// Wakes the worker when an email is queued
+	queued chan struct{}
+}
+
+// mailQueue is the part of repository.MailQueueRepository the service
+// uses, so tests can run it without a database.
+type mailQueue interface {
+	Enqueue(mail *models.QueuedMail) error
+	ClaimDue(now time.Time) (*models.QueuedMail, error)
+	RequeueSending(claimedBefore time.Time) (int64, error)
+	MarkSent(id string) error
+	Reschedule(id string, next time.Time, lastError string) error
+	MarkDead(id, lastError string) error
+	ListDead(limit int) ([]*models.QueuedMail, error)
+	RequeueDead(ids []string) (int64, error)
+}
+
+func NewMailQueueService(queueRepo *repository.MailQueueRepository, transport mailer.Mailer, options MailQueueOptions) *MailQueueService {
+	return &MailQueueService{
+		queueRepo: queueRepo,
+		transport: transport,
+		options:   options,
+		queued:    make(chan struct{}, 1),
+	}
+}
+
+// Send queues msg for delivery.
+func (s *MailQueueService) Send(msg *mailer.Message) error {
+	now := time.Now()
+	mail := &models.QueuedMail{
+		ID:            uuid.New().String(),
+		To:            msg.To,
+		Subject:       msg.Subject,
+		Text:          msg.Text,
+		HTML:          msg.HTML,
+		Status:        models.MailPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+
+	if err := s.queueRepo.Enqueue(mail); err != nil {
+		return err
+	}
+
+	s.wake()
+	return nil
+}
+
+// Run delivers queued emails as they come in and as their retries fall
+// due, checking every interval for emails queued by other instances and
+// for emails left sending by stopped ones. It never returns, so run it in
+// its own goroutine.
+func (s *MailQueueService) Run(interval time.Duration) {
+	for {
+		count, err := s.queueRepo.RequeueSending(time.Now().Add(-s.options.ClaimTimeout))
+		if err != nil {
+			log.Printf("Requeueing outbound mail failed: %v", err)
+		} else if count > 0 {
+			log.Printf("Requeued %d outbound emails abandoned while sending", count)
+		}
+
+		s.deliverDue()
+
+		select {
+		case <-s.queued:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// deliverDue makes an attempt at every email that is due, until none is
+// left or the queue can't be read.
+func (s *MailQueueService) deliverDue() {
+	for {
+		mail, err := s.queueRepo.ClaimDue(time.Now())
+		if errors.Is(err, repository.ErrMailUnreadable) {
+			// Retrying won't help, and the email would block the queue
+			log.Printf("Giving up on outbound mail %s: %v", mail.ID, err)
+			if err := s.queueRepo.MarkDead(mail.ID, err.Error()); err != nil {
+				log.Printf("Marking outbound mail %s dead failed: %v", mail.ID, err)
+				return
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("Claiming outbound mail failed: %v", err)
+			return
+		}
+		if mail == nil {
+			return
+		}
+		s.deliver(mail)
+	}
+}
+
+// DeadLetters returns up to limit emails that were given up on, newest
+// first.
+func (s *MailQueueService) DeadLetters(limit int) ([]*models.QueuedMail, error) {
+	return s.queueRepo.ListDead(limit)
+}
+
+// Retry queues the dead emails with ids, or all of them if ids is empty,
+// for delivery again with a fresh set of attempts, and returns how many
+// were queued.
+func (s *MailQueueService) Retry(ids []string) (int64, error) {
+	count, err := s.queueRepo.RequeueDead(ids)
+	if err != nil {
+		return 0, err
+	}
+
+	if count > 0 {
+		s.wake()
+	}
+	return count, nil
+}
+
+// deliver makes one attempt at sending mail. Emails are identified by ID
+// in the log, which doesn't get the recipient's address.
+func (s *MailQueueService) deliver(mail *models.QueuedMail) {
+	err := s.transport.Send(&mailer.Message{
+		To:      mail.To,
+		Subject: mail.Subject,
+		Text:    mail.Text,
+		HTML:    mail.HTML,
+	})
+	if err == nil {
+		if err := s.queueRepo.MarkSent(mail.ID); err != nil {
+			log.Printf("Marking outbound mail %s sent failed: %v", mail.ID, err)
+		}
+		return
+	}
+
+	if mailer.Permanent(err) || mail.Attempts >= s.options.MaxAttempts {
+		log.Printf("Giving up on outbound mail %s after %d attempts: %v", mail.ID, mail.Attempts, err)
+		if err := s.queueRepo.MarkDead(mail.ID, err.Error()); err != nil {
+			log.Printf("Marking outbound mail %s dead failed: %v", mail.ID, err)
+		}
+		return
+	}
+
+	next := time.Now().Add(s.backoff(mail.Attempts))
+	log.Printf("Sending outbound mail %s failed, retrying at %s: %v", mail.ID, next.Format(time.RFC3339), err)
+	if err := s.queueRepo.Reschedule(mail.ID, next, err.Error()); err != nil {
+		log.Printf("Rescheduling outbound mail %s failed: %v", mail.ID, err)
+	}
+}
+
+// backoff returns how long to wait after the attempts-th failed attempt.
+func (s *MailQueueService) backoff(attempts int) time.Duration {
+	wait := s.options.RetryBase
+	for i := 1; i < attempts && wait < s.options.RetryMax; i++ {
+		wait *= 2
+	}
+	return min(wait, s.options.RetryMax)
+}
+
+func (s *MailQueueService) wake() {
+	select {
+	case s.queued <- struct{}{}:
+	default:
+	}
+}
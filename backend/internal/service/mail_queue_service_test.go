@@ -0,0 +1,370 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/mailer"
+	"backend/internal/models"
+	"backend/internal/repository"
+)
+
+// memoryQueue keeps the mail queue in memory, with the semantics of
+// repository.MailQueueRepository.
+type memoryQueue struct {
+	mu         sync.Mutex
+	mails      map[string]*models.QueuedMail
+	unreadable map[string]bool
+}
+
+func newMemoryQueue() *memoryQueue {
+	return &memoryQueue{mails: map[string]*models.QueuedMail{}, unreadable: map[string]bool{}}
+}
+
+func (q *memoryQueue) Enqueue(mail *models.QueuedMail) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stored := *mail
+	q.mails[mail.ID] = &stored
+	return nil
+}
+
+func (q *memoryQueue) ClaimDue(now time.Time) (*models.QueuedMail, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var due *models.QueuedMail
+	for _, mail := range q.mails {
+		if mail.Status == models.MailPending && !mail.NextAttemptAt.After(now) &&
+			(due == nil || mail.NextAttemptAt.Before(due.NextAttemptAt)) {
+			due = mail
+		}
+	}
+	if due == nil {
+		return nil, nil
+	}
+
+	due.Status = models.MailSending
+	due.Attempts++
+	claimed := *due
+	if q.unreadable[due.ID] {
+		return &models.QueuedMail{ID: due.ID}, repository.ErrMailUnreadable
+	}
+	return &claimed, nil
+}
+
+func (q *memoryQueue) RequeueSending(claimedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (q *memoryQueue) MarkSent(id string) error {
+	return q.update(id, func(mail *models.QueuedMail) {
+		now := time.Now()
+		mail.Status = models.MailSent
+		mail.SentAt = &now
+	})
+}
+
+func (q *memoryQueue) Reschedule(id string, next time.Time, lastError string) error {
+	return q.update(id, func(mail *models.QueuedMail) {
+		mail.Status = models.MailPending
+		mail.NextAttemptAt = next
+		mail.LastError = lastError
+	})
+}
+
+func (q *memoryQueue) MarkDead(id, lastError string) error {
+	return q.update(id, func(mail *models.QueuedMail) {
+		mail.Status = models.MailDead
+		mail.LastError = lastError
+	})
+}
+
+func (q *memoryQueue) ListDead(limit int) ([]*models.QueuedMail, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var dead []*models.QueuedMail
+	for _, mail := range q.mails {
+		if mail.Status == models.MailDead {
+			copied := *mail
+			dead = append(dead, &copied)
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool { return dead[i].CreatedAt.After(dead[j].CreatedAt) })
+	if len(dead) > limit {
+		dead = dead[:limit]
+	}
+	return dead, nil
+}
+
+func (q *memoryQueue) RequeueDead(ids []string) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var count int64
+	for _, mail := range q.mails {
+		if mail.Status != models.MailDead || (len(ids) > 0 && !contains(ids, mail.ID)) {
+			continue
+		}
+		mail.Status = models.MailPending
+		mail.Attempts = 0
+		mail.NextAttemptAt = time.Now()
+		count++
+	}
+	return count, nil
+}
+
+func (q *memoryQueue) update(id string, change func(mail *models.QueuedMail)) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	mail, ok := q.mails[id]
+	if !ok {
+		return fmt.Errorf("no queued mail %s", id)
+	}
+	change(mail)
+	return nil
+}
+
+// get returns a copy of the email with id as it is stored.
+func (q *memoryQueue) get(t *testing.T, id string) models.QueuedMail {
+	t.Helper()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	mail, ok := q.mails[id]
+	if !ok {
+		t.Fatalf("no queued mail %s", id)
+	}
+	return *mail
+}
+
+// makeDue moves the next attempt at every pending email to now, as if
+// their backoff had passed.
+func (q *memoryQueue) makeDue() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, mail := range q.mails {
+		if mail.Status == models.MailPending {
+			mail.NextAttemptAt = time.Now()
+		}
+	}
+}
+
+// only returns the one email in the queue.
+func (q *memoryQueue) only(t *testing.T) models.QueuedMail {
+	t.Helper()
+
+	q.mu.Lock()
+	var ids []string
+	for id := range q.mails {
+		ids = append(ids, id)
+	}
+	q.mu.Unlock()
+
+	if len(ids) != 1 {
+		t.Fatalf("%d emails queued, want 1", len(ids))
+	}
+	return q.get(t, ids[0])
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var testMailQueueOptions = MailQueueOptions{
+	MaxAttempts:  4,
+	RetryBase:    time.Minute,
+	RetryMax:     3 * time.Minute,
+	ClaimTimeout: 10 * time.Minute,
+}
+
+func newTestMailQueue() (*MailQueueService, *memoryQueue, *mailer.MemoryMailer) {
+	queue := newMemoryQueue()
+	transport := mailer.NewMemoryMailer()
+	s := &MailQueueService{
+		queueRepo: queue,
+		transport: transport,
+		options:   testMailQueueOptions,
+		queued:    make(chan struct{}, 1),
+	}
+	return s, queue, transport
+}
+
+var testMail = &mailer.Message{To: "ada@example.org", Subject: "Your code", Text: "123456", HTML: "<b>123456</b>"}
+
+func TestMailQueueDelivers(t *testing.T) {
+	s, queue, transport := newTestMailQueue()
+
+	if err := s.Send(testMail); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(transport.Messages()) != 0 {
+		t.Fatal("Send delivered the email instead of queueing it")
+	}
+	select {
+	case <-s.queued:
+	default:
+		t.Error("Send didn't wake the worker")
+	}
+
+	s.deliverDue()
+
+	messages := transport.Messages()
+	if len(messages) != 1 || messages[0] != *testMail {
+		t.Fatalf("delivered %+v, want the queued email", messages)
+	}
+	if mail := queue.only(t); mail.Status != models.MailSent || mail.Attempts != 1 {
+		t.Errorf("status %s after %d attempts, want sent after 1", mail.Status, mail.Attempts)
+	}
+}
+
+func TestMailQueueBacksOff(t *testing.T) {
+	s, queue, transport := newTestMailQueue()
+	transport.FailWith(errors.New("connection refused"))
+
+	if err := s.Send(testMail); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	// The wait doubles from RetryBase up to RetryMax
+	for attempt, wait := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		before := time.Now()
+		s.deliverDue()
+		after := time.Now()
+
+		mail := queue.only(t)
+		if mail.Status != models.MailPending || mail.Attempts != attempt+1 {
+			t.Fatalf("status %s after %d attempts, want pending after %d", mail.Status, mail.Attempts, attempt+1)
+		}
+		if mail.NextAttemptAt.Before(before.Add(wait)) || mail.NextAttemptAt.After(after.Add(wait)) {
+			t.Errorf("attempt %d: next attempt in %s, want %s", attempt+1, mail.NextAttemptAt.Sub(before).Round(time.Second), wait)
+		}
+		if mail.LastError != "connection refused" {
+			t.Errorf("LastError = %q", mail.LastError)
+		}
+
+		// Not due yet
+		s.deliverDue()
+		if mail := queue.only(t); mail.Attempts != attempt+1 {
+			t.Fatalf("email retried before its backoff passed")
+		}
+		queue.makeDue()
+	}
+
+	// The fourth attempt is the last
+	s.deliverDue()
+	if mail := queue.only(t); mail.Status != models.MailDead || mail.Attempts != testMailQueueOptions.MaxAttempts {
+		t.Errorf("status %s after %d attempts, want dead after %d", mail.Status, mail.Attempts, testMailQueueOptions.MaxAttempts)
+	}
+	if len(transport.Messages()) != 0 {
+		t.Error("failing transport delivered the email")
+	}
+}
+
+func TestMailQueueBackoff(t *testing.T) {
+	s, _, _ := newTestMailQueue()
+
+	for attempts, want := range map[int]time.Duration{
+		1: time.Minute,
+		2: 2 * time.Minute,
+		3: 3 * time.Minute,
+		4: 3 * time.Minute,
+		9: 3 * time.Minute,
+	} {
+		if got := s.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestMailQueueGivesUpOnPermanentErrors(t *testing.T) {
+	s, queue, transport := newTestMailQueue()
+	transport.FailWith(&mailer.PermanentError{Err: errors.New("550 no such user")})
+
+	if err := s.Send(testMail); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	s.deliverDue()
+
+	mail := queue.only(t)
+	if mail.Status != models.MailDead || mail.Attempts != 1 {
+		t.Errorf("status %s after %d attempts, want dead after 1", mail.Status, mail.Attempts)
+	}
+	if mail.LastError != "550 no such user" {
+		t.Errorf("LastError = %q", mail.LastError)
+	}
+}
+
+func TestMailQueueGivesUpOnUnreadableMail(t *testing.T) {
+	s, queue, transport := newTestMailQueue()
+
+	if err := s.Send(testMail); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	queue.unreadable[queue.only(t).ID] = true
+	s.deliverDue()
+
+	if mail := queue.only(t); mail.Status != models.MailDead {
+		t.Errorf("status %s, want dead", mail.Status)
+	}
+	if len(transport.Messages()) != 0 {
+		t.Error("unreadable email was delivered")
+	}
+}
+
+func TestMailQueueRetry(t *testing.T) {
+	s, queue, transport := newTestMailQueue()
+	transport.FailWith(&mailer.PermanentError{Err: errors.New("550 mailbox disabled")})
+
+	if err := s.Send(testMail); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	s.deliverDue()
+	<-s.queued
+
+	dead, err := s.DeadLetters(10)
+	if err != nil || len(dead) != 1 {
+		t.Fatalf("DeadLetters = %d emails, %v, want 1", len(dead), err)
+	}
+
+	transport.FailWith(nil)
+	count, err := s.Retry([]string{dead[0].ID})
+	if err != nil || count != 1 {
+		t.Fatalf("Retry = %d, %v, want 1", count, err)
+	}
+	select {
+	case <-s.queued:
+	default:
+		t.Error("Retry didn't wake the worker")
+	}
+	if mail := queue.only(t); mail.Status != models.MailPending || mail.Attempts != 0 {
+		t.Errorf("status %s after %d attempts, want pending with fresh attempts", mail.Status, mail.Attempts)
+	}
+
+	s.deliverDue()
+	if mail := queue.only(t); mail.Status != models.MailSent {
+		t.Errorf("status %s after the retry, want sent", mail.Status)
+	}
+	if len(transport.Messages()) != 1 {
+		t.Errorf("delivered %d emails, want 1", len(transport.Messages()))
+	}
+
+	// Nothing is dead any more
+	if count, err := s.Retry(nil); err != nil || count != 0 {
+		t.Errorf("Retry(nil) = %d, %v, want 0", count, err)
+	}
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend/internal/mailtmpl"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/internal/textnorm"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotOrgMember               = errors.New("not a member of this organization")
+	ErrOrgPermissionDenied        = errors.New("organization role does not allow this")
+	ErrInvalidOrgRole             = errors.New("invalid organization role")
+	ErrLastOwner                  = errors.New("an organization needs at least one owner")
+	ErrInvalidOrgInvitation       = errors.New("invalid or expired invitation")
+	ErrInvitationEmailMismatch    = errors.New("invitation was sent to a different email address")
+	ErrOrgInvitationTokenRequired = errors.New("the emailed invitation token is required until the email address is verified")
+)
+
+// orgRoleRank orders organization roles by the permissions they grant.
+var orgRoleRank = map[string]int{
+	models.OrgRoleMember: 1,
+	models.OrgRoleAdmin:  2,
+	models.OrgRoleOwner:  3,
+}
+
+// OrganizationService manages organizations, their members and invitations.
+// Admins and owners manage members; only owners can grant or take away the
+// owner role.
+type OrganizationService struct {
+	auth           *AuthService
+	orgRepo        *repository.OrganizationRepository
+	invitationRepo *repository.OrgInvitationRepository
+	userRepo       *repository.UserRepository
+	emails         *Emailer
+	invitationURL  string
+	invitationTTL  time.Duration
+}
+
+func NewOrganizationService(
+	auth *AuthService,
+	orgRepo *repository.OrganizationRepository,
+	invitationRepo *repository.OrgInvitationRepository,
+	userRepo *repository.UserRepository,
+	emails *Emailer,
+	invitationURL string,
+	invitationTTL time.Duration,
+) *OrganizationService {
+	return &OrganizationService{
+		auth:           auth,
+		orgRepo:        orgRepo,
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		emails:         emails,
+		invitationURL:  invitationURL,
+		invitationTTL:  invitationTTL,
+	}
+}
+
+// Create makes a new organization owned by userID. Guests have to upgrade
+// their account first.
+func (s *OrganizationService) Create(userID, name string) (*models.Organization, error) {
+	name, err := textnorm.Name(name)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == models.RoleGuest {
+		return nil, ErrGuestNotAllowed
+	}
+
+	org := &models.Organization{
+		ID:        uuid.New().String(),
+		Name:      name,
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Role:      models.OrgRoleOwner,
+	}
+
+	if err := s.orgRepo.Create(org, userID); err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+func (s *OrganizationService) List(userID string) ([]*models.Organization, error) {
+	return s.orgRepo.ListByUserID(userID)
+}
+
+// Switch makes orgID the active organization of userID and returns a new
+// token pair carrying it, which replaces refreshToken. authTime and amr
+// come from the caller's current access token, so switching doesn't count
+// as authenticating again.
+func (s *OrganizationService) Switch(userID, orgID, refreshToken string, authTime time.Time, amr []string, client ClientInfo) (string, string, error) {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleMember); err != nil {
+		return "", "", err
+	}
+
+	if err := s.orgRepo.Touch(orgID, userID); err != nil {
+		return "", "", err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	var accessToken, newRefreshToken string
+	event := newAuditEvent(models.AuditOrgSwitched, userID, userID, client)
+	event.Details = map[string]string{"org_id": orgID}
+	err = s.auth.audit.Track(event, func(tx *sql.Tx) error {
+		var err error
+		accessToken, newRefreshToken, err = s.auth.reissueTokens(tx, user, refreshToken, authTime, amr, orgID)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, newRefreshToken, nil
+}
+
+// Members lists the members of orgID; userID must be one of them.
+func (s *OrganizationService) Members(userID, orgID string) ([]*models.Membership, error) {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleMember); err != nil {
+		return nil, err
+	}
+
+	return s.orgRepo.ListMembers(orgID)
+}
+
+// UpdateMemberRole gives memberID a new role in orgID.
+func (s *OrganizationService) UpdateMemberRole(userID, orgID, memberID, role string) error {
+	if _, ok := orgRoleRank[role]; !ok {
+		return ErrInvalidOrgRole
+	}
+
+	caller, err := s.requireRole(orgID, userID, models.OrgRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.orgRepo.GetMembership(orgID, memberID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return repository.ErrMembershipNotFound
+	}
+
+	if (role == models.OrgRoleOwner || member.Role == models.OrgRoleOwner) && caller.Role != models.OrgRoleOwner {
+		return ErrOrgPermissionDenied
+	}
+
+	if member.Role == models.OrgRoleOwner && role != models.OrgRoleOwner {
+		if err := s.requireAnotherOwner(orgID); err != nil {
+			return err
+		}
+	}
+
+	return s.orgRepo.UpdateMemberRole(orgID, memberID, role)
+}
+
+// RemoveMember takes memberID out of orgID. Any member may remove
+// themselves; removing someone else takes an admin, or an owner if the
+// member is an owner.
+func (s *OrganizationService) RemoveMember(userID, orgID, memberID string) error {
+	caller, err := s.requireRole(orgID, userID, models.OrgRoleMember)
+	if err != nil {
+		return err
+	}
+
+	member := caller
+	if memberID != userID {
+		if orgRoleRank[caller.Role] < orgRoleRank[models.OrgRoleAdmin] {
+			return ErrOrgPermissionDenied
+		}
+
+		member, err = s.orgRepo.GetMembership(orgID, memberID)
+		if err != nil {
+			return err
+		}
+		if member == nil {
+			return repository.ErrMembershipNotFound
+		}
+
+		if member.Role == models.OrgRoleOwner && caller.Role != models.OrgRoleOwner {
+			return ErrOrgPermissionDenied
+		}
+	}
+
+	if member.Role == models.OrgRoleOwner {
+		if err := s.requireAnotherOwner(orgID); err != nil {
+			return err
+		}
+	}
+
+	return s.orgRepo.RemoveMember(orgID, memberID)
+}
+
+// Invite emails an invitation to join orgID with role. An earlier pending
+// invitation for the same address is replaced.
+func (s *OrganizationService) Invite(userID, orgID, email, role string) (*models.OrgInvitation, error) {
+	if role == "" {
+		role = models.OrgRoleMember
+	}
+	if _, ok := orgRoleRank[role]; !ok {
+		return nil, ErrInvalidOrgRole
+	}
+
+	email, err := textnorm.Email(email)
+	if err != nil {
+		return nil, err
+	}
+
+	caller, err := s.requireRole(orgID, userID, models.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if role == models.OrgRoleOwner && caller.Role != models.OrgRoleOwner {
+		return nil, ErrOrgPermissionDenied
+	}
+
+	// Don't invite someone who's already in
+	invitee, err := s.userRepo.GetByEmail(email)
+	if err != nil && err != repository.ErrUserNotFound {
+		return nil, err
+	}
+	if invitee != nil {
+		membership, err := s.orgRepo.GetMembership(orgID, invitee.ID)
+		if err != nil {
+			return nil, err
+		}
+		if membership != nil {
+			return nil, repository.ErrAlreadyMember
+		}
+	}
+
+	org, err := s.orgRepo.GetByID(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	inviter, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.invitationRepo.DeletePending(orgID, email); err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &models.OrgInvitation{
+		ID:        uuid.New().String(),
+		OrgID:     orgID,
+		OrgName:   org.Name,
+		Email:     email,
+		Role:      role,
+		TokenHash: hashToken(token),
+		InvitedBy: userID,
+		ExpiresAt: time.Now().Add(s.invitationTTL),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return nil, err
+	}
+
+	// Invitees without an account get the email in the inviter's language
+	recipientID := inviter.ID
+	if invitee != nil {
+		recipientID = invitee.ID
+	}
+	locale, _, err := s.emails.Recipient(recipientID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.emails.Send(email, mailtmpl.OrgInvitation, locale, mailtmpl.OrgInvitationData{
+		InviterName: inviter.Name,
+		OrgName:     org.Name,
+		Role:        role,
+		URL:         s.invitationURL + "?token=" + url.QueryEscape(token),
+		Days:        int(s.invitationTTL.Hours() / 24),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// Invitations lists the pending invitations of orgID for its admins.
+func (s *OrganizationService) Invitations(userID, orgID string) ([]*models.OrgInvitation, error) {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	return s.invitationRepo.ListPendingByOrgID(orgID)
+}
+
+func (s *OrganizationService) RevokeInvitation(userID, orgID, invitationID string) error {
+	if _, err := s.requireRole(orgID, userID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+
+	return s.invitationRepo.Delete(orgID, invitationID)
+}
+
+// PendingInvitations lists the invitations waiting for userID to answer.
+// Only users who have verified their address see them; anyone can register
+// with an address they don't own.
+func (s *OrganizationService) PendingInvitations(userID string) ([]*models.OrgInvitation, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Email == "" || user.EmailVerifiedAt == nil {
+		return []*models.OrgInvitation{}, nil
+	}
+
+	return s.invitationRepo.ListPendingByEmail(user.Email)
+}
+
+// Accept adds userID to the organization of an invitation identified by
+// either the emailed token or its ID. The user must be signed in with the
+// invited address, and may only use the ID once they have verified it.
+func (s *OrganizationService) Accept(userID, token, invitationID string) (*models.OrgInvitation, error) {
+	invitation, err := s.invitationFor(userID, token, invitationID)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.invitationRepo.MarkAccepted(invitation.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidOrgInvitation
+	}
+
+	err = s.orgRepo.AddMember(invitation.OrgID, userID, invitation.Role)
+	if err != nil && err != repository.ErrAlreadyMember {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// Decline turns down an invitation, identified as for Accept.
+func (s *OrganizationService) Decline(userID, token, invitationID string) error {
+	invitation, err := s.invitationFor(userID, token, invitationID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.invitationRepo.MarkDeclined(invitation.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidOrgInvitation
+	}
+
+	return nil
+}
+
+func (s *OrganizationService) invitationFor(userID, token, invitationID string) (*models.OrgInvitation, error) {
+	var invitation *models.OrgInvitation
+	var err error
+	if token != "" {
+		invitation, err = s.invitationRepo.GetPendingByTokenHash(hashToken(token))
+	} else {
+		invitation, err = s.invitationRepo.GetPendingByID(invitationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil {
+		return nil, ErrInvalidOrgInvitation
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	// The token proves the user got the email; the ID alone doesn't
+	if token == "" && user.EmailVerifiedAt == nil {
+		return nil, ErrOrgInvitationTokenRequired
+	}
+
+	return invitation, nil
+}
+
+// requireRole returns userID's membership of orgID if it grants at least
+// role.
+func (s *OrganizationService) requireRole(orgID, userID, role string) (*models.Membership, error) {
+	membership, err := s.orgRepo.GetMembership(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, ErrNotOrgMember
+	}
+
+	if orgRoleRank[membership.Role] < orgRoleRank[role] {
+		return nil, ErrOrgPermissionDenied
+	}
+
+	return membership, nil
+}
+
+// requireAnotherOwner fails with ErrLastOwner unless orgID has more than one
+// owner, so one of them can step down.
+func (s *OrganizationService) requireAnotherOwner(orgID string) error {
+	owners, err := s.orgRepo.CountOwners(orgID)
+	if err != nil {
+		return err
+	}
+	if owners < 2 {
+		return ErrLastOwner
+	}
+	return nil
+}
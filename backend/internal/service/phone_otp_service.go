@@ -0,0 +1,316 @@
+package service
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/internal/sms"
+	"backend/internal/textnorm"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPhone      = errors.New("invalid phone number")
+	ErrOTPCooldown       = errors.New("code was sent too recently")
+	ErrOTPRateLimited    = errors.New("too many codes requested")
+	ErrInvalidOTP        = errors.New("invalid or expired code")
+	ErrPhoneNameRequired = errors.New("name is required to sign up")
+)
+
+const (
+	// A new code may be requested once otpResendCooldown has passed, and at
+	// most otpMaxPerWindow codes are sent to one number within otpWindow.
+	otpResendCooldown = time.Minute
+	otpMaxPerWindow   = 5
+	otpWindow         = time.Hour
+
+	// A code is burned after this many wrong guesses.
+	otpMaxAttempts = 5
+)
+
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+type PhoneOTPService struct {
+	auth     *AuthService
+	userRepo *repository.UserRepository
+	otpRepo  *repository.PhoneOTPRepository
+	sender   sms.SMSSender
+	ttl      time.Duration
+}
+
+func NewPhoneOTPService(
+	auth *AuthService,
+	userRepo *repository.UserRepository,
+	otpRepo *repository.PhoneOTPRepository,
+	sender sms.SMSSender,
+	ttl time.Duration,
+) *PhoneOTPService {
+	return &PhoneOTPService{
+		auth:     auth,
+		userRepo: userRepo,
+		otpRepo:  otpRepo,
+		sender:   sender,
+		ttl:      ttl,
+	}
+}
+
+// SendCode texts a new code to phone. When the resend cooldown is still
+// running it returns ErrOTPCooldown along with the time left to wait.
+func (s *PhoneOTPService) SendCode(phone string) (time.Duration, error) {
+	phone, err := NormalizePhone(phone)
+	if err != nil {
+		return 0, err
+	}
+
+	lastSentAt, err := s.otpRepo.LastSentAt(phone)
+	if err != nil {
+		return 0, err
+	}
+	if wait := otpResendCooldown - time.Since(lastSentAt); wait > 0 {
+		return wait, ErrOTPCooldown
+	}
+
+	count, err := s.otpRepo.CountSince(phone, time.Now().Add(-otpWindow))
+	if err != nil {
+		return 0, err
+	}
+	if count >= otpMaxPerWindow {
+		return 0, ErrOTPRateLimited
+	}
+
+	// Only the newest code for a number is valid
+	if err := s.otpRepo.InvalidateByPhone(phone); err != nil {
+		return 0, err
+	}
+
+	code, err := randomCode()
+	if err != nil {
+		return 0, err
+	}
+
+	otp := &models.PhoneOTP{
+		ID:        uuid.New().String(),
+		Phone:     phone,
+		ExpiresAt: time.Now().Add(s.ttl),
+		CreatedAt: time.Now(),
+	}
+	otp.CodeHash = hashCode(otp.ID, code)
+
+	if err := s.otpRepo.Create(otp); err != nil {
+		return 0, err
+	}
+
+	message := fmt.Sprintf("Your MultiLangBloc code is %s. It expires in %d minutes.", code, int(s.ttl.Minutes()))
+	return 0, s.sender.Send(phone, message)
+}
+
+// VerifyPhoneInput is a phone number and the code sent to it. The other
+// fields are only needed when the number has no account yet and one is
+// created.
+type VerifyPhoneInput struct {
+	Phone string
+	Code  string
+	Name  string
+
+	// Solution to a challenge from RegistrationGuard.IssueChallenge
+	Challenge string
+	Nonce     string
+
+	// Required when registration is invite-only
+	InvitationCode string
+
+	// Versions of the legal documents the user accepted, by kind
+	Consents map[string]int
+}
+
+// Verify checks the code and logs the owner of the phone number in. If no
+// account uses the number yet, one is created like a registration: it has
+// to pass the proof-of-work challenge and velocity limits, needs an
+// invitation code when registration is invite-only, and records the
+// consents as accepted. The boolean result reports whether the account was
+// created.
+func (s *PhoneOTPService) Verify(input VerifyPhoneInput, client ClientInfo) (*models.User, string, string, bool, error) {
+	phone, err := NormalizePhone(input.Phone)
+	if err != nil {
+		return nil, "", "", false, err
+	}
+
+	otp, err := s.checkCode(phone, input.Code)
+	if err != nil {
+		return nil, "", "", false, err
+	}
+
+	user, err := s.userRepo.GetByPhone(phone)
+	if err != nil && err != repository.ErrUserNotFound {
+		return nil, "", "", false, err
+	}
+
+	// Leave the code usable so the client can retry and cancel the deletion
+	if user != nil {
+		if err := s.auth.checkPendingDeletion(user, models.LoginMethodPhoneOTP, client); err != nil {
+			return nil, "", "", false, err
+		}
+	}
+
+	// Leave the code usable so the client can retry with a name, consents
+	// or a new challenge
+	name := ""
+	riskScore := 0
+	if user == nil {
+		if strings.TrimSpace(input.Name) == "" {
+			return nil, "", "", false, ErrPhoneNameRequired
+		}
+		if name, err = textnorm.Name(input.Name); err != nil {
+			return nil, "", "", false, err
+		}
+		if _, err := s.auth.consentedDocuments(s.auth.legalRepo, input.Consents, client.Locale); err != nil {
+			return nil, "", "", false, err
+		}
+		if riskScore, err = s.auth.guard.Check(client, input.Challenge, input.Nonce); err != nil {
+			return nil, "", "", false, err
+		}
+	}
+
+	// Leave the code usable so the client can retry with an invitation
+	invitationID := ""
+	if user == nil {
+		invitationID, err = s.auth.policy.redeemInvitation(input.InvitationCode, "")
+		if err != nil {
+			return nil, "", "", false, err
+		}
+	}
+
+	ok, err := s.otpRepo.MarkUsed(otp.ID)
+	if err != nil {
+		s.auth.policy.releaseInvitation(invitationID)
+		return nil, "", "", false, err
+	}
+	if !ok {
+		s.auth.policy.releaseInvitation(invitationID)
+		return nil, "", "", false, ErrInvalidOTP
+	}
+
+	created := user == nil
+	event := newAuditEvent(models.AuditLoginSucceeded, "", "", client)
+	if created {
+		event.Action = models.AuditUserRegistered
+	}
+	event.Details = map[string]string{"method": models.LoginMethodPhoneOTP}
+
+	var accessToken, refreshToken string
+	err = s.auth.audit.TrackAll(func(tx *sql.Tx) ([]*models.AuditEvent, error) {
+		events := []*models.AuditEvent{event}
+		if created {
+			var err error
+			if user, err = s.userRepo.WithTx(tx).CreateWithPhone(phone, name, riskScore); err != nil {
+				return nil, err
+			}
+			if err := s.auth.identityRepo.WithTx(tx).Link(user.ID, models.IdentityPhone, phone); err != nil {
+				return nil, err
+			}
+
+			consentEvents, err := s.auth.recordConsents(tx, user.ID, input.Consents, client)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, consentEvents...)
+		}
+		event.ActorID, event.UserID = user.ID, user.ID
+
+		var err error
+		accessToken, refreshToken, err = s.auth.issueTokens(tx, user, time.Now(), []string{amrSMS, amrOTP}, "")
+		if err != nil {
+			return nil, err
+		}
+		return events, nil
+	})
+	if err != nil {
+		s.auth.policy.releaseInvitation(invitationID)
+		return nil, "", "", false, err
+	}
+
+	method := models.LoginMethodPhoneOTP
+	if created {
+		method = models.LoginMethodRegister
+	}
+	s.auth.history.Record(user, method, models.LoginOutcomeSuccess, client, false)
+
+	return user, accessToken, refreshToken, created, nil
+}
+
+// checkCode returns the active code for phone if code matches it, without
+// using it up. Wrong guesses count towards otpMaxAttempts.
+func (s *PhoneOTPService) checkCode(phone, code string) (*models.PhoneOTP, error) {
+	otp, err := s.otpRepo.GetActive(phone)
+	if err != nil {
+		return nil, err
+	}
+	if otp == nil {
+		return nil, ErrInvalidOTP
+	}
+
+	code = strings.TrimSpace(normalizeDigits(code))
+	expected := []byte(otp.CodeHash)
+	actual := []byte(hashCode(otp.ID, code))
+	if subtle.ConstantTimeCompare(expected, actual) != 1 {
+		attempts, err := s.otpRepo.IncrementAttempts(otp.ID)
+		if err != nil {
+			return nil, err
+		}
+		if attempts >= otpMaxAttempts {
+			if err := s.otpRepo.InvalidateByPhone(phone); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrInvalidOTP
+	}
+
+	return otp, nil
+}
+
+// NormalizePhone converts phone to E.164. It accepts Persian and
+// Arabic-Indic digits, common separators and a leading 00 instead of +.
+func NormalizePhone(phone string) (string, error) {
+	phone = normalizeDigits(phone)
+
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(phone) {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	phone = b.String()
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+
+	if !e164Pattern.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+	return phone, nil
+}
+
+// normalizeDigits replaces Arabic-Indic (U+0660–U+0669) and Extended
+// Arabic-Indic/Persian (U+06F0–U+06F9) digits with their ASCII equivalents.
+func normalizeDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '٠' && r <= '٩':
+			return '0' + (r - '٠')
+		case r >= '۰' && r <= '۹':
+			return '0' + (r - '۰')
+		}
+		return r
+	}, s)
+}
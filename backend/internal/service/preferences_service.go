@@ -0,0 +1,158 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/internal/i18n"
+	"backend/internal/models"
+	"backend/internal/repository"
+)
+
+var (
+	ErrInvalidTheme    = errors.New("unknown theme")
+	ErrInvalidTimeZone = errors.New("unknown time zone")
+)
+
+// PreferencesUpdate holds the preferences to change; nil fields stay as
+// they are.
+type PreferencesUpdate struct {
+	Locale   *string
+	Theme    *string
+	TimeZone *string
+
+	NotifyEmail     *bool
+	NotifyPush      *bool
+	NotifyMarketing *bool
+}
+
+// PreferencesService keeps the settings that follow a user across devices,
+// such as their language. The preferred locale and time zone are also put
+// into access tokens, so changing them returns a new token pair.
+type PreferencesService struct {
+	auth      *AuthService
+	prefsRepo *repository.PreferencesRepository
+	userRepo  *repository.UserRepository
+}
+
+func NewPreferencesService(auth *AuthService, prefsRepo *repository.PreferencesRepository, userRepo *repository.UserRepository) *PreferencesService {
+	return &PreferencesService{
+		auth:      auth,
+		prefsRepo: prefsRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// Get returns userID's preferences.
+func (s *PreferencesService) Get(userID string) (*models.Preferences, error) {
+	return s.prefsRepo.Get(userID)
+}
+
+// Update applies update to userID's preferences and returns them. If the
+// locale or time zone changed, it also returns a new token pair carrying
+// them, which replaces refreshToken; authTime, amr and orgID are carried
+// over from the caller's token. Otherwise the tokens are empty and
+// refreshToken isn't needed.
+func (s *PreferencesService) Update(userID string, update PreferencesUpdate, refreshToken string, authTime time.Time, amr []string, orgID string, client ClientInfo) (*models.Preferences, string, string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	prefs, err := s.prefsRepo.Get(userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var fields []string
+	tokenChanged := false
+	if update.Locale != nil {
+		locale := i18n.Supported(*update.Locale)
+		if locale == "" {
+			return nil, "", "", ErrUnsupportedLocale
+		}
+		tokenChanged = locale != prefs.Locale
+		prefs.Locale = locale
+		fields = append(fields, "locale")
+	}
+	if update.Theme != nil {
+		if !slices.Contains(models.Themes, *update.Theme) {
+			return nil, "", "", ErrInvalidTheme
+		}
+		prefs.Theme = *update.Theme
+		fields = append(fields, "theme")
+	}
+	if update.TimeZone != nil {
+		timeZone, err := checkTimeZone(*update.TimeZone)
+		if err != nil {
+			return nil, "", "", err
+		}
+		tokenChanged = tokenChanged || timeZone != prefs.TimeZone
+		prefs.TimeZone = timeZone
+		fields = append(fields, "time_zone")
+	}
+	if update.NotifyEmail != nil {
+		prefs.Notifications.Email = *update.NotifyEmail
+		fields = append(fields, "notifications.email")
+	}
+	if update.NotifyPush != nil {
+		prefs.Notifications.Push = *update.NotifyPush
+		fields = append(fields, "notifications.push")
+	}
+	if update.NotifyMarketing != nil {
+		prefs.Notifications.Marketing = *update.NotifyMarketing
+		fields = append(fields, "notifications.marketing")
+	}
+	if len(fields) == 0 {
+		return prefs, "", "", nil
+	}
+
+	prefs.UpdatedAt = time.Now()
+
+	var accessToken, newRefreshToken string
+	event := newAuditEvent(models.AuditPreferencesUpdated, userID, userID, client)
+	event.Details = map[string]string{"fields": strings.Join(fields, ",")}
+	err = s.auth.audit.Track(event, func(tx *sql.Tx) error {
+		if err := s.prefsRepo.WithTx(tx).Save(prefs); err != nil {
+			return err
+		}
+
+		if !tokenChanged {
+			return nil
+		}
+		var err error
+		accessToken, newRefreshToken, err = s.auth.reissueTokens(tx, user, refreshToken, authTime, amr, orgID)
+		return err
+	})
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return prefs, accessToken, newRefreshToken, nil
+}
+
+// initPreferences stores within tx the preferences userID starts with:
+// the defaults in the language of client. It does nothing if the user
+// already has preferences.
+func (s *AuthService) initPreferences(tx *sql.Tx, userID string, client ClientInfo) error {
+	prefs := models.DefaultPreferences(userID)
+	prefs.Locale = i18n.Match(client.Locale)
+	prefs.UpdatedAt = time.Now()
+
+	return s.prefsRepo.WithTx(tx).Init(prefs)
+}
+
+// checkTimeZone returns name if it is an IANA time zone.
+func checkTimeZone(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "Local" {
+		return "", ErrInvalidTimeZone
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return "", ErrInvalidTimeZone
+	}
+	return name, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/bits"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/repository"
+)
+
+var (
+	ErrChallengeFailed         = errors.New("proof-of-work challenge missing, expired or not solved")
+	ErrRegistrationRateLimited = errors.New("too many registrations from this network")
+)
+
+// RegistrationLimits configures the abuse controls on registration. A zero
+// difficulty disables the proof-of-work challenge and a zero limit disables
+// that velocity check.
+type RegistrationLimits struct {
+	Difficulty   int
+	ChallengeTTL time.Duration
+	IPLimit      int
+	SubnetLimit  int
+	Window       time.Duration
+}
+
+// RegistrationGuard slows down automated sign-ups with a self-hosted
+// proof-of-work challenge and per-IP and per-subnet velocity limits, and
+// scores how risky each registration looks.
+type RegistrationGuard struct {
+	challengeRepo *repository.RegistrationChallengeRepository
+	eventRepo     *repository.LoginEventRepository
+	limits        RegistrationLimits
+}
+
+func NewRegistrationGuard(
+	challengeRepo *repository.RegistrationChallengeRepository,
+	eventRepo *repository.LoginEventRepository,
+	limits RegistrationLimits,
+) *RegistrationGuard {
+	return &RegistrationGuard{
+		challengeRepo: challengeRepo,
+		eventRepo:     eventRepo,
+		limits:        limits,
+	}
+}
+
+// IssueChallenge creates a puzzle for the client to solve. It returns nil
+// when the challenge is disabled.
+func (g *RegistrationGuard) IssueChallenge(client ClientInfo) (*models.RegistrationChallenge, error) {
+	if g.limits.Difficulty == 0 {
+		return nil, nil
+	}
+
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &models.RegistrationChallenge{
+		ID:         id,
+		Difficulty: g.limits.Difficulty,
+		IP:         truncate(client.IP, 45),
+		ExpiresAt:  time.Now().Add(g.limits.ChallengeTTL),
+		CreatedAt:  time.Now(),
+	}
+
+	if err := g.challengeRepo.Create(challenge); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// Check enforces the challenge and velocity limits for a registration and
+// returns its risk score from 0 (benign) to 100.
+func (g *RegistrationGuard) Check(client ClientInfo, challengeID, nonce string) (int, error) {
+	score := 0
+
+	if g.limits.Difficulty > 0 {
+		if challengeID == "" || nonce == "" {
+			return 0, ErrChallengeFailed
+		}
+
+		challenge, err := g.challengeRepo.Consume(challengeID)
+		if err != nil {
+			return 0, err
+		}
+		if challenge == nil || !solvesChallenge(challenge, nonce) {
+			return 0, ErrChallengeFailed
+		}
+		// A solved challenge is only good from where it was requested
+		if challenge.IP != truncate(client.IP, 45) {
+			return 0, ErrChallengeFailed
+		}
+
+		// A human-driven client rarely finishes the form this quickly
+		if time.Since(challenge.CreatedAt) < 2*time.Second {
+			score += 20
+		}
+	}
+
+	since := time.Now().Add(-g.limits.Window)
+
+	if g.limits.IPLimit > 0 {
+		count, err := g.eventRepo.CountByIPSince(models.LoginMethodRegister, client.IP, since)
+		if err != nil {
+			return 0, err
+		}
+		if count >= g.limits.IPLimit {
+			return 0, ErrRegistrationRateLimited
+		}
+		score += 30 * count / g.limits.IPLimit
+	}
+
+	if network := networkOf(client.IP); g.limits.SubnetLimit > 0 && network != "" {
+		count, err := g.eventRepo.CountByNetworkSince(models.LoginMethodRegister, network, since)
+		if err != nil {
+			return 0, err
+		}
+		if count >= g.limits.SubnetLimit {
+			return 0, ErrRegistrationRateLimited
+		}
+		score += 20 * count / g.limits.SubnetLimit
+	}
+
+	if client.UserAgent == "" {
+		score += 30
+	}
+
+	return min(score, 100), nil
+}
+
+// solvesChallenge reports whether SHA-256(challenge + ":" + nonce) starts
+// with at least the challenge's difficulty in zero bits.
+func solvesChallenge(challenge *models.RegistrationChallenge, nonce string) bool {
+	sum := sha256.Sum256([]byte(challenge.ID + ":" + nonce))
+
+	zeros := 0
+	for _, b := range sum {
+		if b == 0 {
+			zeros += 8
+			continue
+		}
+		zeros += bits.LeadingZeros8(b)
+		break
+	}
+
+	return zeros >= challenge.Difficulty
+}
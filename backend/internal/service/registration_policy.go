@@ -0,0 +1,187 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"backend/internal/domainpolicy"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/internal/textnorm"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmailDomainNotAllowed   = errors.New("email domain is not allowed")
+	ErrEmailDomainBlocked      = errors.New("email domain is blocked")
+	ErrDisposableEmail         = errors.New("disposable email addresses are not allowed")
+	ErrInvitationRequired      = errors.New("an invitation code is required to register")
+	ErrPhoneInvitationRequired = errors.New("an invitation code is required to register with a phone number")
+	ErrInvalidInvitation       = errors.New("invalid or expired invitation code")
+)
+
+// invitationAlphabet leaves out characters that are easily confused when a
+// code is read aloud or typed from paper (0/O, 1/I/L).
+const invitationAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// RegistrationPolicy decides who may create an account: which email
+// domains are accepted and, in invite-only mode, who holds an invitation.
+type RegistrationPolicy struct {
+	domains        *domainpolicy.Policy
+	invitationRepo *repository.InvitationCodeRepository
+	inviteOnly     bool
+}
+
+func NewRegistrationPolicy(
+	domains *domainpolicy.Policy,
+	invitationRepo *repository.InvitationCodeRepository,
+	inviteOnly bool,
+) *RegistrationPolicy {
+	return &RegistrationPolicy{
+		domains:        domains,
+		invitationRepo: invitationRepo,
+		inviteOnly:     inviteOnly,
+	}
+}
+
+// InviteOnly reports whether registration requires an invitation code.
+func (p *RegistrationPolicy) InviteOnly() bool {
+	return p.inviteOnly
+}
+
+// CheckEmail rejects addresses whose domain the policy doesn't accept.
+func (p *RegistrationPolicy) CheckEmail(email string) error {
+	switch p.domains.Check(email) {
+	case domainpolicy.NotAllowed:
+		return ErrEmailDomainNotAllowed
+	case domainpolicy.Denied:
+		return ErrEmailDomainBlocked
+	case domainpolicy.Disposable:
+		return ErrDisposableEmail
+	}
+	return nil
+}
+
+// redeemInvitation spends one use of code for a registration with email,
+// which is empty for phone sign-ups. It returns the invitation's ID so the
+// use can be released if the registration fails, or "" when no invitation
+// is needed. Phone sign-ups have no domain the allow list could admit, so
+// with an allow list they need an invitation even when registration isn't
+// invite-only.
+func (p *RegistrationPolicy) redeemInvitation(code, email string) (string, error) {
+	phoneRestricted := email == "" && p.domains.Restricted()
+	if !p.inviteOnly && !phoneRestricted {
+		return "", nil
+	}
+
+	code = normalizeInvitationCode(code)
+	if code == "" {
+		if !p.inviteOnly {
+			return "", ErrPhoneInvitationRequired
+		}
+		return "", ErrInvitationRequired
+	}
+
+	id, err := p.invitationRepo.Redeem(hashToken(code), email)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", ErrInvalidInvitation
+	}
+
+	return id, nil
+}
+
+// releaseInvitation gives back a use taken by redeemInvitation when the
+// registration didn't go through. Failures are only logged, since the
+// caller is already handling another error.
+func (p *RegistrationPolicy) releaseInvitation(id string) {
+	if id == "" {
+		return
+	}
+	if err := p.invitationRepo.Release(id); err != nil {
+		log.Printf("Failed to release invitation %s: %v", id, err)
+	}
+}
+
+// CreateInvitation issues a code good for maxUses registrations. A non-empty
+// email limits it to that address, and a zero ttl means it never expires.
+// The code is only returned here; the database keeps its hash.
+func (p *RegistrationPolicy) CreateInvitation(createdBy, email string, maxUses int, ttl time.Duration) (*models.InvitationCode, string, error) {
+	if email != "" {
+		var err error
+		if email, err = textnorm.Email(email); err != nil {
+			return nil, "", err
+		}
+	}
+
+	code, err := randomInvitationCode()
+	if err != nil {
+		return nil, "", err
+	}
+
+	invitation := &models.InvitationCode{
+		ID:        uuid.New().String(),
+		CodeHash:  hashToken(normalizeInvitationCode(code)),
+		Email:     email,
+		MaxUses:   maxUses,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		invitation.ExpiresAt = &expiresAt
+	}
+
+	if err := p.invitationRepo.Create(invitation); err != nil {
+		return nil, "", err
+	}
+
+	return invitation, code, nil
+}
+
+func (p *RegistrationPolicy) ListInvitations() ([]*models.InvitationCode, error) {
+	return p.invitationRepo.List()
+}
+
+func (p *RegistrationPolicy) RevokeInvitation(id string) error {
+	return p.invitationRepo.Delete(id)
+}
+
+// randomInvitationCode returns a code like "7KQ4M-XW2PH".
+func randomInvitationCode() (string, error) {
+	size := big.NewInt(int64(len(invitationAlphabet)))
+
+	code := make([]byte, 0, 11)
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			code = append(code, '-')
+		}
+
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		code = append(code, invitationAlphabet[n.Int64()])
+	}
+
+	return string(code), nil
+}
+
+// normalizeInvitationCode makes codes match however they were typed: case,
+// dashes and spaces don't matter.
+func normalizeInvitationCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/internal/repository"
+)
+
+// Tables the retention service handles itself rather than through
+// RetentionRepository.Delete.
+const (
+	retentionAuditEvents   = "audit_events"
+	retentionGuestAccounts = "guest_accounts"
+)
+
+var ErrUnknownRetentionPolicy = errors.New("no retention policy for that table")
+
+// RetentionPolicy says how long rows of a table are kept. Rows that expire,
+// such as refresh tokens, are kept for MaxAge past their expiry, others for
+// MaxAge past their creation; zero keeps them forever. They are deleted
+// BatchSize at a time, so no delete holds locks for long. A DryRun policy
+// only counts what it would delete.
+type RetentionPolicy struct {
+	// Table is a table RetentionRepository knows, "audit_events" or
+	// "guest_accounts"
+	Table     string
+	MaxAge    time.Duration
+	BatchSize int
+	DryRun    bool
+}
+
+// RetentionPoliciesFromConfig turns the configured retention policies into
+// the ones the retention service applies.
+func RetentionPoliciesFromConfig(cfg config.RetentionConfig) []RetentionPolicy {
+	policies := make([]RetentionPolicy, 0, len(cfg.Policies))
+	for _, p := range cfg.Policies {
+		policies = append(policies, RetentionPolicy{
+			Table:     p.Table,
+			MaxAge:    p.MaxAge,
+			BatchSize: p.BatchSize,
+			DryRun:    p.DryRun,
+		})
+	}
+	return policies
+}
+
+// RetentionReport tells what applying a policy purged, or would have
+// purged in a dry run.
+type RetentionReport struct {
+	Table  string
+	Purged int64
+	DryRun bool
+}
+
+// RetentionService deletes data that is no longer needed. Deleted guest
+// accounts and purged audit events are audited themselves; see
+// models.AuditEvent for how the audit log stays verifiable.
+type RetentionService struct {
+	audit         *AuditService
+	retentionRepo *repository.RetentionRepository
+	userRepo      *repository.UserRepository
+	policies      []RetentionPolicy
+}
+
+func NewRetentionService(
+	audit *AuditService,
+	retentionRepo *repository.RetentionRepository,
+	userRepo *repository.UserRepository,
+	policies []RetentionPolicy,
+) *RetentionService {
+	return &RetentionService{
+		audit:         audit,
+		retentionRepo: retentionRepo,
+		userRepo:      userRepo,
+		policies:      policies,
+	}
+}
+
+// Run applies every policy, then again every interval, and logs what was
+// purged. It never returns, so run it in its own goroutine.
+func (s *RetentionService) Run(interval time.Duration) {
+	for {
+		for _, policy := range s.policies {
+			report, err := s.apply(policy, false)
+			if err != nil {
+				log.Printf("Retention of %s failed: %v", policy.Table, err)
+				continue
+			}
+			if report.Purged == 0 {
+				continue
+			}
+			if report.DryRun {
+				log.Printf("🧹 Would purge %d row(s) from %s (dry run)", report.Purged, report.Table)
+			} else {
+				log.Printf("🧹 Purged %d row(s) from %s", report.Purged, report.Table)
+			}
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// Purge applies the policies of tables, or every policy if tables is
+// empty, and reports what each purged. With dryRun nothing is deleted.
+// It stops at the first policy that fails.
+func (s *RetentionService) Purge(tables []string, dryRun bool) ([]RetentionReport, error) {
+	for _, table := range tables {
+		if !slices.ContainsFunc(s.policies, func(p RetentionPolicy) bool { return p.Table == table }) {
+			return nil, ErrUnknownRetentionPolicy
+		}
+	}
+
+	var reports []RetentionReport
+	for _, policy := range s.policies {
+		if len(tables) > 0 && !slices.Contains(tables, policy.Table) {
+			continue
+		}
+
+		report, err := s.apply(policy, dryRun)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func (s *RetentionService) apply(policy RetentionPolicy, dryRun bool) (RetentionReport, error) {
+	report := RetentionReport{Table: policy.Table, DryRun: dryRun || policy.DryRun}
+	if policy.MaxAge <= 0 {
+		return report, nil
+	}
+	cutoff := time.Now().Add(-policy.MaxAge)
+	batchSize := max(policy.BatchSize, 1)
+
+	if report.DryRun {
+		var err error
+		report.Purged, err = s.count(policy.Table, cutoff)
+		return report, err
+	}
+
+	for {
+		deleted, err := s.deleteBatch(policy.Table, cutoff, batchSize)
+		report.Purged += deleted
+		if err != nil {
+			return report, err
+		}
+		if deleted < int64(batchSize) {
+			return report, nil
+		}
+	}
+}
+
+func (s *RetentionService) count(table string, cutoff time.Time) (int64, error) {
+	switch table {
+	case retentionAuditEvents:
+		return s.retentionRepo.CountAuditEvents(cutoff)
+	case retentionGuestAccounts:
+		return s.userRepo.CountInactiveGuests(cutoff)
+	default:
+		return s.retentionRepo.Count(table, cutoff)
+	}
+}
+
+// deleteBatch deletes up to limit rows of table older than cutoff and
+// returns how many it deleted.
+func (s *RetentionService) deleteBatch(table string, cutoff time.Time, limit int) (int64, error) {
+	switch table {
+	case retentionAuditEvents:
+		var deleted int64
+		err := s.audit.TrackAll(func(tx *sql.Tx) ([]*models.AuditEvent, error) {
+			var throughSeq int64
+			var throughHash string
+			var err error
+			deleted, throughSeq, throughHash, err = s.retentionRepo.DeleteAuditEvents(tx, cutoff, limit)
+			if err != nil || deleted == 0 {
+				return nil, err
+			}
+
+			event := newAuditEvent(models.AuditEventsPurged, models.AuditActorSystem, "", ClientInfo{})
+			event.Details = map[string]string{
+				"count":        strconv.FormatInt(deleted, 10),
+				"through_seq":  strconv.FormatInt(throughSeq, 10),
+				"through_hash": throughHash,
+			}
+			return []*models.AuditEvent{event}, nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		return deleted, nil
+
+	case retentionGuestAccounts:
+		var deleted []string
+		err := s.audit.TrackAll(func(tx *sql.Tx) ([]*models.AuditEvent, error) {
+			var err error
+			deleted, err = s.userRepo.WithTx(tx).DeleteInactiveGuests(cutoff, limit)
+			return systemDeletionEvents(deleted, "inactive_guest"), err
+		})
+		if err != nil {
+			return 0, err
+		}
+		return int64(len(deleted)), nil
+
+	default:
+		return s.retentionRepo.Delete(table, cutoff, limit)
+	}
+}
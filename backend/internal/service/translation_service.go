@@ -0,0 +1,342 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/arb"
+	"backend/internal/i18n"
+	"backend/internal/models"
+	"backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidARB                 = errors.New("bundle is not an ARB file")
+	ErrARBLocaleMismatch          = errors.New("bundle's @@locale doesn't match")
+	ErrTranslationTemplateMissing = errors.New("no English template is published")
+	ErrTranslationNotPending      = errors.New("translation bundle was already reviewed")
+)
+
+// BundleIssue is a problem with one message of an uploaded bundle: its
+// code and the arguments of the code's message.
+type BundleIssue struct {
+	Key  string
+	Code string
+	Args []interface{}
+}
+
+// InvalidBundleError lists every problem with the messages of an uploaded
+// bundle, so translators can fix them all at once.
+type InvalidBundleError struct {
+	Issues []BundleIssue
+}
+
+func (e *InvalidBundleError) Error() string {
+	return strconv.Itoa(len(e.Issues)) + " invalid message(s) in translation bundle"
+}
+
+// PublishedTranslations is the bundle the app is served for a locale: the
+// published translations, completed from the English template.
+type PublishedTranslations struct {
+	Locale   string
+	Messages map[string]string
+
+	// Versions of the translations and of the template they were completed
+	// from; 0 if the locale has no published translations
+	Version         int
+	TemplateVersion int
+}
+
+// TranslationService keeps the app's translations, uploaded as ARB files
+// per locale, so strings can change without releasing the app. Uploaded
+// bundles are checked against the English template and wait for review
+// before the app is served them.
+type TranslationService struct {
+	auth            *AuthService
+	translationRepo *repository.TranslationRepository
+}
+
+func NewTranslationService(auth *AuthService, translationRepo *repository.TranslationRepository) *TranslationService {
+	return &TranslationService{
+		auth:            auth,
+		translationRepo: translationRepo,
+	}
+}
+
+// Upload checks the ARB file data and stores its messages as the next
+// version of the bundle of locale, pending review. The messages of other
+// languages must have keys of the published English template and use the
+// same placeholders; those of the template must declare theirs in their
+// metadata.
+func (s *TranslationService) Upload(adminID, locale string, data []byte, client ClientInfo) (*models.TranslationBundle, error) {
+	if i18n.Supported(locale) != locale {
+		return nil, ErrUnsupportedLocale
+	}
+
+	file, err := arb.Parse(data)
+	if err != nil {
+		return nil, ErrInvalidARB
+	}
+	if file.Locale != "" && i18n.Supported(file.Locale) != locale {
+		return nil, ErrARBLocaleMismatch
+	}
+
+	var issues []BundleIssue
+	if locale == i18n.Default {
+		issues = checkTemplate(file)
+	} else {
+		template, err := s.translationRepo.LatestPublished(i18n.Default)
+		if err == repository.ErrTranslationBundleNotFound {
+			return nil, ErrTranslationTemplateMissing
+		}
+		if err != nil {
+			return nil, err
+		}
+		issues = checkTranslation(file, template.Messages)
+	}
+	if len(issues) > 0 {
+		return nil, &InvalidBundleError{Issues: issues}
+	}
+
+	bundle := &models.TranslationBundle{
+		ID:         uuid.New().String(),
+		Locale:     locale,
+		Status:     models.TranslationPending,
+		Messages:   file.Messages,
+		UploadedBy: adminID,
+		UploadedAt: time.Now(),
+	}
+
+	event := newAuditEvent(models.AuditTranslationUploaded, adminID, "", client)
+	err = s.auth.audit.Track(event, func(tx *sql.Tx) error {
+		translationRepo := s.translationRepo.WithTx(tx)
+
+		var err error
+		if bundle.Version, err = translationRepo.NextVersion(locale); err != nil {
+			return err
+		}
+		if err := translationRepo.Create(bundle); err != nil {
+			return err
+		}
+
+		event.Details = map[string]string{
+			"locale":  locale,
+			"version": strconv.Itoa(bundle.Version),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// Bundles returns the bundles of locale without their messages, newest
+// first.
+func (s *TranslationService) Bundles(locale string) ([]*models.TranslationBundle, error) {
+	if i18n.Supported(locale) != locale {
+		return nil, ErrUnsupportedLocale
+	}
+	return s.translationRepo.List(locale)
+}
+
+// Bundle returns version of the bundle of locale for review, with the keys
+// of the published English template it has no message for, which the app
+// would show in English.
+func (s *TranslationService) Bundle(locale string, version int) (*models.TranslationBundle, []string, error) {
+	bundle, err := s.translationRepo.Get(locale, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if locale == i18n.Default {
+		return bundle, []string{}, nil
+	}
+
+	template, err := s.translationRepo.LatestPublished(i18n.Default)
+	if err == repository.ErrTranslationBundleNotFound {
+		return bundle, []string{}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	missing := []string{}
+	for key := range template.Messages {
+		if _, ok := bundle.Messages[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+
+	return bundle, missing, nil
+}
+
+// Review publishes version of the bundle of locale, which the app is then
+// served, or rejects it with note explaining why.
+func (s *TranslationService) Review(adminID, locale string, version int, publish bool, note string, client ClientInfo) (*models.TranslationBundle, error) {
+	bundle, err := s.translationRepo.Get(locale, version)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.Status != models.TranslationPending {
+		return nil, ErrTranslationNotPending
+	}
+
+	now := time.Now()
+	bundle.ReviewedBy = adminID
+	bundle.ReviewedAt = &now
+	bundle.ReviewNote = strings.TrimSpace(note)
+
+	action := models.AuditTranslationRejected
+	bundle.Status = models.TranslationRejected
+	if publish {
+		action = models.AuditTranslationPublished
+		bundle.Status = models.TranslationPublished
+	}
+
+	event := newAuditEvent(action, adminID, "", client)
+	event.Details = map[string]string{
+		"locale":  locale,
+		"version": strconv.Itoa(version),
+	}
+	err = s.auth.audit.Track(event, func(tx *sql.Tx) error {
+		err := s.translationRepo.WithTx(tx).Review(bundle)
+		if err == repository.ErrTranslationBundleNotFound {
+			// Reviewed by someone else in the meantime
+			return ErrTranslationNotPending
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// Published returns the bundle the app is served for locale: the messages
+// of the published English template, replaced by those of the latest
+// published translation into locale that still match the template.
+func (s *TranslationService) Published(locale string) (*PublishedTranslations, error) {
+	if i18n.Supported(locale) != locale {
+		return nil, ErrUnsupportedLocale
+	}
+
+	template, err := s.translationRepo.LatestPublished(i18n.Default)
+	if err != nil {
+		return nil, err
+	}
+
+	published := &PublishedTranslations{
+		Locale:          locale,
+		Messages:        template.Messages,
+		TemplateVersion: template.Version,
+	}
+	if locale == i18n.Default {
+		published.Version = template.Version
+		return published, nil
+	}
+
+	bundle, err := s.translationRepo.LatestPublished(locale)
+	if err == repository.ErrTranslationBundleNotFound {
+		return published, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	published.Version = bundle.Version
+	published.Messages = make(map[string]string, len(template.Messages))
+	for key, message := range template.Messages {
+		published.Messages[key] = message
+
+		// The template may have changed since the translation was
+		// published
+		translated, ok := bundle.Messages[key]
+		if !ok {
+			continue
+		}
+		want, _ := arb.Arguments(message)
+		got, err := arb.Arguments(translated)
+		if err == nil && slices.Equal(got, want) {
+			published.Messages[key] = translated
+		}
+	}
+
+	return published, nil
+}
+
+// checkTemplate returns the problems with the messages of file as the
+// English template: they must be valid ICU messages whose placeholders are
+// declared, as the Flutter tools require.
+func checkTemplate(file *arb.File) []BundleIssue {
+	var issues []BundleIssue
+	for _, key := range sortedKeys(file.Messages) {
+		used, err := arb.Arguments(file.Messages[key])
+		if err != nil {
+			issues = append(issues, BundleIssue{Key: key, Code: "invalid_icu_message"})
+			continue
+		}
+		for _, name := range used {
+			if !slices.Contains(file.Placeholders[key], name) {
+				issues = append(issues, BundleIssue{Key: key, Code: "placeholder_undeclared", Args: []interface{}{name}})
+			}
+		}
+	}
+	return issues
+}
+
+// checkTranslation returns the problems with the messages of file as a
+// translation of template: each must translate a message of template and
+// use the same placeholders.
+func checkTranslation(file *arb.File, template map[string]string) []BundleIssue {
+	var issues []BundleIssue
+	for _, key := range sortedKeys(file.Messages) {
+		original, ok := template[key]
+		if !ok {
+			issues = append(issues, BundleIssue{Key: key, Code: "unknown_message_key"})
+			continue
+		}
+
+		used, err := arb.Arguments(file.Messages[key])
+		if err != nil {
+			issues = append(issues, BundleIssue{Key: key, Code: "invalid_icu_message"})
+			continue
+		}
+		want, _ := arb.Arguments(original)
+		switch {
+		case slices.Equal(used, want):
+		case len(want) == 0:
+			issues = append(issues, BundleIssue{Key: key, Code: "placeholders_not_allowed"})
+		default:
+			issues = append(issues, BundleIssue{Key: key, Code: "placeholder_mismatch", Args: []interface{}{placeholderList(want)}})
+		}
+	}
+	return issues
+}
+
+// placeholderList writes names as "{a}, {b}" for messages.
+func placeholderList(names []string) string {
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = "{" + name + "}"
+	}
+	return strings.Join(list, ", ")
+}
+
+func sortedKeys(messages map[string]string) []string {
+	keys := make([]string, 0, len(messages))
+	for key := range messages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func (s *AuthService) ListTrustedDevices(userID string) ([]*models.TrustedDevice, error) {
+	return s.deviceRepo.ListByUserID(userID)
+}
+
+func (s *AuthService) RevokeTrustedDevice(userID, deviceID string) error {
+	return s.deviceRepo.Delete(userID, deviceID)
+}
+
+func (s *AuthService) RevokeAllTrustedDevices(userID string) error {
+	return s.deviceRepo.DeleteByUserID(userID)
+}
+
+// checkTrustedDevice reports whether token identifies an unexpired trusted
+// device of userID.
+func (s *AuthService) checkTrustedDevice(userID, token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+
+	device, err := s.deviceRepo.GetActive(userID, hashToken(token))
+	if err != nil {
+		return false, err
+	}
+	if device == nil {
+		return false, nil
+	}
+
+	if err := s.deviceRepo.Touch(device.ID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// trustDevice remembers the client's device and returns the token it must
+// present on later logins. Only the token's hash is stored.
+func (s *AuthService) trustDevice(userID string, client ClientInfo) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	device := &models.TrustedDevice{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		TokenHash:  hashToken(token),
+		Name:       truncate(client.DeviceName, 100),
+		UserAgent:  truncate(client.UserAgent, 500),
+		ExpiresAt:  time.Now().Add(s.trustedDeviceTTL),
+		LastUsedAt: time.Now(),
+		CreatedAt:  time.Now(),
+	}
+
+	if err := s.deviceRepo.Create(device); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) > maxLen {
+		return string(runes[:maxLen])
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package sms
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// SMSSender delivers text messages to E.164 phone numbers.
+type SMSSender interface {
+	Send(to, message string) error
+}
+
+// LogSender writes messages to the service log instead of delivering them.
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(to, message string) error {
+	log.Printf("📱 SMS to=%s\n%s", to, message)
+	return nil
+}
+
+// FileSender appends every message as a JSON line to a file, so tests and
+// local tooling can read the codes that would have been sent.
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+type fileMessage struct {
+	To      string    `json:"to"`
+	Message string    `json:"message"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+func (s *FileSender) Send(to, message string) error {
+	line, err := json.Marshal(fileMessage{To: to, Message: message, SentAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
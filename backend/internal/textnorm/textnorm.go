@@ -0,0 +1,178 @@
+// Package textnorm normalizes and validates user-supplied text such as
+// display names and email addresses. Names may be written in any script,
+// so the rules are about invisible and direction-changing characters, not
+// about which letters are allowed.
+package textnorm
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrEmpty               = errors.New("value is empty")
+	ErrTooLong             = errors.New("value is too long")
+	ErrForbiddenCharacters = errors.New("value contains characters that are not allowed")
+	ErrInvalidEmail        = errors.New("invalid email address")
+)
+
+// MaxNameLength is the longest name accepted, in user-perceived characters
+// (grapheme clusters), so a name in Arabic script with diacritics or one
+// with emoji counts the same as it looks.
+const MaxNameLength = 100
+
+const (
+	zeroWidthNonJoiner = '\u200c'
+	zeroWidthJoiner    = '\u200d'
+)
+
+// Name returns s in NFC with surrounding space trimmed and runs of white
+// space collapsed to one space. Invisible characters that have no place in
+// a name are removed. Zero-width (non-)joiners are kept between two
+// visible characters, where Persian and emoji sequences need them, and
+// dropped elsewhere. Bidi embedding, override and isolate controls and
+// other control characters make the name invalid, since they can reorder
+// how surrounding text is displayed.
+func Name(s string) (string, error) {
+	s = norm.NFC.String(s)
+
+	var runes []rune
+	pendingSpace := false
+	for _, r := range s {
+		switch {
+		case isBidiControl(r):
+			return "", ErrForbiddenCharacters
+		case unicode.IsSpace(r):
+			runes = trimJoiners(runes)
+			pendingSpace = len(runes) > 0
+			continue
+		case unicode.Is(unicode.Cc, r), unicode.Is(unicode.Co, r), r == unicode.ReplacementChar:
+			return "", ErrForbiddenCharacters
+		case r == zeroWidthNonJoiner || r == zeroWidthJoiner:
+			// Only between two visible characters
+			if len(runes) == 0 || pendingSpace || isJoiner(runes[len(runes)-1]) {
+				continue
+			}
+		case isTagCharacter(r):
+			// Part of emoji flag sequences such as England's
+		case unicode.Is(unicode.Cf, r):
+			// Zero-width space, word joiner, BOM, soft hyphen, LRM/RLM, ...
+			continue
+		}
+
+		if pendingSpace {
+			runes = append(runes, ' ')
+			pendingSpace = false
+		}
+		runes = append(runes, r)
+	}
+
+	// Removing characters can leave sequences that compose further
+	name := norm.NFC.String(string(trimJoiners(runes)))
+	if name == "" {
+		return "", ErrEmpty
+	}
+	if uniseg.GraphemeClusterCount(name) > MaxNameLength {
+		return "", ErrTooLong
+	}
+
+	return name, nil
+}
+
+// Email returns the canonical form of an email address: NFC, lower case,
+// and the domain in its ASCII (IDNA/punycode) form. Turkish dotted and
+// dotless i are folded to a plain i, since they mostly turn up when a
+// client lower- or upper-cases an address under a Turkish locale. The
+// address must not contain white space or invisible characters.
+func Email(s string) (string, error) {
+	s = norm.NFC.String(strings.TrimSpace(s))
+
+	for _, r := range s {
+		if unicode.IsSpace(r) || unicode.Is(unicode.C, r) {
+			return "", ErrInvalidEmail
+		}
+	}
+
+	at := strings.LastIndex(s, "@")
+	if at <= 0 || at == len(s)-1 {
+		return "", ErrInvalidEmail
+	}
+
+	local := strings.ToLower(foldTurkishI(s[:at]))
+	if strings.Contains(local, "@") || len(local) > 64 {
+		return "", ErrInvalidEmail
+	}
+
+	domain, err := Domain(s[at+1:])
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", ErrInvalidEmail
+	}
+
+	email := local + "@" + domain
+	if len(email) > 254 {
+		return "", ErrInvalidEmail
+	}
+
+	return email, nil
+}
+
+// Domain returns the lower-case ASCII form of an internationalized domain
+// name, e.g. "bücher.example" becomes "xn--bcher-kva.example".
+func Domain(domain string) (string, error) {
+	domain = strings.TrimSuffix(norm.NFC.String(strings.TrimSpace(domain)), ".")
+
+	// A dotless i in an otherwise ASCII domain is a locale case-mapping
+	// slip, not an IDN
+	if folded := foldTurkishI(domain); isASCII(folded) {
+		domain = folded
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", ErrInvalidEmail
+	}
+
+	return ascii, nil
+}
+
+func foldTurkishI(s string) string {
+	return strings.NewReplacer("İ", "i", "ı", "i").Replace(s)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func isJoiner(r rune) bool {
+	return r == zeroWidthNonJoiner || r == zeroWidthJoiner
+}
+
+// trimJoiners drops joiners at the end of runes, where nothing follows
+// them to join.
+func trimJoiners(runes []rune) []rune {
+	for len(runes) > 0 && isJoiner(runes[len(runes)-1]) {
+		runes = runes[:len(runes)-1]
+	}
+	return runes
+}
+
+// isBidiControl reports whether r is an explicit directional embedding,
+// override or isolate (U+202A–U+202E, U+2066–U+2069).
+func isBidiControl(r rune) bool {
+	return (r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069')
+}
+
+func isTagCharacter(r rune) bool {
+	return r >= 0xE0020 && r <= 0xE007F
+}
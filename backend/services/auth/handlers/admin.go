@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/internal/repository"
+	"backend/internal/service"
+)
+
+// RequireActiveUser returns a wrapper for the handlers of authenticated
+// endpoints that turns away callers whose account was deleted or is
+// scheduled for deletion, whose access tokens haven't expired yet.
+// Requests without a token are left to the handler to reject.
+func RequireActiveUser(authService *service.AuthService) func(http.HandlerFunc) http.Handler {
+	return func(next http.HandlerFunc) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := getUserIDFromToken(r)
+			if userID == "" {
+				next(w, r)
+				return
+			}
+
+			if err := authService.CheckActive(userID); err != nil {
+				switch {
+				case err == repository.ErrUserNotFound:
+					respondError(w, r, http.StatusUnauthorized, "unauthorized")
+				case !respondServiceError(w, r, err):
+					respondError(w, r, http.StatusInternalServerError, "internal_error")
+				}
+				return
+			}
+
+			next(w, r)
+		})
+	}
+}
+
+// requireAdmin checks that the caller is an administrator. It writes the
+// error response and returns false otherwise.
+func requireAdmin(w http.ResponseWriter, r *http.Request, authService *service.AuthService) bool {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return false
+	}
+
+	isAdmin, err := authService.IsAdmin(userID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "check_permissions_failed")
+		return false
+	}
+
+	if !isAdmin {
+		respondError(w, r, http.StatusForbidden, "forbidden")
+		return false
+	}
+
+	return true
+}
@@ -2,10 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
-	"backend/internal/repository"
+	"backend/internal/datefmt"
+	"backend/internal/i18n"
+	"backend/internal/models"
 	"backend/internal/service"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -25,11 +30,37 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Name     string `json:"name"`
+
+	// Proof-of-work solution, required unless challenges are disabled
+	Challenge string `json:"challenge"`
+	Nonce     string `json:"nonce"`
+
+	// Required when registration is invite-only
+	InvitationCode string `json:"invitation_code"`
+
+	// Versions of the legal documents the user accepted, by kind
+	Consents map[string]int `json:"consents"`
+
+	// Device locale, the user's initial preferred locale; defaults to
+	// Accept-Language
+	Locale string `json:"locale"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+
+	// Device locale, used as the preferred locale if the user has none yet;
+	// defaults to Accept-Language
+	Locale string `json:"locale"`
+
+	// Optional trusted-device fields
+	DeviceToken    string `json:"device_token"`
+	RememberDevice bool   `json:"remember_device"`
+	DeviceName     string `json:"device_name"`
+
+	// Restores an account scheduled for deletion
+	CancelDeletion bool `json:"cancel_deletion"`
 }
 
 type RefreshTokenRequest struct {
@@ -40,87 +71,150 @@ type UpdateProfileRequest struct {
 	Name string `json:"name"`
 }
 
+type ReauthenticateRequest struct {
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	NewPassword string `json:"new_password"`
+}
+
+type ChangeEmailRequest struct {
+	Email string `json:"email"`
+}
+
 type AuthResponse struct {
 	User struct {
 		ID        string `json:"id"`
 		Email     string `json:"email"`
+		Phone     string `json:"phone,omitempty"`
 		Name      string `json:"name"`
+		Guest     bool   `json:"guest,omitempty"`
 		CreatedAt string `json:"created_at"`
+
+		// CreatedAt in the language and time zone of the response
+		CreatedAtDisplay string `json:"created_at_display"`
 	} `json:"user"`
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+
+	// Set on password login only
+	TrustedDevice bool   `json:"trusted_device,omitempty"`
+	DeviceToken   string `json:"device_token,omitempty"`
+}
+
+func newAuthResponse(user *models.User, accessToken, refreshToken string, dates *datefmt.Formatter) AuthResponse {
+	response := AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+	response.User.ID = user.ID
+	response.User.Email = user.Email
+	response.User.Phone = user.Phone
+	response.User.Name = user.Name
+	response.User.Guest = user.Role == models.RoleGuest
+	response.User.CreatedAt = datefmt.Timestamp(user.CreatedAt)
+	response.User.CreatedAtDisplay = dates.Format(user.CreatedAt)
+	return response
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
 		return
 	}
 
 	// Validation
-	if req.Email == "" || req.Password == "" || req.Name == "" {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "Email, password, and name are required"})
+	if !requireFields(w, r, "registration_fields_required", "email", req.Email, "password", req.Password, "name", req.Name) {
 		return
 	}
 
 	if len(req.Password) < 8 {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "Password must be at least 8 characters"})
+		respondFieldError(w, r, http.StatusBadRequest, "password_too_short", "password")
 		return
 	}
 
-	user, accessToken, refreshToken, err := h.authService.Register(req.Email, req.Password, req.Name)
+	client := clientInfo(r)
+	if req.Locale != "" {
+		client.Locale = req.Locale
+	}
+
+	user, accessToken, refreshToken, err := h.authService.Register(service.RegisterInput{
+		Email:          req.Email,
+		Password:       req.Password,
+		Name:           req.Name,
+		Challenge:      req.Challenge,
+		Nonce:          req.Nonce,
+		InvitationCode: req.InvitationCode,
+		Consents:       req.Consents,
+	}, client)
 	if err != nil {
-		if err == repository.ErrEmailAlreadyExists {
-			respondJSON(w, http.StatusConflict, map[string]string{"error": "Email already exists"})
-			return
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "register_failed")
 		}
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to register user"})
 		return
 	}
 
-	response := AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+	respondJSON(w, http.StatusCreated, newAuthResponse(user, accessToken, refreshToken, requestDates(r)))
+}
+
+// RegisterChallenge issues the proof-of-work puzzle that must be solved
+// before registering.
+func (h *AuthHandler) RegisterChallenge(w http.ResponseWriter, r *http.Request) {
+	challenge, err := h.authService.IssueRegistrationChallenge(clientInfo(r))
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "create_challenge_failed")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	// Challenges are disabled in this environment
+	if challenge == nil {
+		respondJSON(w, http.StatusOK, map[string]interface{}{"difficulty": 0})
+		return
 	}
-	response.User.ID = user.ID
-	response.User.Email = user.Email
-	response.User.Name = user.Name
-	response.User.CreatedAt = user.CreatedAt.Format("2006-01-02T15:04:05Z")
 
-	respondJSON(w, http.StatusCreated, response)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"challenge":  challenge.ID,
+		"difficulty": challenge.Difficulty,
+		"algorithm":  "sha256",
+		"expires_at": datefmt.Timestamp(challenge.ExpiresAt),
+	})
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
 		return
 	}
 
-	if req.Email == "" || req.Password == "" {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "Email and password are required"})
+	if !requireFields(w, r, "credentials_required", "email", req.Email, "password", req.Password) {
 		return
 	}
 
-	user, accessToken, refreshToken, err := h.authService.Login(req.Email, req.Password)
+	client := clientInfo(r)
+	if req.Locale != "" {
+		client.Locale = req.Locale
+	}
+	client.DeviceToken = req.DeviceToken
+	client.RememberDevice = req.RememberDevice
+	client.DeviceName = req.DeviceName
+	client.CancelDeletion = req.CancelDeletion
+
+	result, err := h.authService.Login(req.Email, req.Password, client)
 	if err != nil {
-		if err == service.ErrInvalidCredentials {
-			respondJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid credentials"})
-			return
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "login_failed")
 		}
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to login"})
 		return
 	}
 
-	response := AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}
-	response.User.ID = user.ID
-	response.User.Email = user.Email
-	response.User.Name = user.Name
-	response.User.CreatedAt = user.CreatedAt.Format("2006-01-02T15:04:05Z")
+	response := newAuthResponse(result.User, result.AccessToken, result.RefreshToken, requestDates(r))
+	response.TrustedDevice = result.TrustedDevice
+	response.DeviceToken = result.DeviceToken
 
 	respondJSON(w, http.StatusOK, response)
 }
@@ -128,22 +222,19 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	var req RefreshTokenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
 		return
 	}
 
-	if req.RefreshToken == "" {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "Refresh token is required"})
+	if !requireFields(w, r, "refresh_token_required", "refresh_token", req.RefreshToken) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.authService.RefreshToken(req.RefreshToken)
+	accessToken, refreshToken, err := h.authService.RefreshToken(req.RefreshToken, clientInfo(r))
 	if err != nil {
-		if err == service.ErrInvalidToken {
-			respondJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid refresh token"})
-			return
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "refresh_failed")
 		}
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to refresh token"})
 		return
 	}
 
@@ -159,58 +250,74 @@ func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	// 1. Generate a password reset token
 	// 2. Send email with reset link
 	// 3. Store token in database with expiry
-	respondJSON(w, http.StatusOK, map[string]string{
-		"message": "Password reset email sent",
-	})
+	respondMessage(w, r, http.StatusOK, "password_reset_sent", nil)
 }
 
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromToken(r)
 	if userID == "" {
-		respondJSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	user, err := h.authService.GetUserByID(userID)
 	if err != nil {
-		if err == repository.ErrUserNotFound {
-			respondJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
-			return
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "get_profile_failed")
 		}
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to get profile"})
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"id":         user.ID,
-		"email":      user.Email,
-		"name":       user.Name,
-		"created_at": user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		"updated_at": user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-	})
+	dates := requestDates(r)
+	response := map[string]interface{}{
+		"id":                 user.ID,
+		"email":              user.Email,
+		"name":               user.Name,
+		"created_at":         datefmt.Timestamp(user.CreatedAt),
+		"created_at_display": dates.Format(user.CreatedAt),
+		"updated_at":         datefmt.Timestamp(user.UpdatedAt),
+		"updated_at_display": dates.Format(user.UpdatedAt),
+	}
+	if user.Phone != "" {
+		response["phone"] = user.Phone
+	}
+	if user.Role == models.RoleGuest {
+		response["guest"] = true
+	}
+	identities, err := h.authService.Identities(user.ID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "get_profile_failed")
+		return
+	}
+	response["identities"] = identities
+
+	respondJSON(w, http.StatusOK, response)
 }
 
 func (h *AuthHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromToken(r)
 	if userID == "" {
-		respondJSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	users, err := h.authService.ListUsers()
 	if err != nil {
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list users"})
+		respondError(w, r, http.StatusInternalServerError, "list_users_failed")
 		return
 	}
 
+	dates := requestDates(r)
 	response := make([]map[string]string, 0, len(users))
 	for _, user := range users {
 		response = append(response, map[string]string{
-			"id":         user.ID,
-			"email":      user.Email,
-			"name":       user.Name,
-			"created_at": user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			"updated_at": user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+			"id":                 user.ID,
+			"email":              user.Email,
+			"name":               user.Name,
+			"created_at":         datefmt.Timestamp(user.CreatedAt),
+			"created_at_display": dates.Format(user.CreatedAt),
+			"updated_at":         datefmt.Timestamp(user.UpdatedAt),
+			"updated_at_display": dates.Format(user.UpdatedAt),
 		})
 	}
 
@@ -220,94 +327,207 @@ func (h *AuthHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromToken(r)
 	if userID == "" {
-		respondJSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	id := r.PathValue("id")
 	if id == "" {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "User id is required"})
+		respondError(w, r, http.StatusBadRequest, "user_id_required")
 		return
 	}
 
 	user, err := h.authService.GetUserByID(id)
 	if err != nil {
-		if err == repository.ErrUserNotFound {
-			respondJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
-			return
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "get_user_failed")
 		}
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to get user"})
 		return
 	}
 
+	dates := requestDates(r)
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"id":         user.ID,
-		"email":      user.Email,
-		"name":       user.Name,
-		"created_at": user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		"updated_at": user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		"id":                 user.ID,
+		"email":              user.Email,
+		"name":               user.Name,
+		"created_at":         datefmt.Timestamp(user.CreatedAt),
+		"created_at_display": dates.Format(user.CreatedAt),
+		"updated_at":         datefmt.Timestamp(user.UpdatedAt),
+		"updated_at_display": dates.Format(user.UpdatedAt),
 	})
 }
 
 func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromToken(r)
 	if userID == "" {
-		respondJSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	var req UpdateProfileRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "name_required", "name", req.Name) {
+		return
+	}
+
+	if err := h.authService.UpdateProfile(userID, req.Name, clientInfo(r)); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "update_profile_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "profile_updated", nil)
+}
+
+func (h *AuthHandler) Reauthenticate(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req ReauthenticateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
 		return
 	}
 
-	if req.Name == "" {
-		respondJSON(w, http.StatusBadRequest, map[string]string{"error": "Name is required"})
+	if !requireFields(w, r, "password_required", "password", req.Password) {
 		return
 	}
 
-	if err := h.authService.UpdateProfile(userID, req.Name); err != nil {
-		if err == repository.ErrUserNotFound {
-			respondJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
-			return
+	accessToken, expiresIn, err := h.authService.Reauthenticate(userID, req.Password, getOrgIDFromToken(r))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "reauthenticate_failed")
 		}
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update profile"})
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{"message": "Profile updated successfully"})
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"expires_in":   int(expiresIn.Seconds()),
+	})
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if len(req.NewPassword) < 8 {
+		respondFieldError(w, r, http.StatusBadRequest, "password_too_short", "new_password")
+		return
+	}
+
+	accessToken, refreshToken, err := h.authService.ChangePassword(userID, req.NewPassword, getAuthTimeFromToken(r), getOrgIDFromToken(r), clientInfo(r))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "change_password_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+func (h *AuthHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "email_address_required", "email", req.Email) {
+		return
+	}
+
+	if err := h.authService.ChangeEmail(userID, req.Email, getAuthTimeFromToken(r), clientInfo(r)); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "change_email_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "email_updated", nil)
 }
 
 func (h *AuthHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromToken(r)
 	if userID == "" {
-		respondJSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	purgeAt, err := h.authService.DeleteAccount(userID, getAuthTimeFromToken(r), clientInfo(r))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "delete_account_failed")
+		}
+		return
+	}
+
+	if purgeAt.IsZero() {
+		respondMessage(w, r, http.StatusOK, "account_deleted", nil)
 		return
 	}
 
-	if err := h.authService.DeleteAccount(userID); err != nil {
-		if err == repository.ErrUserNotFound {
-			respondJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
-			return
+	dates := requestDates(r)
+	respondMessage(w, r, http.StatusAccepted, "account_deletion_scheduled", map[string]string{
+		"purge_at":         datefmt.Timestamp(purgeAt),
+		"purge_at_display": dates.Format(purgeAt),
+	})
+}
+
+// RestoreUser lets administrators cancel the scheduled deletion of an
+// account.
+func (h *AuthHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	if err := h.authService.RestoreAccount(getUserIDFromToken(r), r.PathValue("id"), clientInfo(r)); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "restore_account_failed")
 		}
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete account"})
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{"message": "Account deleted successfully"})
+	respondMessage(w, r, http.StatusOK, "account_restored", nil)
 }
 
 func (h *AuthHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromToken(r)
 	if userID == "" {
-		respondJSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	usage, err := h.authService.GetUsageStats(userID)
 	if err != nil {
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to get usage stats"})
+		respondError(w, r, http.StatusInternalServerError, "get_usage_failed")
 		return
 	}
 
@@ -324,14 +544,156 @@ func getUserIDFromToken(r *http.Request) string {
 	}
 
 	// Fallback: parse JWT directly (for direct auth service access)
+	if claims := parseTokenClaims(r); claims != nil {
+		if userID, ok := claims["user_id"].(string); ok {
+			return userID
+		}
+	}
+
+	return ""
+}
+
+// getAuthTimeFromToken returns when the user behind the request's access
+// token last authenticated, or the zero time if the token doesn't say.
+func getAuthTimeFromToken(r *http.Request) time.Time {
+	// First check X-Auth-Time header (set by gateway after JWT validation)
+	if authTime := r.Header.Get("X-Auth-Time"); authTime != "" {
+		if unix, err := strconv.ParseInt(authTime, 10, 64); err == nil {
+			return time.Unix(unix, 0)
+		}
+		return time.Time{}
+	}
+
+	// Fallback: parse JWT directly (for direct auth service access)
+	if claims := parseTokenClaims(r); claims != nil {
+		if authTime, ok := claims["auth_time"].(float64); ok {
+			return time.Unix(int64(authTime), 0)
+		}
+	}
+
+	return time.Time{}
+}
+
+// getAuthMethodsFromToken returns the authentication methods (amr) of the
+// request's access token.
+func getAuthMethodsFromToken(r *http.Request) []string {
+	// First check X-Auth-Methods header (set by gateway after JWT validation)
+	if methods := r.Header.Get("X-Auth-Methods"); methods != "" {
+		return strings.Split(methods, ",")
+	}
+
+	// Fallback: parse JWT directly (for direct auth service access)
+	var methods []string
+	if claims := parseTokenClaims(r); claims != nil {
+		if amr, ok := claims["amr"].([]interface{}); ok {
+			for _, m := range amr {
+				if method, ok := m.(string); ok {
+					methods = append(methods, method)
+				}
+			}
+		}
+	}
+
+	return methods
+}
+
+// getOrgIDFromToken returns the active organization of the request's
+// access token, or "" if there is none.
+func getOrgIDFromToken(r *http.Request) string {
+	// First check X-Org-ID header (set by gateway after JWT validation)
+	if orgID := r.Header.Get("X-Org-ID"); orgID != "" {
+		return orgID
+	}
+
+	// Fallback: parse JWT directly (for direct auth service access)
+	if claims := parseTokenClaims(r); claims != nil {
+		if orgID, ok := claims["org_id"].(string); ok {
+			return orgID
+		}
+	}
+
+	return ""
+}
+
+// clientInfo describes the client that sent r.
+func clientInfo(r *http.Request) service.ClientInfo {
+	return service.ClientInfo{
+		IP:        getClientIP(r),
+		UserAgent: r.UserAgent(),
+		Locale:    requestLocale(r),
+	}
+}
+
+func getClientIP(r *http.Request) string {
+	// The gateway appends the address it saw to X-Forwarded-For. Entries
+	// before it come from the client and can't be trusted.
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		entries := strings.Split(forwarded, ",")
+		return strings.TrimSpace(entries[len(entries)-1])
+	}
+
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// requestLanguage returns the language to respond to r in: the user's
+// preferred locale if they are signed in, otherwise the best match for
+// Accept-Language.
+func requestLanguage(r *http.Request) string {
+	// First check X-User-Locale header (set by gateway after JWT validation)
+	preferred := r.Header.Get("X-User-Locale")
+
+	// Fallback: parse JWT directly (for direct auth service access)
+	if preferred == "" {
+		if claims := parseTokenClaims(r); claims != nil {
+			preferred, _ = claims["locale"].(string)
+		}
+	}
+
+	return i18n.Negotiate(preferred, r.Header.Get("Accept-Language"))
+}
+
+// requestDates returns the formatter for the dates of the response to r:
+// in its language, the calendar asked for in Accept-Language or the
+// language's default, and the user's time zone if they are signed in,
+// otherwise UTC.
+func requestDates(r *http.Request) *datefmt.Formatter {
+	// First check X-User-Time-Zone header (set by gateway after JWT validation)
+	timeZone := r.Header.Get("X-User-Time-Zone")
+
+	// Fallback: parse JWT directly (for direct auth service access)
+	if timeZone == "" {
+		if claims := parseTokenClaims(r); claims != nil {
+			timeZone, _ = claims["tz"].(string)
+		}
+	}
+
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	calendar := datefmt.ParseCalendar(r.Header.Get("Accept-Language"))
+	return datefmt.New(requestLanguage(r), calendar, location)
+}
+
+// requestLocale returns the first language tag of Accept-Language.
+func requestLocale(r *http.Request) string {
+	tag := strings.Split(r.Header.Get("Accept-Language"), ",")[0]
+	return strings.TrimSpace(strings.Split(tag, ";")[0])
+}
+
+func parseTokenClaims(r *http.Request) jwt.MapClaims {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return ""
+		return nil
 	}
 
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		return ""
+		return nil
 	}
 
 	tokenString := parts[1]
@@ -340,16 +702,30 @@ func getUserIDFromToken(r *http.Request) string {
 	})
 
 	if err != nil || !token.Valid {
-		return ""
+		return nil
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		if userID, ok := claims["user_id"].(string); ok {
-			return userID
-		}
+		return claims
 	}
 
-	return ""
+	return nil
+}
+
+// respondMessage writes a successful response with the message with code,
+// in the language of r, and fields.
+func respondMessage(w http.ResponseWriter, r *http.Request, statusCode int, code string, fields map[string]string) {
+	locale := requestLanguage(r)
+	body := map[string]string{
+		"code":    code,
+		"message": i18n.Message(locale, code),
+	}
+	for name, value := range fields {
+		body[name] = value
+	}
+
+	w.Header().Set("Content-Language", locale)
+	respondJSON(w, statusCode, body)
 }
 
 func respondJSON(w http.ResponseWriter, statusCode int, data interface{}) {
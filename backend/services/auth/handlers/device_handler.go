@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/internal/datefmt"
+)
+
+func (h *AuthHandler) ListTrustedDevices(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	devices, err := h.authService.ListTrustedDevices(userID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "list_devices_failed")
+		return
+	}
+
+	dates := requestDates(r)
+	response := make([]map[string]string, 0, len(devices))
+	for _, device := range devices {
+		response = append(response, map[string]string{
+			"id":                   device.ID,
+			"name":                 device.Name,
+			"user_agent":           device.UserAgent,
+			"created_at":           datefmt.Timestamp(device.CreatedAt),
+			"created_at_display":   dates.Format(device.CreatedAt),
+			"last_used_at":         datefmt.Timestamp(device.LastUsedAt),
+			"last_used_at_display": dates.Format(device.LastUsedAt),
+			"expires_at":           datefmt.Timestamp(device.ExpiresAt),
+			"expires_at_display":   dates.Format(device.ExpiresAt),
+		})
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) RevokeTrustedDevice(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, r, http.StatusBadRequest, "device_id_required")
+		return
+	}
+
+	if err := h.authService.RevokeTrustedDevice(userID, id); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "revoke_device_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "device_revoked", nil)
+}
+
+func (h *AuthHandler) RevokeAllTrustedDevices(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.authService.RevokeAllTrustedDevices(userID); err != nil {
+		respondError(w, r, http.StatusInternalServerError, "revoke_devices_failed")
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "all_devices_revoked", nil)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"backend/internal/service"
+)
+
+type EmailHandler struct {
+	emailer     *service.Emailer
+	authService *service.AuthService
+}
+
+func NewEmailHandler(emailer *service.Emailer, authService *service.AuthService) *EmailHandler {
+	return &EmailHandler{
+		emailer:     emailer,
+		authService: authService,
+	}
+}
+
+// ListTemplates returns the emails the services send and the languages
+// each is translated into. Admin only.
+func (h *EmailHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	templates := h.emailer.Templates()
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	response := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		response = append(response, map[string]interface{}{
+			"name":    name,
+			"locales": templates[name],
+		})
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"templates": response})
+}
+
+// Preview renders an email with sample data in the language of the
+// "locale" query parameter, or of the request. "format" picks the response:
+// the subject and both variants as JSON by default, or only the "html" or
+// "text" variant, to look at in a browser. Admin only.
+func (h *EmailHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	query := r.URL.Query()
+	locale := query.Get("locale")
+	if locale == "" {
+		locale = requestLanguage(r)
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "html" && format != "text" {
+		respondFieldError(w, r, http.StatusBadRequest, "invalid_preview_format", "format")
+		return
+	}
+
+	email, err := h.emailer.Preview(r.PathValue("name"), locale)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "preview_email_failed")
+		}
+		return
+	}
+
+	switch format {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Language", email.Locale)
+		w.Write([]byte(email.HTML))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Language", email.Locale)
+		w.Write([]byte(email.Text))
+	default:
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"name":    r.PathValue("name"),
+			"locale":  email.Locale,
+			"subject": email.Subject,
+			"text":    email.Text,
+			"html":    email.HTML,
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"backend/internal/datefmt"
+	"backend/internal/models"
+	"backend/internal/service"
+)
+
+type ExportHandler struct {
+	exportService *service.ExportService
+}
+
+func NewExportHandler(exportService *service.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// RequestExport queues a copy of the caller's personal data. Poll
+// GET /user/exports/{id} for when it is ready.
+func (h *ExportHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	export, err := h.exportService.Request(userID)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "request_export_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, h.exportResponse(export, requestDates(r)))
+}
+
+func (h *ExportHandler) ListExports(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	exports, err := h.exportService.List(userID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "list_exports_failed")
+		return
+	}
+
+	dates := requestDates(r)
+	response := make([]map[string]interface{}, 0, len(exports))
+	for _, export := range exports {
+		response = append(response, h.exportResponse(export, dates))
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"exports": response})
+}
+
+// GetExport reports the state of an export and, once it is ready, a
+// short-lived link to download it.
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	export, err := h.exportService.Get(userID, r.PathValue("id"))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "get_export_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.exportResponse(export, requestDates(r)))
+}
+
+// Download serves the archive of an export. It needs no access token; the
+// link's signature and expiry authorize it.
+func (h *ExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	export, file, err := h.exportService.Open(r.PathValue("id"), query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		if respondServiceError(w, r, err) {
+			return
+		}
+		log.Printf("Opening data export failed: %v", err)
+		respondError(w, r, http.StatusInternalServerError, "download_export_failed")
+		return
+	}
+	defer file.Close()
+
+	name := "data-export-" + export.CreatedAt.Format("2006-01-02") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+
+	modified := export.CreatedAt
+	if export.CompletedAt != nil {
+		modified = *export.CompletedAt
+	}
+	http.ServeContent(w, r, name, modified, file)
+}
+
+func (h *ExportHandler) exportResponse(export *models.DataExport, dates *datefmt.Formatter) map[string]interface{} {
+	response := map[string]interface{}{
+		"id":                 export.ID,
+		"status":             export.Status,
+		"size":               export.Size,
+		"expires_at":         nil,
+		"created_at":         datefmt.Timestamp(export.CreatedAt),
+		"created_at_display": dates.Format(export.CreatedAt),
+	}
+	if export.ExpiresAt != nil {
+		response["expires_at"] = datefmt.Timestamp(*export.ExpiresAt)
+		response["expires_at_display"] = dates.Format(*export.ExpiresAt)
+	}
+
+	if link, expires, err := h.exportService.DownloadLink(export); err == nil {
+		response["download_url"] = link
+		response["download_url_expires_at"] = datefmt.Timestamp(expires)
+		response["download_url_expires_at_display"] = dates.Format(expires)
+	}
+
+	return response
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend/internal/service"
+)
+
+type GuestHandler struct {
+	guestService *service.GuestService
+}
+
+func NewGuestHandler(guestService *service.GuestService) *GuestHandler {
+	return &GuestHandler{
+		guestService: guestService,
+	}
+}
+
+type GuestSignInRequest struct {
+	// Proof-of-work solution, required unless challenges are disabled
+	Challenge string `json:"challenge"`
+	Nonce     string `json:"nonce"`
+}
+
+type UpgradeRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+
+	// Required when registration is invite-only
+	InvitationCode string `json:"invitation_code"`
+}
+
+type UpgradeWithPhoneRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+
+	// Required when registration is invite-only
+	InvitationCode string `json:"invitation_code"`
+}
+
+func (h *GuestHandler) SignIn(w http.ResponseWriter, r *http.Request) {
+	var req GuestSignInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	user, accessToken, refreshToken, err := h.guestService.SignIn(req.Challenge, req.Nonce, clientInfo(r))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "create_guest_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, newAuthResponse(user, accessToken, refreshToken, requestDates(r)))
+}
+
+// Upgrade turns the caller's guest account into a full account that signs
+// in with email and password.
+func (h *GuestHandler) Upgrade(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req UpgradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "credentials_required", "email", req.Email, "password", req.Password) {
+		return
+	}
+
+	if len(req.Password) < 8 {
+		respondFieldError(w, r, http.StatusBadRequest, "password_too_short", "password")
+		return
+	}
+
+	user, accessToken, refreshToken, err := h.guestService.Upgrade(userID, service.UpgradeInput{
+		Email:          req.Email,
+		Password:       req.Password,
+		Name:           req.Name,
+		InvitationCode: req.InvitationCode,
+	}, clientInfo(r))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "upgrade_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newAuthResponse(user, accessToken, refreshToken, requestDates(r)))
+}
+
+// UpgradeWithPhone turns the caller's guest account into a full account
+// that signs in with a code texted to a phone number. The code is requested
+// with POST /auth/phone/otp first.
+func (h *GuestHandler) UpgradeWithPhone(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req UpgradeWithPhoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "phone_and_code_required", "phone", req.Phone, "code", req.Code) {
+		return
+	}
+
+	user, accessToken, refreshToken, err := h.guestService.UpgradeWithPhone(userID, req.Phone, req.Code, req.Name, req.InvitationCode, clientInfo(r))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "upgrade_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newAuthResponse(user, accessToken, refreshToken, requestDates(r)))
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend/internal/service"
+)
+
+type IdentityHandler struct {
+	identityService *service.IdentityService
+}
+
+func NewIdentityHandler(identityService *service.IdentityService) *IdentityHandler {
+	return &IdentityHandler{
+		identityService: identityService,
+	}
+}
+
+type LinkEmailRequest struct {
+	Email string `json:"email"`
+
+	// Empty to have a code sent, then the code from the email
+	Code string `json:"code"`
+}
+
+type LinkPhoneRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+}
+
+type LinkPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+func (h *IdentityHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	identities, err := h.identityService.List(userID)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "list_identities_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"identities": identities})
+}
+
+// LinkEmail sends a verification code to the address when the request has
+// no code, and links the address once it does.
+func (h *IdentityHandler) LinkEmail(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req LinkEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "email_address_required", "email", req.Email) {
+		return
+	}
+
+	if req.Code == "" {
+		err := h.identityService.RequestEmailLink(userID, req.Email, getAuthTimeFromToken(r))
+		if err != nil {
+			if !respondServiceError(w, r, err) {
+				respondError(w, r, http.StatusInternalServerError, "send_verification_code_failed")
+			}
+			return
+		}
+
+		respondMessage(w, r, http.StatusAccepted, "verification_code_sent", nil)
+		return
+	}
+
+	if err := h.identityService.LinkEmail(userID, req.Email, req.Code, getAuthTimeFromToken(r), clientInfo(r)); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "link_email_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "email_linked", nil)
+}
+
+// LinkPhone links a phone number with a code requested from
+// POST /auth/phone/otp.
+func (h *IdentityHandler) LinkPhone(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req LinkPhoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "phone_and_code_required", "phone", req.Phone, "code", req.Code) {
+		return
+	}
+
+	if err := h.identityService.LinkPhone(userID, req.Phone, req.Code, getAuthTimeFromToken(r), clientInfo(r)); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "link_phone_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "phone_linked", nil)
+}
+
+func (h *IdentityHandler) LinkPassword(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req LinkPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if len(req.Password) < 8 {
+		respondFieldError(w, r, http.StatusBadRequest, "password_too_short", "password")
+		return
+	}
+
+	if err := h.identityService.LinkPassword(userID, req.Password, getAuthTimeFromToken(r), clientInfo(r)); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "set_password_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "password_set", nil)
+}
+
+func (h *IdentityHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.identityService.Unlink(userID, r.PathValue("method"), getAuthTimeFromToken(r), clientInfo(r)); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "remove_identity_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "login_method_removed", nil)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"backend/internal/datefmt"
+	"backend/internal/models"
+	"backend/internal/service"
+)
+
+type InvitationHandler struct {
+	policy      *service.RegistrationPolicy
+	authService *service.AuthService
+}
+
+func NewInvitationHandler(policy *service.RegistrationPolicy, authService *service.AuthService) *InvitationHandler {
+	return &InvitationHandler{
+		policy:      policy,
+		authService: authService,
+	}
+}
+
+type CreateInvitationRequest struct {
+	// Optional: limit the code to this address
+	Email string `json:"email"`
+
+	// Number of registrations the code allows, default 1
+	MaxUses int `json:"max_uses"`
+
+	// Lifetime in seconds, 0 for no expiry
+	ExpiresIn int `json:"expires_in"`
+}
+
+// CreateInvitation issues an invitation code. The code is only shown in
+// this response.
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	var req CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if req.MaxUses == 0 {
+		req.MaxUses = 1
+	}
+	if req.MaxUses < 0 || req.ExpiresIn < 0 {
+		respondError(w, r, http.StatusBadRequest, "invalid_invitation_limits")
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresIn) * time.Second
+	invitation, code, err := h.policy.CreateInvitation(getUserIDFromToken(r), req.Email, req.MaxUses, ttl)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "create_invitation_failed")
+		}
+		return
+	}
+
+	response := invitationResponse(invitation, requestDates(r))
+	response["code"] = code
+	respondJSON(w, http.StatusCreated, response)
+}
+
+func (h *InvitationHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	invitations, err := h.policy.ListInvitations()
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "list_invitations_failed")
+		return
+	}
+
+	dates := requestDates(r)
+	response := make([]map[string]interface{}, 0, len(invitations))
+	for _, invitation := range invitations {
+		response = append(response, invitationResponse(invitation, dates))
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"invitations": response,
+		"invite_only": h.policy.InviteOnly(),
+	})
+}
+
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	if err := h.policy.RevokeInvitation(r.PathValue("id")); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "revoke_invitation_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "invitation_revoked", nil)
+}
+
+func invitationResponse(invitation *models.InvitationCode, dates *datefmt.Formatter) map[string]interface{} {
+	response := map[string]interface{}{
+		"id":                 invitation.ID,
+		"email":              invitation.Email,
+		"max_uses":           invitation.MaxUses,
+		"uses":               invitation.Uses,
+		"created_by":         invitation.CreatedBy,
+		"expires_at":         nil,
+		"created_at":         datefmt.Timestamp(invitation.CreatedAt),
+		"created_at_display": dates.Format(invitation.CreatedAt),
+	}
+	if invitation.ExpiresAt != nil {
+		response["expires_at"] = datefmt.Timestamp(*invitation.ExpiresAt)
+		response["expires_at_display"] = dates.Format(*invitation.ExpiresAt)
+	}
+	return response
+}
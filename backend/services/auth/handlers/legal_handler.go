@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend/internal/datefmt"
+	"backend/internal/models"
+	"backend/internal/service"
+)
+
+type LegalHandler struct {
+	legalService *service.LegalService
+	authService  *service.AuthService
+}
+
+func NewLegalHandler(legalService *service.LegalService, authService *service.AuthService) *LegalHandler {
+	return &LegalHandler{
+		legalService: legalService,
+		authService:  authService,
+	}
+}
+
+type AcceptConsentsRequest struct {
+	// Versions of the legal documents accepted, by kind
+	Consents map[string]int `json:"consents"`
+
+	// The refresh token the new token pair replaces
+	RefreshToken string `json:"refresh_token"`
+}
+
+type PublishLegalDocumentRequest struct {
+	Kind      string `json:"kind"`
+	Mandatory bool   `json:"mandatory"`
+
+	// Title and body by locale; English is required
+	Translations map[string]struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	} `json:"translations"`
+}
+
+// GetDocuments returns the current terms of service and privacy policy in
+// the language of the locale query parameter or, without it, of
+// Accept-Language.
+func (h *LegalHandler) GetDocuments(w http.ResponseWriter, r *http.Request) {
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = requestLocale(r)
+	}
+
+	docs, err := h.legalService.Documents(locale)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "get_legal_documents_failed")
+		return
+	}
+
+	dates := requestDates(r)
+	response := make([]map[string]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		response = append(response, legalDocumentResponse(doc, dates))
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"documents": response})
+}
+
+// GetVersions returns the current and the required version of each kind of
+// legal document. The gateway polls it to know which consents to demand.
+func (h *LegalHandler) GetVersions(w http.ResponseWriter, r *http.Request) {
+	current, required, err := h.legalService.Versions()
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "get_legal_versions_failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"current":  current,
+		"required": required,
+	})
+}
+
+// AcceptConsents records that the user accepted the current versions of the
+// given documents and returns a new token pair that says so, in place of
+// the refresh token in the body. The client should replace its tokens with
+// them.
+func (h *LegalHandler) AcceptConsents(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req AcceptConsentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "refresh_token_required", "refresh_token", req.RefreshToken) {
+		return
+	}
+
+	accessToken, refreshToken, err := h.legalService.Accept(
+		userID,
+		req.Consents,
+		req.RefreshToken,
+		getAuthTimeFromToken(r),
+		getAuthMethodsFromToken(r),
+		getOrgIDFromToken(r),
+		clientInfo(r),
+	)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "record_consent_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+// ListConsents returns the legal documents the user accepted, newest first.
+func (h *LegalHandler) ListConsents(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	consents, err := h.legalService.Consents(userID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "list_consents_failed")
+		return
+	}
+
+	dates := requestDates(r)
+	response := make([]map[string]interface{}, 0, len(consents))
+	for _, consent := range consents {
+		response = append(response, map[string]interface{}{
+			"kind":                consent.Kind,
+			"version":             consent.Version,
+			"locale":              consent.Locale,
+			"ip":                  consent.IP,
+			"user_agent":          consent.UserAgent,
+			"accepted_at":         datefmt.Timestamp(consent.AcceptedAt),
+			"accepted_at_display": dates.Format(consent.AcceptedAt),
+		})
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"consents": response})
+}
+
+// PublishDocument publishes a new version of a legal document. Admin only.
+func (h *LegalHandler) PublishDocument(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	var req PublishLegalDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	translations := make(map[string]service.LegalTranslation, len(req.Translations))
+	for locale, translation := range req.Translations {
+		translations[locale] = service.LegalTranslation{Title: translation.Title, Body: translation.Body}
+	}
+
+	version, err := h.legalService.Publish(getUserIDFromToken(r), req.Kind, req.Mandatory, translations, clientInfo(r))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "publish_legal_document_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"kind":      req.Kind,
+		"version":   version,
+		"mandatory": req.Mandatory,
+	})
+}
+
+func legalDocumentResponse(doc *models.LegalDocument, dates *datefmt.Formatter) map[string]interface{} {
+	return map[string]interface{}{
+		"kind":                 doc.Kind,
+		"version":              doc.Version,
+		"locale":               doc.Locale,
+		"title":                doc.Title,
+		"body":                 doc.Body,
+		"mandatory":            doc.Mandatory,
+		"published_at":         datefmt.Timestamp(doc.PublishedAt),
+		"published_at_display": dates.Format(doc.PublishedAt),
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend/internal/datefmt"
+	"backend/internal/models"
+	"backend/internal/service"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type LoginHistoryHandler struct {
+	historyService *service.LoginHistoryService
+	authService    *service.AuthService
+}
+
+func NewLoginHistoryHandler(historyService *service.LoginHistoryService, authService *service.AuthService) *LoginHistoryHandler {
+	return &LoginHistoryHandler{
+		historyService: historyService,
+		authService:    authService,
+	}
+}
+
+func (h *LoginHistoryHandler) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	h.respondHistory(w, r, userID)
+}
+
+// GetUserLoginHistory lets administrators read any user's login history.
+func (h *LoginHistoryHandler) GetUserLoginHistory(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		respondError(w, r, http.StatusBadRequest, "user_id_required")
+		return
+	}
+
+	h.respondHistory(w, r, id)
+}
+
+func (h *LoginHistoryHandler) respondHistory(w http.ResponseWriter, r *http.Request, userID string) {
+	limit, offset, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	events, total, err := h.historyService.History(userID, limit, offset)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "get_login_history_failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"events": loginEventsResponse(events, requestDates(r)),
+		"limit":  limit,
+		"offset": offset,
+		"total":  total,
+	})
+}
+
+func loginEventsResponse(events []*models.LoginEvent, dates *datefmt.Formatter) []map[string]interface{} {
+	response := make([]map[string]interface{}, 0, len(events))
+	for _, event := range events {
+		response = append(response, map[string]interface{}{
+			"id":                 event.ID,
+			"method":             event.Method,
+			"outcome":            event.Outcome,
+			"ip":                 event.IP,
+			"user_agent":         event.UserAgent,
+			"country":            event.Country,
+			"city":               event.City,
+			"asn":                event.ASN,
+			"new_device":         event.NewDevice,
+			"created_at":         datefmt.Timestamp(event.CreatedAt),
+			"created_at_display": dates.Format(event.CreatedAt),
+		})
+	}
+	return response
+}
+
+// parsePagination reads the limit and offset query parameters. It writes
+// the error response and returns false if either is invalid.
+func parsePagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	limit, offset := defaultPageSize, 0
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			respondFieldError(w, r, http.StatusBadRequest, "invalid_limit", "limit")
+			return 0, 0, false
+		}
+		limit = n
+	}
+
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondFieldError(w, r, http.StatusBadRequest, "invalid_offset", "offset")
+			return 0, 0, false
+		}
+		offset = n
+	}
+
+	return limit, offset, true
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend/internal/models"
+	"backend/internal/service"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService *service.MagicLinkService
+}
+
+func NewMagicLinkHandler(magicLinkService *service.MagicLinkService) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+	}
+}
+
+type MagicLinkRequest struct {
+	Email    string `json:"email"`
+	DeviceID string `json:"device_id"`
+}
+
+// VerifyMagicLinkRequest carries either the token from the emailed link or
+// the email address and 6-digit code.
+type VerifyMagicLinkRequest struct {
+	Token    string `json:"token"`
+	Email    string `json:"email"`
+	Code     string `json:"code"`
+	DeviceID string `json:"device_id"`
+
+	// Restores an account scheduled for deletion
+	CancelDeletion bool `json:"cancel_deletion"`
+}
+
+func (h *MagicLinkHandler) Request(w http.ResponseWriter, r *http.Request) {
+	var req MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "email_and_device_id_required", "email", req.Email, "device_id", req.DeviceID) {
+		return
+	}
+
+	if err := h.magicLinkService.Request(req.Email, req.DeviceID); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "send_magic_link_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusAccepted, "magic_link_sent", nil)
+}
+
+func (h *MagicLinkHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	var req VerifyMagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if req.DeviceID == "" || (req.Token == "" && (req.Email == "" || req.Code == "")) {
+		respondError(w, r, http.StatusBadRequest, "magic_link_fields_required")
+		return
+	}
+
+	client := clientInfo(r)
+	client.CancelDeletion = req.CancelDeletion
+
+	var (
+		user                      *models.User
+		accessToken, refreshToken string
+		err                       error
+	)
+	if req.Token != "" {
+		user, accessToken, refreshToken, err = h.magicLinkService.VerifyToken(req.Token, req.DeviceID, client)
+	} else {
+		user, accessToken, refreshToken, err = h.magicLinkService.VerifyCode(req.Email, req.Code, req.DeviceID, client)
+	}
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "verify_magic_link_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newAuthResponse(user, accessToken, refreshToken, requestDates(r)))
+}
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend/internal/datefmt"
+	"backend/internal/models"
+	"backend/internal/service"
+)
+
+type OrganizationHandler struct {
+	orgService *service.OrganizationService
+}
+
+func NewOrganizationHandler(orgService *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: orgService,
+	}
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type InviteMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// AnswerInvitationRequest identifies an invitation by either the token from
+// the invitation email or its ID.
+type AnswerInvitationRequest struct {
+	Token string `json:"token"`
+	ID    string `json:"id"`
+}
+
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	org, err := h.orgService.Create(userID, req.Name)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "create_organization_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, organizationResponse(org, requestDates(r)))
+}
+
+func (h *OrganizationHandler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	orgs, err := h.orgService.List(userID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "list_organizations_failed")
+		return
+	}
+
+	dates := requestDates(r)
+	response := make([]map[string]interface{}, 0, len(orgs))
+	for _, org := range orgs {
+		response = append(response, organizationResponse(org, dates))
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"organizations": response,
+		"active_org_id": getOrgIDFromToken(r),
+	})
+}
+
+// SwitchOrganization returns a new token pair whose active organization is
+// the one in the path, in place of the refresh token in the body. The
+// client should replace its tokens with them.
+func (h *OrganizationHandler) SwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "refresh_token_required", "refresh_token", req.RefreshToken) {
+		return
+	}
+
+	orgID := r.PathValue("id")
+	accessToken, refreshToken, err := h.orgService.Switch(userID, orgID, req.RefreshToken, getAuthTimeFromToken(r), getAuthMethodsFromToken(r), clientInfo(r))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "switch_organization_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"org_id":        orgID,
+	})
+}
+
+func (h *OrganizationHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	members, err := h.orgService.Members(userID, r.PathValue("id"))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "list_members_failed")
+		}
+		return
+	}
+
+	dates := requestDates(r)
+	response := make([]map[string]interface{}, 0, len(members))
+	for _, member := range members {
+		response = append(response, map[string]interface{}{
+			"user_id":           member.UserID,
+			"name":              member.Name,
+			"email":             member.Email,
+			"role":              member.Role,
+			"joined_at":         datefmt.Timestamp(member.CreatedAt),
+			"joined_at_display": dates.Format(member.CreatedAt),
+		})
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"members": response})
+}
+
+func (h *OrganizationHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if err := h.orgService.UpdateMemberRole(userID, r.PathValue("id"), r.PathValue("userId"), req.Role); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "update_member_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "member_updated", nil)
+}
+
+// RemoveMember removes a member, or lets a member leave when the path names
+// the caller.
+func (h *OrganizationHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.orgService.RemoveMember(userID, r.PathValue("id"), r.PathValue("userId")); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "remove_member_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "member_removed", nil)
+}
+
+func (h *OrganizationHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req InviteMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "email_address_required", "email", req.Email) {
+		return
+	}
+
+	invitation, err := h.orgService.Invite(userID, r.PathValue("id"), req.Email, req.Role)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "send_invitation_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, orgInvitationResponse(invitation, requestDates(r)))
+}
+
+func (h *OrganizationHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	invitations, err := h.orgService.Invitations(userID, r.PathValue("id"))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "list_invitations_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"invitations": orgInvitationsResponse(invitations, requestDates(r))})
+}
+
+func (h *OrganizationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.orgService.RevokeInvitation(userID, r.PathValue("id"), r.PathValue("invitationId")); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "revoke_invitation_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "org_invitation_revoked", nil)
+}
+
+// ListMyInvitations lists the invitations waiting for the caller's answer.
+func (h *OrganizationHandler) ListMyInvitations(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	invitations, err := h.orgService.PendingInvitations(userID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "list_invitations_failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"invitations": orgInvitationsResponse(invitations, requestDates(r))})
+}
+
+func (h *OrganizationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	req, ok := decodeAnswerInvitation(w, r)
+	if !ok {
+		return
+	}
+
+	invitation, err := h.orgService.Accept(userID, req.Token, req.ID)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "accept_invitation_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "org_invitation_accepted", map[string]string{
+		"org_id": invitation.OrgID,
+		"role":   invitation.Role,
+	})
+}
+
+func (h *OrganizationHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	req, ok := decodeAnswerInvitation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.Decline(userID, req.Token, req.ID); err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "decline_invitation_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusOK, "org_invitation_declined", nil)
+}
+
+func decodeAnswerInvitation(w http.ResponseWriter, r *http.Request) (*AnswerInvitationRequest, bool) {
+	var req AnswerInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return nil, false
+	}
+
+	if req.Token == "" && req.ID == "" {
+		respondError(w, r, http.StatusBadRequest, "invitation_token_or_id_required")
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func organizationResponse(org *models.Organization, dates *datefmt.Formatter) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 org.ID,
+		"name":               org.Name,
+		"role":               org.Role,
+		"created_at":         datefmt.Timestamp(org.CreatedAt),
+		"created_at_display": dates.Format(org.CreatedAt),
+	}
+}
+
+func orgInvitationsResponse(invitations []*models.OrgInvitation, dates *datefmt.Formatter) []map[string]interface{} {
+	response := make([]map[string]interface{}, 0, len(invitations))
+	for _, invitation := range invitations {
+		response = append(response, orgInvitationResponse(invitation, dates))
+	}
+	return response
+}
+
+func orgInvitationResponse(invitation *models.OrgInvitation, dates *datefmt.Formatter) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 invitation.ID,
+		"org_id":             invitation.OrgID,
+		"org_name":           invitation.OrgName,
+		"email":              invitation.Email,
+		"role":               invitation.Role,
+		"invited_by":         invitation.InvitedBy,
+		"expires_at":         datefmt.Timestamp(invitation.ExpiresAt),
+		"expires_at_display": dates.Format(invitation.ExpiresAt),
+		"created_at":         datefmt.Timestamp(invitation.CreatedAt),
+		"created_at_display": dates.Format(invitation.CreatedAt),
+	}
+}
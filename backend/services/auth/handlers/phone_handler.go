@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"backend/internal/service"
+)
+
+type PhoneHandler struct {
+	phoneOTPService *service.PhoneOTPService
+}
+
+func NewPhoneHandler(phoneOTPService *service.PhoneOTPService) *PhoneHandler {
+	return &PhoneHandler{
+		phoneOTPService: phoneOTPService,
+	}
+}
+
+type SendOTPRequest struct {
+	Phone string `json:"phone"`
+}
+
+type VerifyOTPRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+
+	// Proof-of-work solution, required to sign up unless challenges are
+	// disabled
+	Challenge string `json:"challenge"`
+	Nonce     string `json:"nonce"`
+
+	// Required to sign up when registration is invite-only
+	InvitationCode string `json:"invitation_code"`
+
+	// Versions of the legal documents accepted when signing up, by kind
+	Consents map[string]int `json:"consents"`
+
+	// Restores an account scheduled for deletion
+	CancelDeletion bool `json:"cancel_deletion"`
+}
+
+func (h *PhoneHandler) SendOTP(w http.ResponseWriter, r *http.Request) {
+	var req SendOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "phone_required", "phone", req.Phone) {
+		return
+	}
+
+	retryAfter, err := h.phoneOTPService.SendCode(req.Phone)
+	if err != nil {
+		if errors.Is(err, service.ErrOTPCooldown) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		}
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "send_code_failed")
+		}
+		return
+	}
+
+	respondMessage(w, r, http.StatusAccepted, "otp_sent", nil)
+}
+
+func (h *PhoneHandler) VerifyOTP(w http.ResponseWriter, r *http.Request) {
+	var req VerifyOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	if !requireFields(w, r, "phone_and_code_required", "phone", req.Phone, "code", req.Code) {
+		return
+	}
+
+	client := clientInfo(r)
+	client.CancelDeletion = req.CancelDeletion
+
+	user, accessToken, refreshToken, created, err := h.phoneOTPService.Verify(service.VerifyPhoneInput{
+		Phone:          req.Phone,
+		Code:           req.Code,
+		Name:           req.Name,
+		Challenge:      req.Challenge,
+		Nonce:          req.Nonce,
+		InvitationCode: req.InvitationCode,
+		Consents:       req.Consents,
+	}, client)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "verify_code_failed")
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	respondJSON(w, status, newAuthResponse(user, accessToken, refreshToken, requestDates(r)))
+}
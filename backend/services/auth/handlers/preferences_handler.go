@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend/internal/datefmt"
+	"backend/internal/models"
+	"backend/internal/service"
+)
+
+type PreferencesHandler struct {
+	preferencesService *service.PreferencesService
+}
+
+func NewPreferencesHandler(preferencesService *service.PreferencesService) *PreferencesHandler {
+	return &PreferencesHandler{preferencesService: preferencesService}
+}
+
+// UpdatePreferencesRequest changes the fields that are present and leaves
+// the others as they are.
+type UpdatePreferencesRequest struct {
+	Locale   *string `json:"locale"`
+	Theme    *string `json:"theme"`
+	TimeZone *string `json:"time_zone"`
+
+	Notifications *struct {
+		Email     *bool `json:"email"`
+		Push      *bool `json:"push"`
+		Marketing *bool `json:"marketing"`
+	} `json:"notifications"`
+
+	// The refresh token a new token pair replaces; needed to change the
+	// locale or time zone
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *PreferencesHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	prefs, err := h.preferencesService.Get(userID)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, "get_preferences_failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, preferencesResponse(prefs, requestDates(r)))
+}
+
+// UpdatePreferences changes some of the user's preferences and returns all
+// of them. A changed locale or time zone also returns a new token pair
+// carrying it, in place of the refresh token in the body, which the client
+// should replace its tokens with.
+func (h *PreferencesHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromToken(r)
+	if userID == "" {
+		respondError(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req UpdatePreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	update := service.PreferencesUpdate{
+		Locale:   req.Locale,
+		Theme:    req.Theme,
+		TimeZone: req.TimeZone,
+	}
+	if req.Notifications != nil {
+		update.NotifyEmail = req.Notifications.Email
+		update.NotifyPush = req.Notifications.Push
+		update.NotifyMarketing = req.Notifications.Marketing
+	}
+
+	prefs, accessToken, refreshToken, err := h.preferencesService.Update(
+		userID,
+		update,
+		req.RefreshToken,
+		getAuthTimeFromToken(r),
+		getAuthMethodsFromToken(r),
+		getOrgIDFromToken(r),
+		clientInfo(r),
+	)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "update_preferences_failed")
+		}
+		return
+	}
+
+	response := preferencesResponse(prefs, requestDates(r))
+	if accessToken != "" {
+		response["access_token"] = accessToken
+		response["refresh_token"] = refreshToken
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+func preferencesResponse(prefs *models.Preferences, dates *datefmt.Formatter) map[string]interface{} {
+	response := map[string]interface{}{
+		"locale":    prefs.Locale,
+		"theme":     prefs.Theme,
+		"time_zone": prefs.TimeZone,
+		"notifications": map[string]bool{
+			"email":     prefs.Notifications.Email,
+			"push":      prefs.Notifications.Push,
+			"marketing": prefs.Notifications.Marketing,
+		},
+	}
+	if !prefs.UpdatedAt.IsZero() {
+		response["updated_at"] = datefmt.Timestamp(prefs.UpdatedAt)
+		response["updated_at_display"] = dates.Format(prefs.UpdatedAt)
+	}
+	return response
+}
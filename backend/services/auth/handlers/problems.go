@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/mailtmpl"
+	"backend/internal/problem"
+	"backend/internal/repository"
+	"backend/internal/service"
+	"backend/internal/textnorm"
+)
+
+// serviceProblem is how a sentinel error of a service or repository is
+// reported: its status and code, and the request field it is about, if any.
+type serviceProblem struct {
+	err    error
+	status int
+	code   string
+	field  string
+	args   []interface{}
+}
+
+var serviceProblems = []serviceProblem{
+	// Names and email addresses
+	{err: textnorm.ErrInvalidEmail, status: http.StatusBadRequest, code: "invalid_email", field: "email"},
+	{err: textnorm.ErrEmpty, status: http.StatusBadRequest, code: "name_required", field: "name"},
+	{err: textnorm.ErrTooLong, status: http.StatusBadRequest, code: "name_too_long", field: "name", args: []interface{}{textnorm.MaxNameLength}},
+	{err: textnorm.ErrForbiddenCharacters, status: http.StatusBadRequest, code: "name_invalid_characters", field: "name"},
+
+	// Registration and login
+	{err: service.ErrInvalidCredentials, status: http.StatusUnauthorized, code: "invalid_credentials"},
+	{err: service.ErrInvalidToken, status: http.StatusUnauthorized, code: "invalid_refresh_token"},
+	{err: service.ErrReauthRequired, status: http.StatusForbidden, code: "reauthentication_required"},
+	{err: service.ErrChallengeFailed, status: http.StatusForbidden, code: "challenge_failed"},
+	{err: service.ErrRegistrationRateLimited, status: http.StatusTooManyRequests, code: "registration_rate_limited"},
+
+	// Registration policy
+	{err: service.ErrEmailDomainNotAllowed, status: http.StatusForbidden, code: "email_domain_not_allowed", field: "email"},
+	{err: service.ErrEmailDomainBlocked, status: http.StatusForbidden, code: "email_domain_blocked", field: "email"},
+	{err: service.ErrDisposableEmail, status: http.StatusForbidden, code: "disposable_email", field: "email"},
+	{err: service.ErrInvitationRequired, status: http.StatusForbidden, code: "invitation_required", field: "invitation_code"},
+	{err: service.ErrPhoneInvitationRequired, status: http.StatusForbidden, code: "phone_invitation_required", field: "invitation_code"},
+	{err: service.ErrInvalidInvitation, status: http.StatusForbidden, code: "invalid_invitation", field: "invitation_code"},
+
+	// Users and accounts
+	{err: repository.ErrUserNotFound, status: http.StatusNotFound, code: "user_not_found"},
+	{err: repository.ErrEmailAlreadyExists, status: http.StatusConflict, code: "email_exists", field: "email"},
+	{err: repository.ErrPhoneAlreadyExists, status: http.StatusConflict, code: "phone_exists", field: "phone"},
+	{err: service.ErrAccountPendingDeletion, status: http.StatusForbidden, code: "account_pending_deletion"},
+	{err: service.ErrAccountNotDeleted, status: http.StatusConflict, code: "account_not_deleted"},
+
+	// Phone numbers and codes
+	{err: service.ErrInvalidPhone, status: http.StatusBadRequest, code: "invalid_phone", field: "phone"},
+	{err: service.ErrPhoneNameRequired, status: http.StatusBadRequest, code: "signup_name_required", field: "name"},
+	{err: service.ErrInvalidOTP, status: http.StatusUnauthorized, code: "invalid_code", field: "code"},
+	{err: service.ErrOTPCooldown, status: http.StatusTooManyRequests, code: "code_cooldown"},
+	{err: service.ErrOTPRateLimited, status: http.StatusTooManyRequests, code: "code_rate_limited"},
+
+	// Login links
+	{err: service.ErrInvalidMagicLink, status: http.StatusUnauthorized, code: "invalid_magic_link"},
+
+	// Guest accounts
+	{err: service.ErrGuestAccessDisabled, status: http.StatusForbidden, code: "guest_access_disabled"},
+	{err: service.ErrGuestNotAllowed, status: http.StatusForbidden, code: "guest_not_allowed"},
+	{err: service.ErrNotGuest, status: http.StatusConflict, code: "not_guest"},
+
+	// Login methods
+	{err: service.ErrIdentityAlreadyLinked, status: http.StatusConflict, code: "identity_already_linked"},
+	{err: service.ErrIdentityNotLinked, status: http.StatusNotFound, code: "identity_not_linked"},
+	{err: repository.ErrLastIdentity, status: http.StatusConflict, code: "last_identity"},
+	{err: service.ErrPasswordNeedsEmail, status: http.StatusConflict, code: "email_required"},
+	{err: service.ErrVerificationRateLimited, status: http.StatusTooManyRequests, code: "code_rate_limited"},
+	{err: service.ErrInvalidVerificationCode, status: http.StatusUnauthorized, code: "invalid_verification_code", field: "code"},
+
+	// Devices
+	{err: repository.ErrDeviceNotFound, status: http.StatusNotFound, code: "device_not_found"},
+
+	// Data exports
+	{err: repository.ErrDataExportNotFound, status: http.StatusNotFound, code: "export_not_found"},
+	{err: service.ErrExportRateLimited, status: http.StatusTooManyRequests, code: "export_rate_limited"},
+	{err: service.ErrExportNotReady, status: http.StatusConflict, code: "export_not_ready"},
+	{err: service.ErrInvalidExportLink, status: http.StatusForbidden, code: "invalid_export_link"},
+
+	// Invitation codes
+	{err: repository.ErrInvitationNotFound, status: http.StatusNotFound, code: "invitation_not_found"},
+
+	// Organizations
+	{err: service.ErrNotOrgMember, status: http.StatusNotFound, code: "organization_not_found"},
+	{err: repository.ErrOrganizationNotFound, status: http.StatusNotFound, code: "organization_not_found"},
+	{err: service.ErrOrgPermissionDenied, status: http.StatusForbidden, code: "organization_permission_denied"},
+	{err: service.ErrInvalidOrgRole, status: http.StatusBadRequest, code: "invalid_role", field: "role"},
+	{err: service.ErrLastOwner, status: http.StatusConflict, code: "last_owner"},
+	{err: repository.ErrMembershipNotFound, status: http.StatusNotFound, code: "member_not_found"},
+	{err: repository.ErrAlreadyMember, status: http.StatusConflict, code: "already_member"},
+	{err: service.ErrInvalidOrgInvitation, status: http.StatusNotFound, code: "invalid_org_invitation"},
+	{err: repository.ErrOrgInvitationNotFound, status: http.StatusNotFound, code: "invalid_org_invitation"},
+	{err: service.ErrInvitationEmailMismatch, status: http.StatusForbidden, code: "invitation_email_mismatch"},
+	{err: service.ErrOrgInvitationTokenRequired, status: http.StatusForbidden, code: "org_invitation_token_required", field: "token"},
+
+	// Legal documents and consents
+	{err: service.ErrUnknownLegalDocument, status: http.StatusBadRequest, code: "unknown_legal_document", field: "kind"},
+	{err: service.ErrConsentOutdated, status: http.StatusConflict, code: "consent_outdated"},
+	{err: service.ErrNothingToAccept, status: http.StatusBadRequest, code: "consents_required", field: "consents"},
+	{err: service.ErrLegalEnglishRequired, status: http.StatusBadRequest, code: "english_translation_required", field: "translations"},
+	{err: service.ErrLegalDocumentIncomplete, status: http.StatusBadRequest, code: "translation_incomplete", field: "translations"},
+
+	// Preferences; translations of legal documents have locales too
+	{err: service.ErrUnsupportedLocale, status: http.StatusBadRequest, code: "unsupported_locale"},
+	{err: service.ErrInvalidTheme, status: http.StatusBadRequest, code: "invalid_theme", field: "theme"},
+	{err: service.ErrInvalidTimeZone, status: http.StatusBadRequest, code: "invalid_time_zone", field: "time_zone"},
+
+	// Translation bundles
+	{err: service.ErrInvalidARB, status: http.StatusBadRequest, code: "invalid_arb"},
+	{err: service.ErrARBLocaleMismatch, status: http.StatusBadRequest, code: "arb_locale_mismatch", field: "@@locale"},
+	{err: service.ErrTranslationTemplateMissing, status: http.StatusConflict, code: "translation_template_missing"},
+	{err: service.ErrTranslationNotPending, status: http.StatusConflict, code: "translation_bundle_not_pending"},
+	{err: repository.ErrTranslationBundleNotFound, status: http.StatusNotFound, code: "translation_bundle_not_found"},
+
+	// Email templates
+	{err: mailtmpl.ErrUnknownEmail, status: http.StatusNotFound, code: "email_template_not_found"},
+}
+
+// respondServiceError writes the problem for err if it is one of the
+// sentinel errors in serviceProblems or lists the problems with a
+// translation bundle, and reports whether it was.
+func respondServiceError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var bundleErr *service.InvalidBundleError
+	if errors.As(err, &bundleErr) {
+		p := problem.New(requestLanguage(r), http.StatusUnprocessableEntity, "invalid_translation_bundle")
+		for _, issue := range bundleErr.Issues {
+			p.WithField(issue.Key, issue.Code, issue.Args...)
+		}
+		p.Write(w, r)
+		return true
+	}
+
+	for _, sp := range serviceProblems {
+		if !errors.Is(err, sp.err) {
+			continue
+		}
+
+		p := problem.New(requestLanguage(r), sp.status, sp.code, sp.args...)
+		if sp.field != "" {
+			p.WithField(sp.field, sp.code, sp.args...)
+		}
+		p.Write(w, r)
+		return true
+	}
+	return false
+}
+
+// respondError writes the problem with code in the language of r; args
+// fill in the title's placeholders.
+func respondError(w http.ResponseWriter, r *http.Request, statusCode int, code string, args ...interface{}) {
+	problem.New(requestLanguage(r), statusCode, code, args...).Write(w, r)
+}
+
+// respondFieldError writes the problem with code about the request field
+// field.
+func respondFieldError(w http.ResponseWriter, r *http.Request, statusCode int, code, field string, args ...interface{}) {
+	problem.New(requestLanguage(r), statusCode, code, args...).
+		WithField(field, code, args...).
+		Write(w, r)
+}
+
+// requireFields reports whether the fields, given as pairs of name and
+// value, are all set. Otherwise it writes the problem with code listing
+// the missing ones.
+func requireFields(w http.ResponseWriter, r *http.Request, code string, fields ...string) bool {
+	p := problem.New(requestLanguage(r), http.StatusBadRequest, code)
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] == "" {
+			p.WithField(fields[i], "field_required")
+		}
+	}
+	if len(p.Errors) == 0 {
+		return true
+	}
+
+	p.Write(w, r)
+	return false
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/internal/datefmt"
+	"backend/internal/models"
+	"backend/internal/service"
+)
+
+// maxBundleSize limits the size of uploaded ARB files.
+const maxBundleSize = 1 << 20
+
+type TranslationHandler struct {
+	translationService *service.TranslationService
+	authService        *service.AuthService
+}
+
+func NewTranslationHandler(translationService *service.TranslationService, authService *service.AuthService) *TranslationHandler {
+	return &TranslationHandler{
+		translationService: translationService,
+		authService:        authService,
+	}
+}
+
+type RejectTranslationRequest struct {
+	// Why the bundle was rejected, for the translator
+	Note string `json:"note"`
+}
+
+// GetBundle serves the published translations of a locale as an ARB file,
+// with keys it has no translation for in English. The app fetches it at
+// startup; its ETag changes with each published version, so clients can
+// revalidate with If-None-Match.
+func (h *TranslationHandler) GetBundle(w http.ResponseWriter, r *http.Request) {
+	published, err := h.translationService.Published(r.PathValue("locale"))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "get_translations_failed")
+		}
+		return
+	}
+
+	etag := `"` + published.Locale + "-" + strconv.Itoa(published.Version) + "-en-" + strconv.Itoa(published.TemplateVersion) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	bundle := make(map[string]interface{}, len(published.Messages)+1)
+	for key, message := range published.Messages {
+		bundle[key] = message
+	}
+	bundle["@@locale"] = published.Locale
+
+	respondJSON(w, http.StatusOK, bundle)
+}
+
+// UploadBundle stores an ARB file, the request body, as the next version of
+// the translations of a locale, pending review. Admin only.
+func (h *TranslationHandler) UploadBundle(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBundleSize))
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+		return
+	}
+
+	bundle, err := h.translationService.Upload(getUserIDFromToken(r), r.PathValue("locale"), data, clientInfo(r))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "upload_translations_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, translationBundleResponse(bundle, requestDates(r)))
+}
+
+// ListBundles returns the versions of the translations of a locale, newest
+// first. Admin only.
+func (h *TranslationHandler) ListBundles(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	bundles, err := h.translationService.Bundles(r.PathValue("locale"))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "list_translations_failed")
+		}
+		return
+	}
+
+	dates := requestDates(r)
+	response := make([]map[string]interface{}, 0, len(bundles))
+	for _, bundle := range bundles {
+		response = append(response, translationBundleResponse(bundle, dates))
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"bundles": response})
+}
+
+// GetBundleVersion returns a version of the translations of a locale for
+// review, with the keys of the English template it leaves untranslated.
+// Admin only.
+func (h *TranslationHandler) GetBundleVersion(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	version, ok := bundleVersion(w, r)
+	if !ok {
+		return
+	}
+
+	bundle, missing, err := h.translationService.Bundle(r.PathValue("locale"), version)
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "get_translations_failed")
+		}
+		return
+	}
+
+	response := translationBundleResponse(bundle, requestDates(r))
+	response["messages"] = bundle.Messages
+	response["missing"] = missing
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// PublishBundle publishes a pending version of the translations of a
+// locale; the app is served it from then on. Admin only.
+func (h *TranslationHandler) PublishBundle(w http.ResponseWriter, r *http.Request) {
+	h.reviewBundle(w, r, true, "")
+}
+
+// RejectBundle rejects a pending version of the translations of a locale
+// with a note for the translator. Admin only.
+func (h *TranslationHandler) RejectBundle(w http.ResponseWriter, r *http.Request) {
+	var req RejectTranslationRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, r, http.StatusBadRequest, "invalid_request_body")
+			return
+		}
+	}
+
+	h.reviewBundle(w, r, false, req.Note)
+}
+
+func (h *TranslationHandler) reviewBundle(w http.ResponseWriter, r *http.Request, publish bool, note string) {
+	if !requireAdmin(w, r, h.authService) {
+		return
+	}
+
+	version, ok := bundleVersion(w, r)
+	if !ok {
+		return
+	}
+
+	bundle, err := h.translationService.Review(getUserIDFromToken(r), r.PathValue("locale"), version, publish, note, clientInfo(r))
+	if err != nil {
+		if !respondServiceError(w, r, err) {
+			respondError(w, r, http.StatusInternalServerError, "review_translations_failed")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, translationBundleResponse(bundle, requestDates(r)))
+}
+
+// bundleVersion returns the version in the path of r. It writes the error
+// response and returns false if it isn't a number.
+func bundleVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil || version < 1 {
+		respondError(w, r, http.StatusNotFound, "translation_bundle_not_found")
+		return 0, false
+	}
+	return version, true
+}
+
+// etagMatches reports whether the If-None-Match header value ifNoneMatch
+// lists etag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func translationBundleResponse(bundle *models.TranslationBundle, dates *datefmt.Formatter) map[string]interface{} {
+	response := map[string]interface{}{
+		"locale":              bundle.Locale,
+		"version":             bundle.Version,
+		"status":              bundle.Status,
+		"uploaded_by":         bundle.UploadedBy,
+		"uploaded_at":         datefmt.Timestamp(bundle.UploadedAt),
+		"uploaded_at_display": dates.Format(bundle.UploadedAt),
+	}
+	if bundle.ReviewedAt != nil {
+		response["reviewed_by"] = bundle.ReviewedBy
+		response["reviewed_at"] = datefmt.Timestamp(*bundle.ReviewedAt)
+		response["reviewed_at_display"] = dates.Format(*bundle.ReviewedAt)
+		response["review_note"] = bundle.ReviewNote
+	}
+	return response
+}
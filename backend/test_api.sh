@@ -2,6 +2,8 @@
 
 # API Testing Script for Multi Language Bloc Backend
 # Usage: ./test_api.sh
+# Registration needs REGISTRATION_PROTECTION=false on the auth service, since
+# this script doesn't solve proof-of-work challenges.
 
 BASE_URL="http://localhost:8080"
 GREEN='\033[0;32m'